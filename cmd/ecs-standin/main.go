@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/takutakahashi/k8s-ecstask/internal/standin"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+func main() {
+	var (
+		addr      = flag.String("addr", "127.0.0.1:4566", "Address to listen on")
+		region    = flag.String("region", "us-east-1", "AWS region used in ARNs")
+		accountID = flag.String("account-id", "123456789012", "AWS account ID used in ARNs")
+		tick      = flag.Duration("tick", time.Second,
+			"Interval at which tasks advance one lifecycle state (PROVISIONING, PENDING, RUNNING, ...)")
+		secretsFile = flag.String("secrets-file", "",
+			"KEY=VALUE file of Parameter Store paths and Secrets Manager ARNs to preload")
+	)
+	flag.Parse()
+
+	server := standin.NewServer(*region, *accountID)
+	if *secretsFile != "" {
+		values, err := ecs.LoadSecretsFile(*secretsFile)
+		if err != nil {
+			log.Fatalf("Failed to read secrets file: %v", err)
+		}
+		if err := server.Seed(values); err != nil {
+			log.Fatalf("Failed to load secrets: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go server.Run(ctx, *tick)
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+	httpServer := &http.Server{Handler: server, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Serving ECS, SSM, Secrets Manager and CloudWatch Logs on http://%s", listener.Addr())
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
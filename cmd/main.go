@@ -63,6 +63,10 @@ func main() {
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
+	var offload offloadOptions
+	var admissionMode string
+	var virtualNode virtualNodeOptions
+	var watched watchOptions
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -80,6 +84,12 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&admissionMode, "webhook-admission-mode", string(webhookv1.AdmissionModeEnforce),
+		"How watched Pods that do not convert to an ECS task definition are admitted: enforce, warn or audit. "+
+			"The "+webhookv1.AdmissionModeLabel+" label of a namespace overrides it.")
+	offload.bindFlags(flag.CommandLine)
+	virtualNode.bindFlags(flag.CommandLine)
+	watched.bindFlags(flag.CommandLine)
 	opts := zap.Options{
 		Development: true,
 	}
@@ -201,19 +211,63 @@ func main() {
 		os.Exit(1)
 	}
 
+	ctx := ctrl.SetupSignalHandler()
+	matcher, err := watched.matcher(mgr)
+	if err != nil {
+		setupLog.Error(err, "invalid watch selectors")
+		os.Exit(1)
+	}
+	profile, err := offload.loadProfile(ctx, mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to load converter config")
+		os.Exit(1)
+	}
+	converter := newConverter(mgr, profile)
+	var offloader *controller.Offloader
+	if offload.enabled {
+		offloader, err = offload.newOffloader(ctx, profile, converter)
+		if err != nil {
+			setupLog.Error(err, "unable to set up offloading to ECS")
+			os.Exit(1)
+		}
+	}
+	if virtualNode.name != "" {
+		if err := virtualNode.setup(ctx, mgr, &offload, offloader); err != nil {
+			setupLog.Error(err, "unable to set up the virtual node")
+			os.Exit(1)
+		}
+	}
+
 	if err := (&controller.PodWatcherReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Offloader: offloader,
+		Recorder:  mgr.GetEventRecorderFor("podwatcher"),
+		Matcher:   matcher,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PodWatcher")
 		os.Exit(1)
 	}
 	// nolint:goconst
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		if err := webhookv1.SetupPodWebhookWithManager(mgr); err != nil {
+		webhookOptions := webhookv1.PodWebhookOptions{
+			Converter:   converter,
+			DefaultMode: webhookv1.AdmissionMode(admissionMode),
+			Offload:     offloader != nil,
+			Matcher:     matcher,
+		}
+		if err := webhookv1.SetupPodWebhookWithManager(mgr, webhookOptions); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
 			os.Exit(1)
 		}
+		if err := webhookv1.SetupWorkloadWebhooksWithManager(mgr, webhookOptions); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "workloads")
+			os.Exit(1)
+		}
+		if err := watched.setupWebhookSelectors(mgr, matcher); err != nil {
+			setupLog.Error(err, "unable to set up the webhook selectors")
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
@@ -243,7 +297,7 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
@@ -0,0 +1,137 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/takutakahashi/k8s-ecstask/internal/controller"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+	"github.com/takutakahashi/k8s-ecstask/pkg/k8s"
+)
+
+// offloadOptions are the flags configuring how the controller runs watched pods on ECS
+type offloadOptions struct {
+	enabled          bool
+	converterConfig  string
+	converterProfile string
+	cluster          string
+	region           string
+	endpointURL      string
+	statusSyncPeriod time.Duration
+	deregister       bool
+}
+
+func (o *offloadOptions) bindFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.enabled, "offload", false,
+		"If set, watched Pods held back by the "+controller.OffloadSchedulingGate+" scheduling gate are run as ECS tasks.")
+	fs.StringVar(&o.converterConfig, "converter-config", "",
+		"Converter config file, or configmap:namespace/name to read it from the cluster.")
+	fs.StringVar(&o.converterProfile, "converter-profile", "",
+		"Profile of the converter config to use (default: the defaultProfile of the config).")
+	fs.StringVar(&o.cluster, "ecs-cluster", "",
+		"ECS cluster tasks are started in (default: the run.cluster of the profile, otherwise default).")
+	fs.StringVar(&o.region, "ecs-region", "", "AWS region of the ECS API (default: the region of the profile).")
+	fs.StringVar(&o.endpointURL, "ecs-endpoint-url", "",
+		"Endpoint of the ECS API, such as a local stand-in (default: the regional endpoint).")
+	fs.DurationVar(&o.statusSyncPeriod, "ecs-status-sync-period", 15*time.Second,
+		"How often the ECS tasks of offloaded Pods are polled to update the Pod status.")
+	fs.BoolVar(&o.deregister, "ecs-deregister-task-definitions", false,
+		"If set, task definition revisions registered by the controller are deregistered with their last Pod.")
+}
+
+// loadProfile reads the converter profile from a file or, for configmap:namespace/name, with
+// the manager's credentials from the cluster
+func (o *offloadOptions) loadProfile(ctx context.Context, mgr ctrl.Manager) (*ecs.Profile, error) {
+	if o.converterConfig == "" {
+		if o.converterProfile != "" {
+			return nil, fmt.Errorf("--converter-profile requires --converter-config")
+		}
+		return &ecs.Profile{}, nil
+	}
+
+	reference, isConfigMap := strings.CutPrefix(o.converterConfig, k8s.ConfigMapSourcePrefix)
+	if !isConfigMap {
+		config, err := ecs.LoadProfileConfig(o.converterConfig)
+		if err != nil {
+			return nil, err
+		}
+		return config.Profile(o.converterProfile)
+	}
+
+	namespace, name, found := strings.Cut(reference, "/")
+	if !found || namespace == "" || name == "" {
+		return nil, fmt.Errorf("expected %snamespace/name, got %q", k8s.ConfigMapSourcePrefix, o.converterConfig)
+	}
+	client, err := k8s.NewClientFromConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	config, err := k8s.LoadProfileConfigFromConfigMap(ctx, client, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return config.Profile(o.converterProfile)
+}
+
+// newConverter creates the converter shared by the offloader and the webhooks, so that pods are
+// admitted only if the controller can convert them
+func newConverter(mgr ctrl.Manager, profile *ecs.Profile) *ecs.Converter {
+	options := profile.ConversionOptions()
+	// The API reader avoids caching every ServiceAccount of the cluster
+	options.ServiceAccounts = controller.ServiceAccountReader{Reader: mgr.GetAPIReader()}
+	return ecs.NewConverter(options)
+}
+
+// newOffloader creates the offloader of the PodWatcher controller. Flags override the profile.
+func (o *offloadOptions) newOffloader(
+	ctx context.Context,
+	profile *ecs.Profile,
+	converter *ecs.Converter,
+) (*controller.Offloader, error) {
+	// The region of the profile also applies to the other AWS clients, such as the one reading logs
+	if o.region == "" {
+		o.region = profile.Region
+	}
+	client, err := ecsapi.NewClient(ctx, ecsapi.Config{
+		Region:     o.region,
+		Endpoint:   o.endpointURL,
+		OnThrottle: controller.ObserveECSThrottle,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ECS client: %w", err)
+	}
+
+	run := profile.Run
+	if o.cluster != "" {
+		run.Cluster = o.cluster
+	}
+	return &controller.Offloader{
+		ECS:                       controller.InstrumentECS(client),
+		Converter:                 converter,
+		Run:                       run,
+		StatusSyncPeriod:          o.statusSyncPeriod,
+		DeregisterTaskDefinitions: o.deregister,
+	}, nil
+}
@@ -19,6 +19,7 @@ import (
 type ValidationResult struct {
 	PodName         string   `json:"podName"`
 	Namespace       string   `json:"namespace"`
+	Family          string   `json:"family,omitempty"`
 	CanConvert      bool     `json:"canConvert"`
 	Errors          []string `json:"errors,omitempty"`
 	Warnings        []string `json:"warnings,omitempty"`
@@ -39,8 +40,13 @@ func main() {
 			"Kubernetes namespace to check (default: all namespaces)")
 		kubeconfig = flag.String("kubeconfig", "",
 			"Path to kubeconfig file (default: ~/.kube/config)")
-		outputFormat = flag.String("output", "text", "Output format: text or json")
-		skipWarnings = flag.Bool("skip-warnings", false, "Skip validation warnings")
+		outputFormat   = flag.String("output", "text", "Output format: text or json")
+		skipWarnings   = flag.Bool("skip-warnings", false, "Skip validation warnings")
+		familyTemplate = flag.String("family-template", ecs.DefaultFamilyTemplate,
+			"Go template naming the task definition family of each pod")
+		configSource = flag.String("config", "",
+			"Converter config file, or configmap:namespace/name to read it from the cluster")
+		profileName = flag.String("profile", "", "Profile of the converter config to use (default: defaultProfile)")
 	)
 	flag.Parse()
 
@@ -68,8 +74,25 @@ func main() {
 		Results:   make([]ValidationResult, 0, len(pods.Items)),
 	}
 
+	options := defaultCheckOptions()
+	if *configSource != "" {
+		profile, err := k8s.LoadProfile(ctx, *configSource, *profileName, k8s.ClientConfig{KubeconfigPath: *kubeconfig})
+		if err != nil {
+			log.Fatalf("Failed to load converter config: %v", err)
+		}
+		options = profileCheckOptions(profile)
+	} else if *profileName != "" {
+		log.Fatalf("-profile requires -config")
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "family-template" {
+			options.FamilyTemplate = *familyTemplate
+		}
+	})
+
+	converter := newConverter(options, k8s.NewServiceAccountService(client))
 	for _, pod := range pods.Items {
-		result := validatePod(&pod, *skipWarnings)
+		result := validatePod(converter, &pod, *skipWarnings)
 		summary.Results = append(summary.Results, result)
 
 		if result.CanConvert {
@@ -92,7 +115,43 @@ func main() {
 	}
 }
 
-func validatePod(pod *corev1.Pod, skipWarnings bool) ValidationResult {
+// defaultCheckOptions returns the options used for validation when no converter config is
+// given. No default task role is set so that pods whose ServiceAccount resolves to no role
+// are reported.
+func defaultCheckOptions() ecs.ConversionOptions {
+	return ecs.ConversionOptions{
+		ParameterStorePrefix:    "/pods",
+		SkipUnsupportedFeatures: true,
+		DefaultCPU:              "256",
+		DefaultMemory:           "512",
+		FamilyTemplate:          ecs.DefaultFamilyTemplate,
+	}
+}
+
+// profileCheckOptions returns the options of the profile, keeping the validation defaults
+// for the task size when the profile does not set one
+func profileCheckOptions(profile *ecs.Profile) ecs.ConversionOptions {
+	defaults := defaultCheckOptions()
+	options := profile.ConversionOptions()
+	if profile.SkipUnsupportedFeatures == nil {
+		options.SkipUnsupportedFeatures = defaults.SkipUnsupportedFeatures
+	}
+	if options.DefaultCPU == "" {
+		options.DefaultCPU = defaults.DefaultCPU
+	}
+	if options.DefaultMemory == "" {
+		options.DefaultMemory = defaults.DefaultMemory
+	}
+	return options
+}
+
+// newConverter creates the converter used for validation
+func newConverter(options ecs.ConversionOptions, serviceAccounts ecs.ServiceAccountResolver) *ecs.Converter {
+	options.ServiceAccounts = serviceAccounts
+	return ecs.NewConverter(options)
+}
+
+func validatePod(converter *ecs.Converter, pod *corev1.Pod, skipWarnings bool) ValidationResult {
 	result := ValidationResult{
 		PodName:         pod.Name,
 		Namespace:       pod.Namespace,
@@ -102,28 +161,9 @@ func validatePod(pod *corev1.Pod, skipWarnings bool) ValidationResult {
 		UnsupportedInfo: []string{},
 	}
 
-	// Create converter with default options
-	options := ecs.ConversionOptions{
-		ParameterStorePrefix: "/pods",
-		DefaultLogDriver:     "awslogs",
-		DefaultLogOptions: map[string]string{
-			"awslogs-group":  "/ecs/pods",
-			"awslogs-region": "us-east-1",
-		},
-		SkipUnsupportedFeatures: true,
-		DefaultExecutionRoleArn: "arn:aws:iam::123456789012:role/ecsTaskExecutionRole",
-		DefaultTaskRoleArn:      "arn:aws:iam::123456789012:role/ecsTaskRole",
-	}
-
-	converter := ecs.NewConverter(options)
-
-	// Create minimal ECS config for validation
-	ecsConfig := &ecs.ECSConfig{
-		Family:                  fmt.Sprintf("%s-%s", pod.Namespace, pod.Name),
-		RequiresCompatibilities: []string{"FARGATE"},
-		CPU:                     "256",
-		Memory:                  "512",
-	}
+	// Create minimal ECS config for validation; the family is rendered from the template and
+	// the remaining settings come from the pod annotations or the converter defaults
+	ecsConfig := &ecs.ECSConfig{}
 
 	// Try to convert using the existing converter
 	namespace := pod.Namespace
@@ -131,11 +171,26 @@ func validatePod(pod *corev1.Pod, skipWarnings bool) ValidationResult {
 		namespace = "default"
 	}
 
-	_, err := converter.Convert(&pod.Spec, ecsConfig, namespace)
+	taskDef, diagnostics, err := converter.ConvertPodWithDiagnostics(
+		context.Background(), pod, &pod.Spec, ecsConfig, namespace)
 	if err != nil {
 		// Parse the error to categorize it
 		result.CanConvert = false
 		categorizeConversionError(err, &result)
+	} else {
+		result.Family = taskDef.Family
+		for _, diagnostic := range diagnostics {
+			if diagnostic.Severity != ecs.SeverityInfo {
+				result.Warnings = append(result.Warnings, diagnostic.Message)
+			}
+		}
+		if errs := taskDef.Validate(); len(errs) > 0 {
+			// The conversion succeeded but ECS would reject the result
+			result.CanConvert = false
+			for _, validationErr := range errs {
+				result.Errors = append(result.Errors, validationErr.Error())
+			}
+		}
 	}
 
 	// Additional validation checks for warnings
@@ -277,6 +332,9 @@ func outputText(summary ValidationSummary) {
 		}
 
 		fmt.Printf("Pod: %s/%s - %s\n", result.Namespace, result.PodName, status)
+		if result.Family != "" {
+			fmt.Printf("  Family: %s\n", result.Family)
+		}
 
 		if len(result.Errors) > 0 {
 			fmt.Printf("  Errors:\n")
@@ -173,11 +173,38 @@ func TestValidatePod(t *testing.T) {
 			minErrors:    0,
 			minWarnings:  0,
 		},
+		{
+			name: "Pod violating ECS limits",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod-port-conflict",
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "app",
+							Image: "app:latest",
+							Ports: []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+						},
+						{
+							Name:  "sidecar",
+							Image: "sidecar:latest",
+							Ports: []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+						},
+					},
+				},
+			},
+			skipWarnings: true,
+			wantConvert:  false,
+			minErrors:    1,
+			minWarnings:  0,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := validatePod(tt.pod, tt.skipWarnings)
+			result := validatePod(newConverter(defaultCheckOptions(), nil), tt.pod, tt.skipWarnings)
 
 			if result.CanConvert != tt.wantConvert {
 				t.Errorf("validatePod() CanConvert = %v, want %v", result.CanConvert, tt.wantConvert)
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/k8s"
+)
+
+// outcome is the result of processing one input document
+type outcome string
+
+const (
+	outcomeConverted outcome = "converted"
+	outcomeSkipped   outcome = "skipped"
+	outcomeFailed    outcome = "failed"
+)
+
+// result records what happened to one input document
+type result struct {
+	manifest k8s.Manifest
+	outcome  outcome
+	reason   string
+	taskDef  *ecs.ECSTaskDefinition
+	file     string
+}
+
+// conversion converts the workloads of the input documents
+type conversion struct {
+	converter      *ecs.Converter
+	flagConfig     *ecs.ECSConfig
+	namespace      string
+	skipValidation bool
+
+	results []*result
+}
+
+// convert converts one document, recording it as skipped when it does not run pods
+func (c *conversion) convert(manifest k8s.Manifest) {
+	res := &result{manifest: manifest}
+	c.results = append(c.results, res)
+
+	pod, documentConfig, err := ecs.DecodeWorkload(manifest.TypeMeta, manifest.Raw)
+	if errors.Is(err, ecs.ErrUnsupportedKind) {
+		res.outcome, res.reason = outcomeSkipped, "not a workload"
+		return
+	}
+	if err != nil {
+		res.outcome, res.reason = outcomeFailed, err.Error()
+		return
+	}
+
+	ns := c.namespace
+	if ns == "" {
+		ns = pod.Namespace
+	}
+	if ns == "" {
+		ns = "default"
+	}
+
+	ecsConfig := ecs.MergeECSConfig(c.flagConfig, documentConfig)
+	taskDef, diagnostics, err := c.converter.ConvertPodWithDiagnostics(context.Background(), pod, &pod.Spec, ecsConfig, ns)
+	if err != nil {
+		res.outcome, res.reason = outcomeFailed, err.Error()
+		return
+	}
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", manifest, diagnostic)
+	}
+
+	// Validate against ECS limits before writing anything
+	if errs := taskDef.Validate(); len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "Task definition %s violates ECS limits:\n", taskDef.Family)
+		for _, validationErr := range errs {
+			fmt.Fprintf(os.Stderr, "  - %s\n", validationErr)
+		}
+		if !c.skipValidation {
+			res.outcome, res.reason = outcomeFailed, "task definition violates ECS limits"
+			return
+		}
+	}
+
+	res.outcome, res.taskDef = outcomeConverted, taskDef
+}
+
+// write emits the converted task definitions. With outputDir every family is written to its
+// own file; otherwise exactly one task definition is written to outputFile or stdout.
+func (c *conversion) write(emitter ecs.Emitter, outputFile, outputDir string) error {
+	converted := c.converted()
+	if outputDir == "" {
+		switch len(converted) {
+		case 0:
+			return nil
+		case 1:
+			return writeTaskDefinition(emitter, converted[0], outputFile)
+		default:
+			return fmt.Errorf("%d task definitions were converted, use -output-dir to write them", len(converted))
+		}
+	}
+
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return err
+	}
+	written := map[string]*result{}
+	for _, res := range converted {
+		family := res.taskDef.Family
+		if previous, ok := written[family]; ok {
+			res.outcome = outcomeFailed
+			res.reason = fmt.Sprintf("family %s was already written for %s", family, previous.manifest)
+			continue
+		}
+		written[family] = res
+
+		file := filepath.Join(outputDir, family+"."+emitter.Extension())
+		if err := writeTaskDefinition(emitter, res, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTaskDefinition(emitter ecs.Emitter, res *result, file string) error {
+	if file == "" {
+		if err := emitter.Emit(os.Stdout, res.taskDef); err != nil {
+			return err
+		}
+		printEmitterDiagnostics(emitter, res)
+		return nil
+	}
+
+	output, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := emitter.Emit(output, res.taskDef); err != nil {
+		_ = output.Close()
+		return err
+	}
+	printEmitterDiagnostics(emitter, res)
+	res.file = file
+	return output.Close()
+}
+
+// printEmitterDiagnostics reports what the output format could not represent exactly
+func printEmitterDiagnostics(emitter ecs.Emitter, res *result) {
+	reporter, ok := emitter.(interface{ Diagnostics() []ecs.Diagnostic })
+	if !ok {
+		return
+	}
+	for _, diagnostic := range reporter.Diagnostics() {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", res.manifest, diagnostic)
+	}
+}
+
+// converted returns the results of the documents converted so far
+func (c *conversion) converted() []*result {
+	var converted []*result
+	for _, res := range c.results {
+		if res.outcome == outcomeConverted {
+			converted = append(converted, res)
+		}
+	}
+	return converted
+}
+
+// printSummary lists every input document with its outcome
+func (c *conversion) printSummary(w io.Writer) {
+	counts := map[outcome]int{}
+	for _, res := range c.results {
+		counts[res.outcome]++
+		switch {
+		case res.file != "":
+			_, _ = fmt.Fprintf(w, "%-9s %s -> %s (%s)\n", res.outcome, res.manifest, res.taskDef.Family, res.file)
+		case res.taskDef != nil && res.outcome == outcomeConverted:
+			_, _ = fmt.Fprintf(w, "%-9s %s -> %s\n", res.outcome, res.manifest, res.taskDef.Family)
+		default:
+			_, _ = fmt.Fprintf(w, "%-9s %s (%s: %s)\n", res.outcome, res.manifest, res.manifest.Source, res.reason)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "%d converted, %d skipped, %d failed\n",
+		counts[outcomeConverted], counts[outcomeSkipped], counts[outcomeFailed])
+}
+
+// failed reports whether any document failed or nothing could be converted
+func (c *conversion) failed() bool {
+	converted := false
+	for _, res := range c.results {
+		switch res.outcome {
+		case outcomeFailed:
+			return true
+		case outcomeConverted:
+			converted = true
+		}
+	}
+	return !converted
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// exitChanged is the exit status of diff mode when a task definition changed, leaving 1 for
+// errors so CI jobs can tell the two apart
+const exitChanged = 2
+
+// registeredPrefix selects task definitions registered in ECS as -against value
+const registeredPrefix = "ecs:"
+
+// previousFunc loads the previous task definition of a family and names where it came from.
+// It returns nil without an error when the family has no previous task definition.
+type previousFunc func(family string) (*ecs.ECSTaskDefinition, string, error)
+
+// fileSource reads previous task definitions from a JSON file, or from the <family>.json
+// files of a directory
+func fileSource(against string, converted int) (previousFunc, error) {
+	info, err := os.Stat(against)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() && converted > 1 {
+		return nil, fmt.Errorf("%d task definitions were converted, -against must be a directory", converted)
+	}
+
+	return func(family string) (*ecs.ECSTaskDefinition, string, error) {
+		file := against
+		if info.IsDir() {
+			file = filepath.Join(against, family+".json")
+		}
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) && info.IsDir() {
+			return nil, file, nil
+		}
+		if err != nil {
+			return nil, file, err
+		}
+		previous, err := ecs.ParseTaskDefinition(data)
+		if err != nil {
+			return nil, file, fmt.Errorf("%s: %w", file, err)
+		}
+		return previous, file, nil
+	}, nil
+}
+
+// registeredSource describes the previous task definitions in ECS: the latest ACTIVE revision
+// of each family, or the given family[:revision] or ARN for a single task definition
+func registeredSource(ctx context.Context, client ecsapi.Client, against string, converted int) (previousFunc, error) {
+	name := strings.TrimPrefix(against, registeredPrefix)
+	if name != "" && converted > 1 {
+		return nil, fmt.Errorf("%d task definitions were converted, use -against %s to compare each family",
+			converted, registeredPrefix)
+	}
+
+	return func(family string) (*ecs.ECSTaskDefinition, string, error) {
+		taskDefinition := name
+		if taskDefinition == "" {
+			taskDefinition = family
+		}
+		source := registeredPrefix + taskDefinition
+		previous, err := client.DescribeTaskDefinition(ctx, taskDefinition)
+		if ecsapi.IsNotFound(err) {
+			return nil, source, nil
+		}
+		if err != nil {
+			return nil, source, err
+		}
+		return previous, previous.TaskDefinitionArn, nil
+	}, nil
+}
+
+// diff compares the converted task definitions with the previous ones and prints the changes.
+// Families without a previous task definition are new. It reports whether anything changed.
+func (c *conversion) diff(w io.Writer, previousOf previousFunc) (bool, error) {
+	changed := false
+	for _, res := range c.converted() {
+		family := res.taskDef.Family
+		previous, source, err := previousOf(family)
+		if err != nil {
+			return false, err
+		}
+		if previous == nil {
+			_, _ = fmt.Fprintf(w, "Task definition %s: new, %s does not exist\n", family, source)
+			changed = true
+			continue
+		}
+
+		changes, err := ecs.DiffTaskDefinitions(previous, res.taskDef)
+		if err != nil {
+			return false, err
+		}
+		if len(changes) == 0 {
+			_, _ = fmt.Fprintf(w, "Task definition %s: no changes against %s\n", family, source)
+			continue
+		}
+
+		changed = true
+		_, _ = fmt.Fprintf(w, "Task definition %s: %d changes against %s\n", family, len(changes), source)
+		for _, change := range changes {
+			_, _ = fmt.Fprintf(w, "  %s\n", change)
+		}
+	}
+	return changed, nil
+}
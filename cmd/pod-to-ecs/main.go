@@ -1,26 +1,28 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
+	"maps"
 	"os"
-
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/util/yaml"
+	"strings"
 
 	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+	"github.com/takutakahashi/k8s-ecstask/pkg/k8s"
 )
 
 func main() {
 	var (
-		inputFile  = flag.String("input", "", "Input YAML file containing Kubernetes Pod specification")
-		outputFile = flag.String("output", "", "Output JSON file for ECS task definition (default: stdout)")
-		family     = flag.String("family", "", "ECS task definition family name (required)")
-		namespace  = flag.String("namespace", "",
+		inputFile = flag.String("input", "",
+			"Comma-separated manifest files or directories, or - for stdin (multi-document YAML is accepted)")
+		outputFile = flag.String("output", "", "Output file for ECS task definition (default: stdout)")
+		outputDir  = flag.String("output-dir", "",
+			"Directory receiving one file per task definition family (required for several workloads)")
+		family    = flag.String("family", "", "ECS task definition family name (default: rendered from -family-template)")
+		namespace = flag.String("namespace", "",
 			"Kubernetes namespace (extracted from Pod metadata if not specified)")
 		parameterStorePrefix = flag.String("parameter-store-prefix", "/pods", "Prefix for Parameter Store parameters")
 		executionRoleArn     = flag.String("execution-role-arn", "", "ECS execution role ARN")
@@ -31,41 +33,101 @@ func main() {
 		logGroup             = flag.String("log-group", "/ecs/pods", "CloudWatch log group")
 		logRegion            = flag.String("log-region", "us-east-1", "AWS region for logs")
 		skipUnsupported      = flag.Bool("skip-unsupported", true, "Skip unsupported Kubernetes features")
+		outputFormat         = flag.String("format", "json",
+			"Output format: "+strings.Join(ecs.EmitterFormats(), ", "))
+		secretsFile = flag.String("secrets-file", "",
+			"KEY=VALUE file mapping Parameter Store paths to local values (compose format only)")
+		skipValidation = flag.Bool("skip-validation", false,
+			"Write the task definition even if it violates ECS limits")
+		familyTemplate = flag.String("family-template", ecs.DefaultFamilyTemplate,
+			"Go template naming the family when -family is not set")
+		propagateLabels = flag.String("propagate-labels", "",
+			"Comma-separated pod label keys (or prefixes ending in *) copied to tags and dockerLabels")
+		propagateAnnotations = flag.String("propagate-annotations", "",
+			"Comma-separated pod annotation keys (or prefixes ending in *) copied to tags and dockerLabels")
 	)
-	flag.Parse()
+	serviceAccountRoles := ecs.ServiceAccountRoles{}
+	flag.Func("service-account-role",
+		"Task role for a ServiceAccount without an IRSA annotation, as namespace/name=arn "+
+			"(name may be *; repeatable)",
+		func(value string) error {
+			serviceAccount, role, found := strings.Cut(value, "=")
+			if !found || !strings.Contains(serviceAccount, "/") || role == "" {
+				return fmt.Errorf("expected namespace/name=arn, got %q", value)
+			}
+			serviceAccountRoles[serviceAccount] = role
+			return nil
+		})
+	serviceAccountManifests := flag.String("service-accounts", "",
+		"Comma-separated ServiceAccount manifest files or directories used to resolve IRSA task roles")
+	configSource := flag.String("config", "",
+		"Converter config file, or configmap:namespace/name to read it from the cluster")
+	profileName := flag.String("profile", "", "Profile of the converter config to use (default: defaultProfile)")
+	kubeconfig := flag.String("kubeconfig", "", "Path to kubeconfig file, used with -config configmap:...")
+	against := flag.String("against", "",
+		"Previous task definition JSON file, directory of <family>.json files, or ecs:[family[:revision]] "+
+			"for registered task definitions, to compare with (diff mode)")
+	register := flag.Bool("register", false, "Register the converted task definitions with ECS")
+	runTask := flag.Bool("run", false, "Register the converted task definitions and run one task of each")
+	cluster := flag.String("cluster", "default", "ECS cluster the tasks of -run are started in")
+	launchType := flag.String("launch-type", "",
+		"Launch type of -run (default: FARGATE when the task definition requires it, otherwise EC2)")
+	subnets := flag.String("subnets", "", "Comma-separated subnets of awsvpc tasks started by -run")
+	securityGroups := flag.String("security-groups", "",
+		"Comma-separated security groups of awsvpc tasks started by -run")
+	assignPublicIP := flag.Bool("assign-public-ip", false, "Assign public IPs to awsvpc tasks started by -run")
+	region := flag.String("region", "", "AWS region of the ECS API (default: profile region, then AWS configuration)")
+	endpointURL := flag.String("endpoint-url", "",
+		"ECS API endpoint, such as a local stand-in (default: AWS_ENDPOINT_URL_ECS, then the regional endpoint)")
+
+	// "pod-to-ecs diff ..." compares the conversion with previous task definitions instead of writing it
+	diffMode := len(os.Args) > 1 && os.Args[1] == "diff"
+	if diffMode {
+		_ = flag.CommandLine.Parse(os.Args[2:])
+	} else {
+		flag.Parse()
+	}
 
-	if *inputFile == "" {
-		fmt.Fprintf(os.Stderr, "Usage: %s -input <yaml-file> -family <family-name> [options]\n", os.Args[0])
+	if *inputFile == "" || diffMode != (*against != "") {
+		fmt.Fprintf(os.Stderr, "Usage: %s -input <file|directory|-> [-family <family-name>] [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s diff -input <file|directory|-> -against <file|directory> [options]\n",
+			os.Args[0])
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	if *family == "" {
-		fmt.Fprintf(os.Stderr, "Error: -family is required\n")
+	emitter, err := ecs.NewEmitter(*outputFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Read input file
-	data, err := os.ReadFile(*inputFile)
-	if err != nil {
-		log.Fatalf("Failed to read input file: %v", err)
+	if composeEmitter, ok := emitter.(*ecs.ComposeEmitter); ok && *secretsFile != "" {
+		secrets, err := ecs.LoadSecretsFile(*secretsFile)
+		if err != nil {
+			log.Fatalf("Failed to read secrets file: %v", err)
+		}
+		composeEmitter.Secrets = secrets
+	}
+
+	if *outputFile != "" && *outputDir != "" {
+		log.Fatalf("-output and -output-dir cannot be used together")
 	}
 
-	// Parse YAML as Kubernetes Pod using Kubernetes YAML decoder
-	var pod corev1.Pod
-	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 1024)
-	if err := decoder.Decode(&pod); err != nil {
-		log.Fatalf("Failed to parse Kubernetes Pod YAML: %v", err)
+	// Read every document of the inputs
+	manifests, err := k8s.ReadManifests(os.Stdin, strings.Split(*inputFile, ",")...)
+	if err != nil {
+		log.Fatalf("Failed to read input: %v", err)
 	}
 
-	// Create ECS configuration
+	// Create ECS configuration; flags override the configuration of the documents
 	var networkModePtr *string
 	if *networkMode != "awsvpc" {
 		// Only set if explicitly changed from default
 		networkModePtr = networkMode
 	}
 
-	ecsConfig := &ecs.ECSConfig{
+	flagConfig := &ecs.ECSConfig{
 		Family:           *family,
 		ExecutionRoleArn: *executionRoleArn,
 		TaskRoleArn:      *taskRoleArn,
@@ -80,66 +142,177 @@ func main() {
 		Memory:                  *memory,
 	}
 
-	// Create converter
-	options := ecs.ConversionOptions{
-		ParameterStorePrefix:    *parameterStorePrefix,
-		DefaultExecutionRoleArn: *executionRoleArn,
-		DefaultTaskRoleArn:      *taskRoleArn,
-		DefaultLogDriver:        "awslogs",
-		DefaultLogOptions: map[string]string{
-			"awslogs-group":  *logGroup,
-			"awslogs-region": *logRegion,
-		},
-		SkipUnsupportedFeatures: *skipUnsupported,
+	// Create converter from the selected profile; flags given on the command line override it
+	var profile *ecs.Profile
+	if *configSource != "" {
+		profile, err = k8s.LoadProfile(context.Background(), *configSource, *profileName,
+			k8s.ClientConfig{KubeconfigPath: *kubeconfig})
+		if err != nil {
+			log.Fatalf("Failed to load converter config: %v", err)
+		}
+	} else if *profileName != "" {
+		log.Fatalf("-profile requires -config")
 	}
 
-	converter := ecs.NewConverter(options)
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	// flagOr returns the flag value when it was set or the profile leaves the setting empty
+	flagOr := func(name, profileValue, flagValue string) string {
+		if explicit[name] || profileValue == "" {
+			return flagValue
+		}
+		return profileValue
+	}
 
-	// Determine namespace
-	ns := *namespace
-	if ns == "" {
-		ns = pod.Namespace
+	var options ecs.ConversionOptions
+	if profile != nil {
+		options = profile.ConversionOptions()
+	}
+	options.ParameterStorePrefix = flagOr("parameter-store-prefix", options.ParameterStorePrefix, *parameterStorePrefix)
+	options.DefaultExecutionRoleArn = flagOr("execution-role-arn", options.DefaultExecutionRoleArn, *executionRoleArn)
+	options.DefaultTaskRoleArn = flagOr("task-role-arn", options.DefaultTaskRoleArn, *taskRoleArn)
+	options.FamilyTemplate = flagOr("family-template", options.FamilyTemplate, *familyTemplate)
+	if profile == nil || profile.SkipUnsupportedFeatures == nil || explicit["skip-unsupported"] {
+		options.SkipUnsupportedFeatures = *skipUnsupported
+	}
+	if options.DefaultLogOptions == nil || explicit["log-group"] || explicit["log-region"] {
+		if options.DefaultLogOptions == nil {
+			options.DefaultLogOptions = map[string]string{"awslogs-stream-prefix": ecs.DefaultLogStreamPrefix}
+		}
+		options.DefaultLogOptions["awslogs-group"] = flagOr("log-group",
+			options.DefaultLogOptions["awslogs-group"], *logGroup)
+		options.DefaultLogOptions["awslogs-region"] = flagOr("log-region",
+			options.DefaultLogOptions["awslogs-region"], *logRegion)
+	}
+	if explicit["propagate-labels"] || explicit["propagate-annotations"] {
+		options.MetadataRules = metadataRules(*propagateLabels, *propagateAnnotations)
 	}
-	if ns == "" {
-		ns = "default"
+	if options.ServiceAccountRoles == nil {
+		options.ServiceAccountRoles = ecs.ServiceAccountRoles{}
+	}
+	maps.Copy(options.ServiceAccountRoles, serviceAccountRoles)
+	if *serviceAccountManifests != "" {
+		serviceAccounts, err := k8s.LoadServiceAccountManifests(strings.Split(*serviceAccountManifests, ",")...)
+		if err != nil {
+			log.Fatalf("Failed to read ServiceAccount manifests: %v", err)
+		}
+		options.ServiceAccounts = serviceAccounts
 	}
 
-	// Convert to ECS task definition
-	taskDef, err := converter.ConvertPod(&pod, &pod.Spec, ecsConfig, ns)
-	if err != nil {
-		log.Fatalf("Failed to convert: %v", err)
+	run := &conversion{
+		converter:      ecs.NewConverter(options),
+		flagConfig:     flagConfig,
+		namespace:      *namespace,
+		skipValidation: *skipValidation,
+	}
+	for _, manifest := range manifests {
+		run.convert(manifest)
 	}
 
-	// Marshal to JSON
-	jsonData, err := json.MarshalIndent(taskDef, "", "  ")
-	if err != nil {
-		log.Fatalf("Failed to marshal JSON: %v", err)
+	ctx := context.Background()
+	// ecsClient creates the ECS API client of -register, -run and -against ecs:...
+	ecsClient := func() ecsapi.Client {
+		client, err := ecsapi.NewClient(ctx, ecsapi.Config{
+			Region:   flagOr("region", options.Region, *region),
+			Endpoint: *endpointURL,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create ECS client: %v", err)
+		}
+		return client
 	}
 
-	// Write output
-	var output io.Writer = os.Stdout
-	if *outputFile != "" {
-		file, err := os.Create(*outputFile)
+	if diffMode {
+		if run.failed() {
+			run.printSummary(os.Stderr)
+			os.Exit(1)
+		}
+		var previousOf previousFunc
+		if strings.HasPrefix(*against, registeredPrefix) {
+			previousOf, err = registeredSource(ctx, ecsClient(), *against, len(run.converted()))
+		} else {
+			previousOf, err = fileSource(*against, len(run.converted()))
+		}
 		if err != nil {
-			log.Fatalf("Failed to create output file: %v", err)
+			log.Fatalf("Failed to read previous task definitions: %v", err)
 		}
-		defer func() {
-			if err := file.Close(); err != nil {
-				log.Printf("Failed to close output file: %v", err)
-			}
-		}()
-		output = file
+		changed, err := run.diff(os.Stdout, previousOf)
+		if err != nil {
+			log.Fatalf("Failed to compare task definitions: %v", err)
+		}
+		if changed {
+			os.Exit(exitChanged)
+		}
+		return
 	}
 
-	if _, err := output.Write(jsonData); err != nil {
+	if err := run.write(emitter, *outputFile, *outputDir); err != nil {
 		log.Fatalf("Failed to write output: %v", err)
 	}
+	if *outputFile != "" && !run.failed() {
+		fmt.Printf("ECS task definition written to %s\n", *outputFile)
+	}
+	if *register || *runTask {
+		var taskRun *ecs.ProfileRun
+		if *runTask {
+			// Flags given on the command line override the run settings of the profile
+			taskRun = &ecs.ProfileRun{}
+			if profile != nil {
+				taskRun = &profile.Run
+			}
+			taskRun.Cluster = flagOr("cluster", taskRun.Cluster, *cluster)
+			taskRun.LaunchType = flagOr("launch-type", taskRun.LaunchType, *launchType)
+			if explicit["subnets"] || len(taskRun.Subnets) == 0 {
+				taskRun.Subnets = splitList(*subnets)
+			}
+			if explicit["security-groups"] || len(taskRun.SecurityGroups) == 0 {
+				taskRun.SecurityGroups = splitList(*securityGroups)
+			}
+			if explicit["assign-public-ip"] || !taskRun.AssignPublicIP {
+				taskRun.AssignPublicIP = *assignPublicIP
+			}
+		}
+		run.register(ctx, os.Stderr, ecsClient(), taskRun)
+	}
+	if *outputDir != "" || len(manifests) != 1 || run.failed() {
+		run.printSummary(os.Stderr)
+	}
+	if run.failed() {
+		os.Exit(1)
+	}
+}
 
-	if _, err := fmt.Fprintln(output); err != nil {
-		log.Printf("Failed to write newline: %v", err)
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
+}
 
-	if *outputFile != "" {
-		fmt.Printf("ECS task definition written to %s\n", *outputFile)
+// metadataRules builds the rules copying the selected labels and annotations to both
+// task definition tags and container dockerLabels
+func metadataRules(labels, annotations string) []ecs.MetadataRule {
+	targets := []ecs.MetadataTarget{ecs.MetadataTargetTag, ecs.MetadataTargetDockerLabel}
+
+	// Annotations are applied last so they win over labels with the same key
+	var rules []ecs.MetadataRule
+	for _, selection := range []struct {
+		source ecs.MetadataSource
+		keys   string
+	}{
+		{ecs.MetadataSourceLabel, labels},
+		{ecs.MetadataSourceAnnotation, annotations},
+	} {
+		if selection.keys == "" {
+			continue
+		}
+		rule := ecs.MetadataRule{Source: selection.source, Targets: targets}
+		rule.Keys = splitList(selection.keys)
+		rules = append(rules, rule)
 	}
+	return rules
 }
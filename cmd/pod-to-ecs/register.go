@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// register registers the converted task definitions with ECS and, when run is set, starts one
+// task of each. Documents whose registration or task fails are recorded as failed.
+func (c *conversion) register(ctx context.Context, w io.Writer, client ecsapi.Client, run *ecs.ProfileRun) {
+	for _, res := range c.converted() {
+		registered, err := client.RegisterTaskDefinition(ctx, res.taskDef)
+		if err != nil {
+			res.outcome, res.reason = outcomeFailed, fmt.Sprintf("failed to register task definition: %v", err)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "Registered %s\n", registered.TaskDefinitionArn)
+		if run == nil {
+			continue
+		}
+
+		input := ecsapi.NewRunTaskInput(registered, *run)
+		input.StartedBy = "pod-to-ecs"
+		output, err := client.RunTask(ctx, input)
+		if err != nil {
+			res.outcome, res.reason = outcomeFailed, fmt.Sprintf("failed to run task: %v", err)
+			continue
+		}
+		for _, failure := range output.Failures {
+			res.outcome = outcomeFailed
+			res.reason = strings.TrimSpace(fmt.Sprintf("failed to run task: %s %s", failure.Reason, failure.Detail))
+		}
+		for _, task := range output.Tasks {
+			_, _ = fmt.Fprintf(w, "Started task %s (%s)\n", task.TaskArn, task.LastStatus)
+		}
+	}
+}
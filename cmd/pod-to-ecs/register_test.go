@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+func TestConversion_RegisterAndRun(t *testing.T) {
+	fake := ecsapi.NewFake()
+	run := &conversion{results: []*result{
+		{outcome: outcomeConverted, taskDef: &ecs.ECSTaskDefinition{
+			Family:                  "shop-web",
+			NetworkMode:             "awsvpc",
+			RequiresCompatibilities: []string{"FARGATE"},
+			ContainerDefinitions:    []ecs.ECSContainerDefinition{{Name: "web", Image: "nginx", Essential: true}},
+		}},
+		{outcome: outcomeSkipped},
+	}}
+
+	var output bytes.Buffer
+	run.register(context.Background(), &output, fake, &ecs.ProfileRun{Cluster: "shop", Subnets: []string{"subnet-1"}})
+	if run.failed() {
+		t.Fatalf("register failed: %+v", run.results[0])
+	}
+	if !strings.Contains(output.String(), "Registered arn:aws:ecs:us-east-1:123456789012:task-definition/shop-web:1") {
+		t.Errorf("output = %q", output.String())
+	}
+
+	described, err := fake.DescribeTasks(context.Background(), "shop", []string{"00000000000000000000000000000001"})
+	if err != nil || len(described.Tasks) != 1 {
+		t.Fatalf("DescribeTasks() = %+v, %v", described, err)
+	}
+	if task := described.Tasks[0]; task.LaunchType != ecsapi.LaunchTypeFargate || task.StartedBy != "pod-to-ecs" {
+		t.Errorf("task = %+v, want a FARGATE task started by pod-to-ecs", task)
+	}
+
+	// Without subnets the awsvpc task cannot be started
+	run.register(context.Background(), &output, fake, &ecs.ProfileRun{Cluster: "shop"})
+	if !run.failed() || !strings.Contains(run.results[0].reason, "Network Configuration") {
+		t.Errorf("result = %+v, want a failed RunTask", run.results[0])
+	}
+}
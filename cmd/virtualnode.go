@@ -0,0 +1,132 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/takutakahashi/k8s-ecstask/internal/controller"
+	"github.com/takutakahashi/k8s-ecstask/internal/virtualnode"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// serviceAccountCA is the cluster CA mounted into pods, which also signs the client certificate
+// the API server presents to kubelets in most clusters
+const serviceAccountCA = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+
+// virtualNodeOptions are the flags of the virtual node standing for ECS capacity
+type virtualNodeOptions struct {
+	name     string
+	address  string
+	port     int
+	cpu      string
+	memory   string
+	pods     string
+	certPath string
+	clientCA string
+}
+
+func (o *virtualNodeOptions) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.name, "virtual-node", "",
+		"If set, a virtual Node of this name advertises ECS capacity; Pods scheduled to it run as ECS tasks. "+
+			"Requires --offload.")
+	fs.StringVar(&o.address, "virtual-node-address", os.Getenv("POD_IP"),
+		"IP address the API server reaches the kubelet API of the virtual node at (default: $POD_IP).")
+	fs.IntVar(&o.port, "virtual-node-port", 10250, "Port of the kubelet API of the virtual node.")
+	fs.StringVar(&o.cpu, "virtual-node-cpu", "1k", "CPU capacity the virtual node advertises.")
+	fs.StringVar(&o.memory, "virtual-node-memory", "4Ti", "Memory capacity the virtual node advertises.")
+	fs.StringVar(&o.pods, "virtual-node-pods", "1k", "Number of Pods the virtual node accepts.")
+	fs.StringVar(&o.certPath, "virtual-node-cert-path", "",
+		"Directory with the tls.crt and tls.key of the kubelet API (default: a self-signed certificate).")
+	fs.StringVar(&o.clientCA, "virtual-node-client-ca", serviceAccountCA,
+		"CA verifying the client certificate the API server presents to the kubelet API.")
+}
+
+// capacity parses the resources the node advertises
+func (o *virtualNodeOptions) capacity() (corev1.ResourceList, error) {
+	capacity := corev1.ResourceList{}
+	for name, value := range map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    o.cpu,
+		corev1.ResourceMemory: o.memory,
+		corev1.ResourcePods:   o.pods,
+	} {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s capacity %q: %w", name, value, err)
+		}
+		capacity[name] = quantity
+	}
+	return capacity, nil
+}
+
+// setup adds the virtual node and its kubelet API to the manager, and makes the offloader run
+// the pods bound to it
+func (o *virtualNodeOptions) setup(
+	ctx context.Context,
+	mgr ctrl.Manager,
+	offload *offloadOptions,
+	offloader *controller.Offloader,
+) error {
+	if offloader == nil {
+		return errors.New("--virtual-node requires --offload")
+	}
+	if o.address == "" {
+		return errors.New("--virtual-node-address or $POD_IP is required")
+	}
+	capacity, err := o.capacity()
+	if err != nil {
+		return err
+	}
+
+	// The node and its lease are read directly; the cache would watch every node and lease
+	uncached, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	logs, err := ecsapi.NewLogsClient(ctx, ecsapi.Config{Region: offload.region, Endpoint: offload.endpointURL})
+	if err != nil {
+		return fmt.Errorf("failed to create CloudWatch Logs client: %w", err)
+	}
+
+	offloader.NodeName = o.name
+	if err := mgr.Add(&virtualnode.Node{
+		Client:   uncached,
+		Name:     o.name,
+		Address:  o.address,
+		Port:     int32(o.port),
+		Capacity: capacity,
+	}); err != nil {
+		return err
+	}
+	return mgr.Add(&virtualnode.Server{
+		Client:       mgr.GetClient(),
+		ECS:          offloader.ECS,
+		Logs:         logs,
+		Addr:         fmt.Sprintf(":%d", o.port),
+		CertDir:      o.certPath,
+		ClientCAFile: o.clientCA,
+	})
+}
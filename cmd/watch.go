@@ -0,0 +1,77 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/takutakahashi/k8s-ecstask/internal/watch"
+	webhookv1 "github.com/takutakahashi/k8s-ecstask/internal/webhook/v1"
+)
+
+// watchOptions are the flags selecting the pods the controller and the webhooks handle
+type watchOptions struct {
+	selector                       string
+	namespaceSelector              string
+	mutatingWebhookConfiguration   string
+	validatingWebhookConfiguration string
+}
+
+func (o *watchOptions) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.selector, "watch-selector", watch.Label,
+		"Label selector of the Pods the controller and the webhooks handle, such as 'team in (web,batch)'.")
+	fs.StringVar(&o.namespaceSelector, "watch-namespace-selector", "",
+		"Label selector of the namespaces of watched Pods (default: every namespace).")
+	fs.StringVar(&o.mutatingWebhookConfiguration, "mutating-webhook-configuration",
+		"pod-controller-mutating-webhook-configuration",
+		"MutatingWebhookConfiguration whose Pod webhook selectors are set to the watch selectors.")
+	fs.StringVar(&o.validatingWebhookConfiguration, "validating-webhook-configuration",
+		"pod-controller-validating-webhook-configuration",
+		"ValidatingWebhookConfiguration whose Pod webhook selectors are set to the watch selectors.")
+}
+
+// matcher returns the matcher shared by the controller and the webhooks; namespaces are read
+// from the manager's cache
+func (o *watchOptions) matcher(mgr ctrl.Manager) (*watch.Matcher, error) {
+	selector, err := watch.ParseSelector(o.selector, o.namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	return watch.NewMatcher(selector, mgr.GetClient())
+}
+
+// setupWebhookSelectors makes the manager set the selectors of the webhooks to the ones of the
+// matcher, so that the API server filters pods like the controller
+func (o *watchOptions) setupWebhookSelectors(mgr ctrl.Manager, matcher *watch.Matcher) error {
+	// The configurations are read directly; the cache would watch all of them
+	uncached, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return mgr.Add(&watch.WebhookSelectors{
+		Client:                  uncached,
+		Selector:                matcher.Selector(),
+		MutatingConfiguration:   o.mutatingWebhookConfiguration,
+		ValidatingConfiguration: o.validatingWebhookConfiguration,
+		PodWebhooks:             []string{webhookv1.MutatingPodWebhookName, webhookv1.ValidatingPodWebhookName},
+		WorkloadWebhooks:        webhookv1.WorkloadWebhookNames,
+	})
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// TaskCleanupFinalizer holds an offloaded pod until its ECS task has stopped
+const TaskCleanupFinalizer = "ecs.takutakahashi.dev/task-cleanup"
+
+const (
+	// taskStopPollInterval is how often the task of a deleted pod is checked until it stops
+	taskStopPollInterval = 5 * time.Second
+	// taskStopStuckAfter is how long after the end of the grace period a task that has not
+	// stopped is reported as stuck
+	taskStopStuckAfter = 2 * time.Minute
+)
+
+// Event reasons of the task cleanup
+const (
+	eventReasonTaskStopping                   = "TaskStopping"
+	eventReasonTaskStopped                    = "TaskStopped"
+	eventReasonTaskStopStuck                  = "TaskStopStuck"
+	eventReasonTaskDefinitionDeregistered     = "TaskDefinitionDeregistered"
+	eventReasonTaskDefinitionDeregisterFailed = "TaskDefinitionDeregisterFailed"
+)
+
+// ManagedByTagKey tags the task definitions registered by the controller; only these are
+// deregistered when their last pod is deleted
+const ManagedByTagKey = "ecs.takutakahashi.dev/managed-by"
+
+// managedByTagValue is the value of ManagedByTagKey
+const managedByTagValue = "k8s-ecstask"
+
+// Limits of the stop timeout ECS accepts for Fargate containers, and its default
+const (
+	minStopTimeout     = 2
+	maxStopTimeout     = 120
+	defaultStopTimeout = 30
+)
+
+// applyGracePeriod makes ECS kill the containers after the pod's termination grace period
+// instead of its 30 second default. Containers with their own stop timeout keep it.
+func applyGracePeriod(pod *corev1.Pod, taskDef *ecs.ECSTaskDefinition) {
+	grace := pod.Spec.TerminationGracePeriodSeconds
+	if grace == nil || *grace == defaultStopTimeout {
+		return
+	}
+	timeout := max(int(*grace), minStopTimeout)
+	if slices.Contains(taskDef.RequiresCompatibilities, ecsapi.LaunchTypeFargate) {
+		timeout = min(timeout, maxStopTimeout)
+	}
+	for i := range taskDef.ContainerDefinitions {
+		if taskDef.ContainerDefinitions[i].StopTimeout == 0 {
+			taskDef.ContainerDefinitions[i].StopTimeout = timeout
+		}
+	}
+}
+
+// stopTask stops the tasks of the pod, reporting whether all of them have STOPPED and which
+// ones were asked to stop by this call. Tasks are found through the task annotation and, for
+// tasks started before it was recorded, through the pod UID they were started by.
+func (o *Offloader) stopTask(ctx context.Context, pod *corev1.Pod, reason string) (bool, []string, error) {
+	cluster := pod.Annotations[ecs.AnnotationCluster]
+	if cluster == "" {
+		cluster = o.cluster()
+	}
+	taskArns, err := o.ECS.ListTasks(ctx, cluster, string(pod.UID))
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if taskArn := pod.Annotations[ecs.AnnotationTaskArn]; taskArn != "" && !slices.Contains(taskArns, taskArn) {
+		taskArns = append(taskArns, taskArn)
+	}
+	if len(taskArns) == 0 {
+		return true, nil, nil
+	}
+
+	output, err := o.ECS.DescribeTasks(ctx, cluster, taskArns)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to describe tasks: %w", err)
+	}
+	allStopped := true
+	var requested []string
+	for _, task := range output.Tasks {
+		if task.LastStatus == ecsapi.TaskStatusStopped {
+			continue
+		}
+		allStopped = false
+		if task.DesiredStatus == ecsapi.TaskStatusStopped {
+			continue
+		}
+		if _, err := o.ECS.StopTask(ctx, cluster, task.TaskArn, reason); err != nil {
+			return false, requested, fmt.Errorf("failed to stop task %s: %w", task.TaskArn, err)
+		}
+		requested = append(requested, task.TaskArn)
+	}
+	return allStopped, requested, nil
+}
+
+// cleanup stops the task of a deleted pod, deregisters its task definition when configured,
+// and releases the finalizer once the task has stopped. Tasks still running well after the
+// grace period are reported as stuck.
+func (r *PodWatcherReconciler) cleanup(ctx context.Context, pod *corev1.Pod) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(pod, TaskCleanupFinalizer) {
+		return ctrl.Result{}, r.release(ctx, pod)
+	}
+	log := logf.FromContext(ctx)
+	key := client.ObjectKeyFromObject(pod)
+
+	stopped, requested, err := r.Offloader.stopTask(ctx, pod, fmt.Sprintf("Pod %s was deleted", key))
+	for _, taskArn := range requested {
+		r.event(pod, corev1.EventTypeNormal, eventReasonTaskStopping, "Stopping ECS task %s", taskArn)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !stopped {
+		// The deletion timestamp of a pod is the end of its grace period
+		overdue := time.Since(pod.DeletionTimestamp.Time)
+		if overdue > taskStopStuckAfter && r.markStuck(key, true) {
+			r.event(pod, corev1.EventTypeWarning, eventReasonTaskStopStuck,
+				"ECS task %s has not stopped %s after the grace period", pod.Annotations[ecs.AnnotationTaskArn],
+				overdue.Round(time.Second))
+		}
+		return ctrl.Result{RequeueAfter: taskStopPollInterval}, nil
+	}
+	r.markStuck(key, false)
+	r.trackPhase(key, "")
+
+	if r.Offloader.DeregisterTaskDefinitions {
+		if err := r.deregisterTaskDefinition(ctx, pod); err != nil {
+			r.event(pod, corev1.EventTypeWarning, eventReasonTaskDefinitionDeregisterFailed, "%v", err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	patch := client.MergeFrom(pod.DeepCopy())
+	controllerutil.RemoveFinalizer(pod, TaskCleanupFinalizer)
+	if err := r.Patch(ctx, pod, patch); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if taskArn := pod.Annotations[ecs.AnnotationTaskArn]; taskArn != "" {
+		r.event(pod, corev1.EventTypeNormal, eventReasonTaskStopped, "ECS task %s stopped", taskArn)
+	}
+	log.Info("Cleaned up ECS task of deleted Pod", "task", pod.Annotations[ecs.AnnotationTaskArn])
+	return ctrl.Result{}, r.release(ctx, pod)
+}
+
+// release removes a deleted pod bound to the virtual node, as the kubelet does for the pods of
+// other nodes once their containers have stopped
+func (r *PodWatcherReconciler) release(ctx context.Context, pod *corev1.Pod) error {
+	if !r.Offloader.onVirtualNode(pod) {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, pod, client.GracePeriodSeconds(0)))
+}
+
+// deregisterTaskDefinition deregisters the task definition revision of the pod when the
+// controller registered it and no other pod that is not being deleted uses it
+func (r *PodWatcherReconciler) deregisterTaskDefinition(ctx context.Context, pod *corev1.Pod) error {
+	taskDefinitionArn := pod.Annotations[ecs.AnnotationTaskDefinitionArn]
+	if taskDefinitionArn == "" {
+		return nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods); err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	for _, other := range pods.Items {
+		if other.UID != pod.UID && other.DeletionTimestamp == nil &&
+			other.Annotations[ecs.AnnotationTaskDefinitionArn] == taskDefinitionArn {
+			return nil
+		}
+	}
+
+	taskDef, err := r.Offloader.ECS.DescribeTaskDefinition(ctx, taskDefinitionArn)
+	if ecsapi.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to describe task definition %s: %w", taskDefinitionArn, err)
+	}
+	managed := slices.Contains(taskDef.Tags, ecs.ECSTag{Key: ManagedByTagKey, Value: managedByTagValue})
+	if !managed || taskDef.Status != ecsapi.TaskDefinitionStatusActive {
+		return nil
+	}
+	if _, err := r.Offloader.ECS.DeregisterTaskDefinition(ctx, taskDefinitionArn); err != nil {
+		return fmt.Errorf("failed to deregister task definition %s: %w", taskDefinitionArn, err)
+	}
+	r.event(pod, corev1.EventTypeNormal, eventReasonTaskDefinitionDeregistered,
+		"Deregistered task definition %s", taskDefinitionArn)
+	return nil
+}
+
+// markStuck tracks the pods whose task does not stop for the stuck deletions metric, reporting
+// whether the pod was not tracked as stuck before, so the stuck task is reported once per pod
+func (r *PodWatcherReconciler) markStuck(key types.NamespacedName, stuck bool) bool {
+	r.stuckMu.Lock()
+	defer r.stuckMu.Unlock()
+	if r.stuck == nil {
+		r.stuck = map[types.NamespacedName]bool{}
+	}
+	changed := r.stuck[key] != stuck
+	if stuck {
+		r.stuck[key] = true
+	} else {
+		delete(r.stuck, key)
+	}
+	stuckTaskStops.Set(float64(len(r.stuck)))
+	return changed
+}
+
+// event records an event on the pod when the reconciler has a recorder
+func (r *PodWatcherReconciler) event(pod *corev1.Pod, eventType, reason, format string, args ...any) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(pod, eventType, reason, format, args...)
+	}
+}
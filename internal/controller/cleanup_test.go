@@ -0,0 +1,189 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// drainEvents returns the events recorded so far
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestPodWatcher_CleanupStopsTaskBeforeReleasingPod(t *testing.T) {
+	ctx := context.Background()
+	ecsClient := ecsapi.NewFake()
+	r := newOffloadReconciler(t, ecsClient, offloadedPod("web-1"), offloadedPod("web-2"))
+	recorder := record.NewFakeRecorder(20)
+	r.Recorder = recorder
+	r.Offloader.DeregisterTaskDefinitions = true
+
+	first, err := reconcilePod(t, r, "web-1")
+	if err != nil {
+		t.Fatalf("Reconcile(web-1) error = %v", err)
+	}
+	if !controllerutil.ContainsFinalizer(first, TaskCleanupFinalizer) {
+		t.Fatalf("finalizers = %v, want %s", first.Finalizers, TaskCleanupFinalizer)
+	}
+	if _, err := reconcilePod(t, r, "web-2"); err != nil {
+		t.Fatalf("Reconcile(web-2) error = %v", err)
+	}
+	taskDefinitionArn := first.Annotations[ecs.AnnotationTaskDefinitionArn]
+	ecsClient.Advance()
+	ecsClient.Advance()
+	// Leave out the events of the offload
+	drainEvents(recorder)
+
+	if err := r.Delete(ctx, first); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	key := types.NamespacedName{Namespace: "shop", Name: "web-1"}
+	result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+	if err != nil || result.RequeueAfter != taskStopPollInterval {
+		t.Fatalf("Reconcile() of a deleted pod = %+v, %v, want a requeue until the task stops", result, err)
+	}
+	if events := drainEvents(recorder); len(events) != 1 || !strings.Contains(events[0], eventReasonTaskStopping) {
+		t.Errorf("events = %v, want %s", events, eventReasonTaskStopping)
+	}
+	if err := r.Get(ctx, key, &corev1.Pod{}); err != nil {
+		t.Fatalf("pod was released before its task stopped: %v", err)
+	}
+
+	ecsClient.Advance()
+	ecsClient.Advance()
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile() after the task stopped error = %v", err)
+	}
+	if err := r.Get(ctx, key, &corev1.Pod{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Get() after cleanup error = %v, want not found", err)
+	}
+	taskDef, _ := ecsClient.DescribeTaskDefinition(ctx, taskDefinitionArn)
+	if taskDef.Status != ecsapi.TaskDefinitionStatusActive {
+		t.Errorf("task definition still used by web-2 is %s", taskDef.Status)
+	}
+
+	// The last pod using the revision deregisters it
+	second := &corev1.Pod{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "shop", Name: "web-2"}, second); err != nil {
+		t.Fatalf("Get(web-2) error = %v", err)
+	}
+	if err := r.Delete(ctx, second); err != nil {
+		t.Fatalf("Delete(web-2) error = %v", err)
+	}
+	for range 3 {
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(second)})
+		if err != nil {
+			t.Fatalf("Reconcile(web-2) error = %v", err)
+		}
+		ecsClient.Advance()
+	}
+	taskDef, _ = ecsClient.DescribeTaskDefinition(ctx, taskDefinitionArn)
+	if taskDef.Status != ecsapi.TaskDefinitionStatusInactive {
+		t.Errorf("task definition of the deleted pods is %s, want INACTIVE", taskDef.Status)
+	}
+}
+
+func TestPodWatcher_CleanupReportsStuckTasks(t *testing.T) {
+	ctx := context.Background()
+	ecsClient := ecsapi.NewFake()
+	if _, err := ecsClient.RegisterTaskDefinition(ctx, &ecs.ECSTaskDefinition{
+		Family:               "web",
+		ContainerDefinitions: []ecs.ECSContainerDefinition{{Name: "web", Image: "nginx:1.27", Essential: true}},
+	}); err != nil {
+		t.Fatalf("RegisterTaskDefinition() error = %v", err)
+	}
+	output, err := ecsClient.RunTask(ctx, &ecsapi.RunTaskInput{Cluster: "apps", TaskDefinition: "web"})
+	if err != nil {
+		t.Fatalf("RunTask() error = %v", err)
+	}
+
+	pod := offloadedPod("stuck")
+	pod.Finalizers = []string{TaskCleanupFinalizer}
+	pod.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-10 * time.Minute)}
+	pod.Annotations[ecs.AnnotationTaskArn] = output.Tasks[0].TaskArn
+	pod.Annotations[ecs.AnnotationCluster] = "apps"
+	r := newOffloadReconciler(t, ecsClient, pod)
+	recorder := record.NewFakeRecorder(20)
+	r.Recorder = recorder
+
+	// The fake never advances the task to STOPPED
+	for range 2 {
+		if _, err := reconcilePod(t, r, "stuck"); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+	// The task is reported once, not on every poll
+	events := strings.Join(drainEvents(recorder), "\n")
+	if strings.Count(events, "Warning "+eventReasonTaskStopStuck) != 1 {
+		t.Errorf("events = %q, want one %s warning", events, eventReasonTaskStopStuck)
+	}
+	if stuck := testutil.ToFloat64(stuckTaskStops); stuck != 1 {
+		t.Errorf("stuck task stops = %v, want 1", stuck)
+	}
+
+	ecsClient.Advance()
+	ecsClient.Advance()
+	key := types.NamespacedName{Namespace: "shop", Name: "stuck"}
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if stuck := testutil.ToFloat64(stuckTaskStops); stuck != 0 {
+		t.Errorf("stuck task stops after the task stopped = %v, want 0", stuck)
+	}
+}
+
+func TestApplyGracePeriod(t *testing.T) {
+	taskDef := &ecs.ECSTaskDefinition{
+		RequiresCompatibilities: []string{"FARGATE"},
+		ContainerDefinitions:    []ecs.ECSContainerDefinition{{Name: "app"}, {Name: "proxy", StopTimeout: 10}},
+	}
+	pod := &corev1.Pod{Spec: corev1.PodSpec{TerminationGracePeriodSeconds: ptr.To[int64](600)}}
+
+	applyGracePeriod(pod, taskDef)
+	if got := taskDef.ContainerDefinitions[0].StopTimeout; got != maxStopTimeout {
+		t.Errorf("app stopTimeout = %d, want the Fargate maximum %d", got, maxStopTimeout)
+	}
+	if got := taskDef.ContainerDefinitions[1].StopTimeout; got != 10 {
+		t.Errorf("proxy stopTimeout = %d, want its own 10", got)
+	}
+}
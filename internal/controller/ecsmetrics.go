@@ -0,0 +1,94 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// instrumentedECS counts the calls of an ECS client and their errors
+type instrumentedECS struct {
+	client ecsapi.Client
+}
+
+// InstrumentECS returns a client recording the calls of client in the ECS API metrics
+func InstrumentECS(client ecsapi.Client) ecsapi.Client {
+	return &instrumentedECS{client: client}
+}
+
+func (c *instrumentedECS) RegisterTaskDefinition(
+	ctx context.Context,
+	taskDef *ecs.ECSTaskDefinition,
+) (*ecs.ECSTaskDefinition, error) {
+	registered, err := c.client.RegisterTaskDefinition(ctx, taskDef)
+	observeECSCall("RegisterTaskDefinition", err)
+	return registered, err
+}
+
+func (c *instrumentedECS) DeregisterTaskDefinition(
+	ctx context.Context,
+	taskDefinition string,
+) (*ecs.ECSTaskDefinition, error) {
+	deregistered, err := c.client.DeregisterTaskDefinition(ctx, taskDefinition)
+	observeECSCall("DeregisterTaskDefinition", err)
+	return deregistered, err
+}
+
+func (c *instrumentedECS) DescribeTaskDefinition(
+	ctx context.Context,
+	taskDefinition string,
+) (*ecs.ECSTaskDefinition, error) {
+	taskDef, err := c.client.DescribeTaskDefinition(ctx, taskDefinition)
+	// A missing family is how the controller finds out that it has no revision yet
+	if ecsapi.IsNotFound(err) {
+		observeECSCall("DescribeTaskDefinition", nil)
+	} else {
+		observeECSCall("DescribeTaskDefinition", err)
+	}
+	return taskDef, err
+}
+
+func (c *instrumentedECS) RunTask(ctx context.Context, input *ecsapi.RunTaskInput) (*ecsapi.RunTaskOutput, error) {
+	output, err := c.client.RunTask(ctx, input)
+	observeECSCall("RunTask", err)
+	return output, err
+}
+
+func (c *instrumentedECS) StopTask(ctx context.Context, cluster, task, reason string) (*ecsapi.Task, error) {
+	stopped, err := c.client.StopTask(ctx, cluster, task, reason)
+	observeECSCall("StopTask", err)
+	return stopped, err
+}
+
+func (c *instrumentedECS) DescribeTasks(
+	ctx context.Context,
+	cluster string,
+	tasks []string,
+) (*ecsapi.DescribeTasksOutput, error) {
+	output, err := c.client.DescribeTasks(ctx, cluster, tasks)
+	observeECSCall("DescribeTasks", err)
+	return output, err
+}
+
+func (c *instrumentedECS) ListTasks(ctx context.Context, cluster, startedBy string) ([]string, error) {
+	taskArns, err := c.client.ListTasks(ctx, cluster, startedBy)
+	observeECSCall("ListTasks", err)
+	return taskArns, err
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+// MetricsNamespace prefixes the metrics of the project
+const MetricsNamespace = "ecstask"
+
+// Results of conversions
+const (
+	conversionResultConverted = "converted"
+	conversionResultFailed    = "failed"
+)
+
+// stuckTaskStops counts deleted pods whose ECS task has not stopped long after their grace
+// period
+var stuckTaskStops = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: MetricsNamespace,
+	Name:      "stuck_task_stops",
+	Help:      "Number of deleted Pods whose ECS task has not stopped after the grace period.",
+})
+
+var (
+	conversions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "conversions_total",
+		Help:      "Number of Pod conversions to ECS task definitions by result.",
+	}, []string{"result"})
+	// conversionCodes counts the codes of the diagnostics of successful conversions and of the
+	// errors of failed ones
+	conversionCodes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "conversion_diagnostics_total",
+		Help:      "Number of diagnostics and errors reported by Pod conversions by result and code.",
+	}, []string{"result", "code"})
+	conversionDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "conversion_duration_seconds",
+		Help:      "Time taken to convert a Pod to an ECS task definition.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 12),
+	})
+)
+
+var (
+	ecsCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ecs_api_calls_total",
+		Help:      "Number of ECS API calls by operation.",
+	}, []string{"operation"})
+	ecsErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ecs_api_errors_total",
+		Help:      "Number of failed ECS API calls by operation and error code.",
+	}, []string{"operation", "code"})
+	ecsThrottles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ecs_api_throttles_total",
+		Help:      "Number of ECS API attempts rejected by the rate limits of the API by operation, including retried ones.",
+	}, []string{"operation"})
+)
+
+// offloadedPods counts the offloaded pods with a task by phase
+var offloadedPods = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: MetricsNamespace,
+	Name:      "offloaded_pods",
+	Help:      "Number of Pods running as ECS tasks by phase.",
+}, []string{"phase"})
+
+func init() {
+	metrics.Registry.MustRegister(stuckTaskStops, conversions, conversionCodes, conversionDuration, ecsCalls,
+		ecsErrors, ecsThrottles, offloadedPods)
+}
+
+// observeConversion records the result of a conversion with the codes of its diagnostics or
+// errors
+func observeConversion(diagnostics []ecs.Diagnostic, err error) {
+	if err != nil {
+		conversions.WithLabelValues(conversionResultFailed).Inc()
+		var errs ecs.ValidationErrors
+		if !errors.As(err, &errs) {
+			return
+		}
+		for _, validationErr := range errs {
+			conversionCodes.WithLabelValues(conversionResultFailed, string(validationErr.Code)).Inc()
+		}
+		return
+	}
+	conversions.WithLabelValues(conversionResultConverted).Inc()
+	for _, diagnostic := range diagnostics {
+		conversionCodes.WithLabelValues(conversionResultConverted, string(diagnostic.Code)).Inc()
+	}
+}
+
+// observeECSCall records an ECS API call and its error
+func observeECSCall(operation string, err error) {
+	ecsCalls.WithLabelValues(operation).Inc()
+	if err == nil {
+		return
+	}
+	code := "Unknown"
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code = apiErr.ErrorCode()
+	}
+	ecsErrors.WithLabelValues(operation, code).Inc()
+}
+
+// ObserveECSThrottle records an attempt of an ECS API call rejected by the rate limits of the
+// API. It is the ecsapi.Config.OnThrottle of the controller's client, which reports the attempts
+// the retryer absorbs as well, so observeECSCall does not count throttles again.
+func ObserveECSThrottle(operation string) {
+	ecsThrottles.WithLabelValues(operation).Inc()
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+func TestPodWatcher_Metrics(t *testing.T) {
+	invalid := offloadedPod("invalid")
+	invalid.Spec.InitContainers = []corev1.Container{{Name: "migrate", Image: "migrate:1.0"}}
+	ecsClient := ecsapi.NewFake()
+	r := newOffloadReconciler(t, InstrumentECS(ecsClient), offloadedPod("web"), invalid)
+
+	counters := []struct {
+		name    string
+		counter prometheus.Counter
+		want    float64
+		before  float64
+	}{
+		{name: "converted", counter: conversions.WithLabelValues(conversionResultConverted), want: 1},
+		{name: "failed", counter: conversions.WithLabelValues(conversionResultFailed), want: 1},
+		{name: "MissingTaskRole", counter: conversionCodes.WithLabelValues(conversionResultConverted,
+			"MissingTaskRole"), want: 1},
+		{name: "Unsupported", counter: conversionCodes.WithLabelValues(conversionResultFailed, "Unsupported"),
+			want: 1},
+		{name: "RunTask calls", counter: ecsCalls.WithLabelValues("RunTask"), want: 1},
+		// A missing family is not an error
+		{name: "DescribeTaskDefinition errors", counter: ecsErrors.WithLabelValues("DescribeTaskDefinition",
+			ecsapi.ErrorCodeClient)},
+	}
+	for i := range counters {
+		counters[i].before = testutil.ToFloat64(counters[i].counter)
+	}
+
+	if _, err := reconcilePod(t, r, "web"); err != nil {
+		t.Fatalf("Reconcile(web) error = %v", err)
+	}
+	_, _ = reconcilePod(t, r, "invalid")
+	// Syncing the status tracks the phase of the pod
+	if _, err := reconcilePod(t, r, "web"); err != nil {
+		t.Fatalf("Reconcile(web) error = %v", err)
+	}
+
+	for _, c := range counters {
+		if delta := testutil.ToFloat64(c.counter) - c.before; delta != c.want {
+			t.Errorf("%s increased by %v, want %v", c.name, delta, c.want)
+		}
+	}
+	if pending := testutil.ToFloat64(offloadedPods.WithLabelValues(string(corev1.PodPending))); pending != 1 {
+		t.Errorf("pending offloaded pods = %v, want 1", pending)
+	}
+}
+
+func TestObserveECSCall(t *testing.T) {
+	throttles := ecsThrottles.WithLabelValues("ListTasks")
+	errs := ecsErrors.WithLabelValues("ListTasks", ecsapi.ErrorCodeThrottling)
+	throttlesBefore, errsBefore := testutil.ToFloat64(throttles), testutil.ToFloat64(errs)
+
+	// Throttles are counted per attempt by ObserveECSThrottle, not again for the failed call
+	observeECSCall("ListTasks", fmt.Errorf("ListTasks failed: %w", &ecsapi.APIError{
+		StatusCode: 400, Code: ecsapi.ErrorCodeThrottling, Message: "Rate exceeded",
+	}))
+	if got := testutil.ToFloat64(errs) - errsBefore; got != 1 {
+		t.Errorf("errors increased by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(throttles) - throttlesBefore; got != 0 {
+		t.Errorf("throttles increased by %v, want 0", got)
+	}
+
+	ObserveECSThrottle("ListTasks")
+	if got := testutil.ToFloat64(throttles) - throttlesBefore; got != 1 {
+		t.Errorf("throttles increased by %v, want 1", got)
+	}
+}
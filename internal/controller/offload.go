@@ -0,0 +1,257 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// OffloadSchedulingGate keeps a watched pod away from the nodes of the cluster; the controller
+// runs pods carrying it as ECS tasks instead
+const OffloadSchedulingGate = "ecs.takutakahashi.dev/offload"
+
+// Event reasons of the offload
+const (
+	eventReasonConversionFailed         = "ConversionFailed"
+	eventReasonConversionWarnings       = "ConversionWarnings"
+	eventReasonTaskDefinitionRegistered = "TaskDefinitionRegistered"
+	eventReasonTaskStarted              = "TaskStarted"
+)
+
+const (
+	// defaultCluster is the cluster ECS uses when none is given
+	defaultCluster = "default"
+	// defaultStatusSyncPeriod is how often the tasks of offloaded pods are polled
+	defaultStatusSyncPeriod = 15 * time.Second
+)
+
+// Offloader converts watched pods and starts them as ECS tasks
+type Offloader struct {
+	ECS       ecsapi.Client
+	Converter *ecs.Converter
+	// Run selects the cluster, launch type and network configuration of the tasks
+	Run ecs.ProfileRun
+	// StatusSyncPeriod is how often the task of an offloaded pod is polled to update the pod
+	// status; zero selects 15 seconds
+	StatusSyncPeriod time.Duration
+	// DeregisterTaskDefinitions deregisters the revisions registered by the controller once the
+	// last pod using them is deleted
+	DeregisterTaskDefinitions bool
+	// NodeName is the virtual node of the ECS capacity; pods bound to it are run on ECS like
+	// gated pods. It is empty without a virtual node.
+	NodeName string
+}
+
+// ServiceAccountReader resolves the ServiceAccounts of pods for the converter
+type ServiceAccountReader struct {
+	client.Reader
+}
+
+// GetServiceAccount implements ecs.ServiceAccountResolver
+func (r ServiceAccountReader) GetServiceAccount(
+	ctx context.Context,
+	namespace, name string,
+) (*corev1.ServiceAccount, error) {
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, serviceAccount); err != nil {
+		return nil, err
+	}
+	return serviceAccount, nil
+}
+
+// isOffloaded reports whether the pod is run on ECS: it carries the offload scheduling gate
+// and was not bound to a node, or it was bound to the virtual node
+func (o *Offloader) isOffloaded(pod *corev1.Pod) bool {
+	if o.onVirtualNode(pod) {
+		return true
+	}
+	return pod.Spec.NodeName == "" && slices.ContainsFunc(pod.Spec.SchedulingGates,
+		func(gate corev1.PodSchedulingGate) bool { return gate.Name == OffloadSchedulingGate })
+}
+
+// onVirtualNode reports whether the scheduler bound the pod to the virtual node
+func (o *Offloader) onVirtualNode(pod *corev1.Pod) bool {
+	return o.NodeName != "" && pod.Spec.NodeName == o.NodeName
+}
+
+// cluster returns the ECS cluster tasks are started in
+func (o *Offloader) cluster() string {
+	if o.Run.Cluster == "" {
+		return defaultCluster
+	}
+	return o.Run.Cluster
+}
+
+// statusSyncPeriod returns the interval at which pod status is synchronized
+func (o *Offloader) statusSyncPeriod() time.Duration {
+	if o.StatusSyncPeriod <= 0 {
+		return defaultStatusSyncPeriod
+	}
+	return o.StatusSyncPeriod
+}
+
+// convert converts the pod to a task definition within the ECS limits, tagged as registered by
+// the controller, and reports the diagnostics of the conversion. Its errors do not go away by
+// retrying; the ones naming pod fields are ecs.ValidationErrors.
+func (o *Offloader) convert(ctx context.Context, pod *corev1.Pod) (*ecs.ECSTaskDefinition, []ecs.Diagnostic, error) {
+	start := time.Now()
+	taskDef, diagnostics, err := o.check(ctx, pod)
+	conversionDuration.Observe(time.Since(start).Seconds())
+	observeConversion(diagnostics, err)
+	return taskDef, diagnostics, err
+}
+
+// check converts the pod like convert without recording the metrics of the conversion
+func (o *Offloader) check(ctx context.Context, pod *corev1.Pod) (*ecs.ECSTaskDefinition, []ecs.Diagnostic, error) {
+	check := o.Converter.CheckPod(ctx, pod, ManagedECSConfig())
+	if !check.Convertible() {
+		return nil, check.Diagnostics, check.Errors
+	}
+	taskDef := check.TaskDefinition
+	FinishTaskDefinition(pod, taskDef)
+	if errs := taskDef.Validate(); len(errs) > 0 {
+		return nil, check.Diagnostics, fmt.Errorf("task definition %s violates ECS limits: %w", taskDef.Family, errs)
+	}
+	return taskDef, check.Diagnostics, nil
+}
+
+// ManagedECSConfig returns the explicit ECS configuration pods are converted with: the tag
+// marking the revisions the controller registers. The converter adds it with the other tags,
+// so it counts towards the tag limit of the task definition.
+func ManagedECSConfig() *ecs.ECSConfig {
+	return &ecs.ECSConfig{Tags: map[string]string{ManagedByTagKey: managedByTagValue}}
+}
+
+// FinishTaskDefinition applies what the controller adds to the task definition a pod converted
+// to with ManagedECSConfig: stop timeouts following the grace period of the pod
+func FinishTaskDefinition(pod *corev1.Pod, taskDef *ecs.ECSTaskDefinition) {
+	applyGracePeriod(pod, taskDef)
+}
+
+// register returns the latest ACTIVE revision of the family when it matches the task
+// definition, and registers a new revision otherwise, reporting whether it did. Pods of the
+// same workload therefore share one revision, and retries do not register duplicates.
+func (o *Offloader) register(
+	ctx context.Context,
+	taskDef *ecs.ECSTaskDefinition,
+) (*ecs.ECSTaskDefinition, bool, error) {
+	latest, err := o.ECS.DescribeTaskDefinition(ctx, taskDef.Family)
+	switch {
+	case ecsapi.IsNotFound(err):
+	case err != nil:
+		return nil, false, fmt.Errorf("failed to describe task definition %s: %w", taskDef.Family, err)
+	default:
+		changes, err := ecs.DiffTaskDefinitions(latest, taskDef)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to compare task definition %s: %w", taskDef.Family, err)
+		}
+		if len(changes) == 0 {
+			return latest, false, nil
+		}
+	}
+
+	registered, err := o.ECS.RegisterTaskDefinition(ctx, taskDef)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to register task definition %s: %w", taskDef.Family, err)
+	}
+	return registered, true, nil
+}
+
+// run starts the task of the pod and returns its ARN, reporting whether it started it. Tasks
+// are started by the pod UID, which is also the client token of RunTask: a task started before
+// a restart or a leader change is found again instead of being started twice.
+func (o *Offloader) run(ctx context.Context, pod *corev1.Pod, taskDef *ecs.ECSTaskDefinition) (string, bool, error) {
+	startedBy := string(pod.UID)
+	existing, err := o.ECS.ListTasks(ctx, o.cluster(), startedBy)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if len(existing) > 0 {
+		return existing[0], false, nil
+	}
+
+	input := ecsapi.NewRunTaskInput(taskDef, o.Run)
+	input.Cluster = o.cluster()
+	input.StartedBy = startedBy
+	input.ClientToken = startedBy
+	output, err := o.ECS.RunTask(ctx, input)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to run task: %w", err)
+	}
+	// Failures such as missing capacity are transient, so they are retried like API errors
+	if len(output.Failures) > 0 {
+		failure := output.Failures[0]
+		return "", false, fmt.Errorf("failed to run task: %s", strings.TrimSpace(failure.Reason+" "+failure.Detail))
+	}
+	if len(output.Tasks) == 0 {
+		return "", false, errors.New("RunTask returned no task")
+	}
+	return output.Tasks[0].TaskArn, true, nil
+}
+
+// annotate records the annotations on the pod with a merge patch
+func (r *PodWatcherReconciler) annotate(ctx context.Context, pod *corev1.Pod, annotations map[string]string) error {
+	patch := client.MergeFrom(pod.DeepCopy())
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	maps.Copy(pod.Annotations, annotations)
+	return r.Patch(ctx, pod, patch)
+}
+
+// describeConversionError describes why a pod does not convert, with the code of each field
+// error
+func describeConversionError(err error) string {
+	var errs ecs.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err.Error()
+	}
+	messages := make([]string, 0, len(errs))
+	for _, validationErr := range errs {
+		messages = append(messages, fmt.Sprintf("%s [%s]: %s", validationErr.Field, validationErr.Code,
+			validationErr.Message))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// warnings returns the diagnostics that need attention and their distinct codes
+func warnings(diagnostics []ecs.Diagnostic) ([]string, []string) {
+	var messages, codes []string
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == ecs.SeverityInfo {
+			continue
+		}
+		messages = append(messages, diagnostic.String())
+		if code := string(diagnostic.Code); !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return messages, codes
+}
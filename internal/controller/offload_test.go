@@ -0,0 +1,361 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// offloadedPod returns a watched pod held back by the offload scheduling gate
+func offloadedPod(name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "shop",
+			UID:       types.UID(name + "-uid"),
+			Labels:    map[string]string{"ecs.takutakahashi.dev/watch": "true"},
+			// Pods of a workload share a family
+			Annotations: map[string]string{ecs.AnnotationFamily: "web"},
+		},
+		Spec: corev1.PodSpec{
+			SchedulingGates: []corev1.PodSchedulingGate{{Name: OffloadSchedulingGate}},
+			Containers:      []corev1.Container{{Name: "web", Image: "nginx:1.27"}},
+		},
+	}
+}
+
+func newOffloadReconciler(t *testing.T, ecsClient ecsapi.Client, objects ...*corev1.Pod) *PodWatcherReconciler {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+	builder := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&corev1.Pod{})
+	for _, object := range objects {
+		builder = builder.WithObjects(object)
+	}
+	k8sClient := builder.Build()
+	return &PodWatcherReconciler{
+		Client: k8sClient,
+		Scheme: scheme,
+		Offloader: &Offloader{
+			ECS: ecsClient,
+			Converter: ecs.NewConverter(ecs.ConversionOptions{
+				DefaultCPU:      "256",
+				DefaultMemory:   "512",
+				ServiceAccounts: ServiceAccountReader{Reader: k8sClient},
+			}),
+			Run: ecs.ProfileRun{Cluster: "apps", Subnets: []string{"subnet-0abc"}},
+		},
+	}
+}
+
+func reconcilePod(t *testing.T, r *PodWatcherReconciler, name string) (*corev1.Pod, error) {
+	t.Helper()
+	key := types.NamespacedName{Namespace: "shop", Name: name}
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+	pod := &corev1.Pod{}
+	if getErr := r.Get(context.Background(), key, pod); getErr != nil {
+		t.Fatalf("Get() error = %v", getErr)
+	}
+	return pod, err
+}
+
+func TestPodWatcher_OffloadStartsOneTask(t *testing.T) {
+	ecsClient := ecsapi.NewFake()
+	r := newOffloadReconciler(t, ecsClient, offloadedPod("web-1"), offloadedPod("web-2"))
+
+	first, err := reconcilePod(t, r, "web-1")
+	if err != nil {
+		t.Fatalf("Reconcile(web-1) error = %v", err)
+	}
+	taskArn := first.Annotations[ecs.AnnotationTaskArn]
+	if taskArn == "" || first.Annotations[ecs.AnnotationCluster] != "apps" ||
+		first.Annotations[ecs.AnnotationTaskDefinitionArn] == "" {
+		t.Fatalf("annotations = %v, want the task, cluster and task definition", first.Annotations)
+	}
+
+	// Reconciling again, or after the annotations were lost, does not start another task
+	current, err := reconcilePod(t, r, "web-1")
+	if err != nil {
+		t.Fatalf("second Reconcile(web-1) error = %v", err)
+	}
+	lost := current.DeepCopy()
+	delete(lost.Annotations, ecs.AnnotationTaskArn)
+	if err := r.Update(context.Background(), lost); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	restarted, err := reconcilePod(t, r, "web-1")
+	if err != nil {
+		t.Fatalf("Reconcile(web-1) after losing the annotation error = %v", err)
+	}
+	if restarted.Annotations[ecs.AnnotationTaskArn] != taskArn {
+		t.Errorf("task = %s, want the existing task %s", restarted.Annotations[ecs.AnnotationTaskArn], taskArn)
+	}
+	if tasks, _ := ecsClient.ListTasks(context.Background(), "apps", "web-1-uid"); len(tasks) != 1 {
+		t.Errorf("tasks of web-1 = %v, want exactly one", tasks)
+	}
+
+	// Pods converting to the same task definition share its revision
+	second, err := reconcilePod(t, r, "web-2")
+	if err != nil {
+		t.Fatalf("Reconcile(web-2) error = %v", err)
+	}
+	if second.Annotations[ecs.AnnotationTaskDefinitionArn] != first.Annotations[ecs.AnnotationTaskDefinitionArn] ||
+		second.Annotations[ecs.AnnotationTaskArn] == taskArn {
+		t.Errorf("web-2 annotations = %v", second.Annotations)
+	}
+}
+
+func TestPodWatcher_OffloadSkipsPods(t *testing.T) {
+	scheduled := offloadedPod("scheduled")
+	scheduled.Spec.NodeName = "node-1"
+	ungated := offloadedPod("ungated")
+	ungated.Spec.SchedulingGates = nil
+
+	ecsClient := ecsapi.NewFake()
+	r := newOffloadReconciler(t, ecsClient, scheduled, ungated)
+	for _, name := range []string{"scheduled", "ungated"} {
+		pod, err := reconcilePod(t, r, name)
+		if err != nil {
+			t.Fatalf("Reconcile(%s) error = %v", name, err)
+		}
+		if arn := pod.Annotations[ecs.AnnotationTaskArn]; arn != "" {
+			t.Errorf("%s was offloaded as %s", name, arn)
+		}
+	}
+	if tasks, _ := ecsClient.ListTasks(context.Background(), "apps", ""); len(tasks) != 0 {
+		t.Errorf("tasks = %v, want none", tasks)
+	}
+}
+
+func TestPodWatcher_OffloadErrors(t *testing.T) {
+	invalid := offloadedPod("invalid")
+	invalid.Spec.InitContainers = []corev1.Container{{Name: "migrate", Image: "migrate:1.0"}}
+	unplaced := offloadedPod("unplaced")
+	// The tags of the pod and the managed-by tag of the controller exceed the limit of 50
+	overtagged := offloadedPod("overtagged")
+	tags := make([]string, ecs.MaxTagsPerResource)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("tag%d=value", i)
+	}
+	overtagged.Annotations[ecs.AnnotationTags] = strings.Join(tags, ",")
+
+	ecsClient := ecsapi.NewFake()
+	r := newOffloadReconciler(t, ecsClient, invalid, unplaced, overtagged)
+
+	if _, err := reconcilePod(t, r, "invalid"); !errors.Is(err, reconcile.TerminalError(nil)) {
+		t.Errorf("Reconcile(invalid) error = %v, want a terminal error", err)
+	}
+	_, err := reconcilePod(t, r, "overtagged")
+	if !errors.Is(err, reconcile.TerminalError(nil)) || !strings.Contains(err.Error(), "51 tags") {
+		t.Errorf("Reconcile(overtagged) error = %v, want a terminal error for 51 tags", err)
+	}
+	if registered, _ := ecsClient.DescribeTaskDefinition(context.Background(), "web"); registered != nil {
+		t.Errorf("overtagged pod registered %s", registered.TaskDefinitionArn)
+	}
+
+	// ECS rejects awsvpc tasks without subnets; the error is retried with backoff
+	r.Offloader.Run.Subnets = nil
+	pod, err := reconcilePod(t, r, "unplaced")
+	if err == nil || errors.Is(err, reconcile.TerminalError(nil)) {
+		t.Errorf("Reconcile(unplaced) error = %v, want a retryable error", err)
+	}
+	if arn := pod.Annotations[ecs.AnnotationTaskArn]; arn != "" {
+		t.Errorf("unplaced pod recorded task %s", arn)
+	}
+}
+
+func TestPodWatcher_SyncsTaskStatus(t *testing.T) {
+	ctx := context.Background()
+	ecsClient := ecsapi.NewFake()
+	r := newOffloadReconciler(t, ecsClient, offloadedPod("job"))
+	key := types.NamespacedName{Namespace: "shop", Name: "job"}
+
+	pod, err := reconcilePod(t, r, "job")
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	taskArn := pod.Annotations[ecs.AnnotationTaskArn]
+
+	result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+	if err != nil || result.RequeueAfter != defaultStatusSyncPeriod {
+		t.Fatalf("Reconcile() = %+v, %v, want a requeue after the sync period", result, err)
+	}
+	pod, _ = reconcilePod(t, r, "job")
+	if pod.Status.Phase != corev1.PodPending {
+		t.Errorf("phase of a provisioning task = %s, want Pending", pod.Status.Phase)
+	}
+
+	ecsClient.Advance()
+	ecsClient.Advance()
+	pod, _ = reconcilePod(t, r, "job")
+	if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" ||
+		len(pod.Status.ContainerStatuses) != 1 || !pod.Status.ContainerStatuses[0].Ready {
+		t.Errorf("status of a running task = %+v", pod.Status)
+	}
+
+	if err := ecsClient.ExitContainer(taskArn, "web", 0); err != nil {
+		t.Fatalf("ExitContainer() error = %v", err)
+	}
+	ecsClient.Advance()
+	ecsClient.Advance()
+	result, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+	if err != nil || result.RequeueAfter != 0 {
+		t.Errorf("Reconcile() of a stopped task = %+v, %v, want no requeue", result, err)
+	}
+	pod, _ = reconcilePod(t, r, "job")
+	if pod.Status.Phase != corev1.PodSucceeded {
+		t.Errorf("phase of a completed task = %s, want Succeeded", pod.Status.Phase)
+	}
+}
+
+func TestPodWatcher_OffloadsPodsOnTheVirtualNode(t *testing.T) {
+	ctx := context.Background()
+	bound := offloadedPod("bound")
+	bound.Spec.SchedulingGates = nil
+	bound.Spec.NodeName = "ecs"
+	bound.Labels = nil
+	elsewhere := bound.DeepCopy()
+	elsewhere.Name, elsewhere.UID = "elsewhere", "elsewhere-uid"
+	elsewhere.Spec.NodeName = "node-1"
+
+	ecsClient := ecsapi.NewFake()
+	r := newOffloadReconciler(t, ecsClient, bound, elsewhere)
+	r.Offloader.NodeName = "ecs"
+	if !r.watches(bound) || r.watches(elsewhere) {
+		t.Errorf("watches(bound) = %v, watches(elsewhere) = %v", r.watches(bound), r.watches(elsewhere))
+	}
+
+	pod, err := reconcilePod(t, r, "bound")
+	if err != nil {
+		t.Fatalf("Reconcile(bound) error = %v", err)
+	}
+	if pod.Annotations[ecs.AnnotationTaskArn] == "" {
+		t.Fatalf("pod bound to the virtual node was not offloaded: %v", pod.Annotations)
+	}
+	if other, _ := reconcilePod(t, r, "elsewhere"); other.Annotations[ecs.AnnotationTaskArn] != "" {
+		t.Errorf("pod bound to another node was offloaded")
+	}
+
+	// Deleting the pod stops its task before the pod goes away
+	if err := r.Delete(ctx, pod); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	for range 3 {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: "shop", Name: "bound"}}); err != nil {
+			t.Fatalf("Reconcile() of the deleted pod error = %v", err)
+		}
+		ecsClient.Advance()
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "shop", Name: "bound"}, &corev1.Pod{}); err == nil {
+		t.Error("deleted pod on the virtual node was not released")
+	}
+}
+
+func TestPodWatcher_OffloadEventsAndConditions(t *testing.T) {
+	invalid := offloadedPod("invalid")
+	invalid.Spec.InitContainers = []corev1.Container{{Name: "migrate", Image: "migrate:1.0"}}
+	ecsClient := ecsapi.NewFake()
+	r := newOffloadReconciler(t, ecsClient, offloadedPod("web-1"), offloadedPod("web-2"), invalid)
+	recorder := record.NewFakeRecorder(20)
+	r.Recorder = recorder
+
+	// The events carry the codes of the diagnostics
+	first, err := reconcilePod(t, r, "web-1")
+	if err != nil {
+		t.Fatalf("Reconcile(web-1) error = %v", err)
+	}
+	events := drainEvents(recorder)
+	if len(events) != 3 || !strings.HasPrefix(events[0], "Warning "+eventReasonConversionWarnings) ||
+		!strings.Contains(events[0], "[MissingTaskRole] taskRoleArn") ||
+		!strings.HasPrefix(events[1], "Normal "+eventReasonTaskDefinitionRegistered) ||
+		!strings.HasPrefix(events[2], "Normal "+eventReasonTaskStarted) {
+		t.Errorf("events = %v", events)
+	}
+	condition := podCondition(first, ConditionConverted)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != reasonConverted ||
+		!strings.HasSuffix(condition.Message, "with warnings: ServiceAccountNotFound, MissingTaskRole") {
+		t.Errorf("Converted condition = %+v", condition)
+	}
+
+	// Pods sharing a revision do not register it again
+	if _, err := reconcilePod(t, r, "web-2"); err != nil {
+		t.Fatalf("Reconcile(web-2) error = %v", err)
+	}
+	if events := strings.Join(drainEvents(recorder), "\n"); strings.Contains(events,
+		eventReasonTaskDefinitionRegistered) {
+		t.Errorf("events of a shared revision = %s", events)
+	}
+
+	pod, _ := reconcilePod(t, r, "invalid")
+	events = drainEvents(recorder)
+	if len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+eventReasonConversionFailed) ||
+		!strings.Contains(events[0], "spec.initContainers [Unsupported]") {
+		t.Errorf("events of an invalid pod = %v", events)
+	}
+	if condition := podCondition(pod, ConditionConverted); condition == nil ||
+		condition.Status != corev1.ConditionFalse || condition.Reason != reasonConversionFailed {
+		t.Errorf("Converted condition of an invalid pod = %+v", condition)
+	}
+
+	// Stopped tasks are reported with the exit code of their containers
+	if err := ecsClient.ExitContainer(first.Annotations[ecs.AnnotationTaskArn], "web", 1); err != nil {
+		t.Fatalf("ExitContainer() error = %v", err)
+	}
+	for range 4 {
+		ecsClient.Advance()
+	}
+	if _, err := reconcilePod(t, r, "web-1"); err != nil {
+		t.Fatalf("Reconcile(web-1) error = %v", err)
+	}
+	events = drainEvents(recorder)
+	if len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+eventReasonTaskStopped) ||
+		!strings.Contains(events[0], "web exited with 1 (Error)") {
+		t.Errorf("events of a stopped task = %v", events)
+	}
+	if _, err := reconcilePod(t, r, "web-1"); err != nil || len(drainEvents(recorder)) != 0 {
+		t.Errorf("Reconcile() of a stopped pod = %v, recorded events again", err)
+	}
+}
+
+// podCondition returns the condition of the pod of the type, or nil
+func podCondition(pod *corev1.Pod, conditionType corev1.PodConditionType) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == conditionType {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
@@ -18,25 +18,62 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/takutakahashi/k8s-ecstask/internal/watch"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// Retries of failed ECS calls back off exponentially between these delays
+const (
+	offloadRetryBaseDelay = 5 * time.Second
+	offloadRetryMaxDelay  = 5 * time.Minute
 )
 
 // PodWatcherReconciler reconciles a PodWatcher object
 type PodWatcherReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Offloader runs watched pods as ECS tasks. Without it pods are only logged.
+	Offloader *Offloader
+	// Recorder records events on offloaded pods; it is optional
+	Recorder record.EventRecorder
+	// Matcher selects the watched pods (default: the pods with the watch label)
+	Matcher *watch.Matcher
+
+	stuckMu sync.Mutex
+	stuck   map[types.NamespacedName]bool
+
+	phasesMu sync.Mutex
+	phases   map[types.NamespacedName]corev1.PodPhase
 }
 
-// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
-// +kubebuilder:rbac:groups="",resources=pods/status,verbs=get
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -50,12 +87,201 @@ func (r *PodWatcherReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			log.Error(err, "Failed to get Pod")
 			return ctrl.Result{}, err
 		}
+		r.trackPhase(req.NamespacedName, "")
+		r.markStuck(req.NamespacedName, false)
 		return ctrl.Result{}, nil
 	}
 
 	log.Info("Reconciling Pod", "namespace", pod.Namespace, "name", pod.Name, "labels", pod.Labels)
 
-	return ctrl.Result{}, nil
+	if r.Offloader == nil {
+		return ctrl.Result{}, nil
+	}
+	if pod.DeletionTimestamp != nil {
+		return r.cleanup(ctx, pod)
+	}
+	if !r.Offloader.isOffloaded(pod) {
+		return ctrl.Result{}, nil
+	}
+	if pod.Annotations[ecs.AnnotationTaskArn] != "" {
+		return r.syncStatus(ctx, pod)
+	}
+	// Recording the task updates the pod, which reconciles it again to sync its status
+	return ctrl.Result{}, r.offload(ctx, pod)
+}
+
+// syncStatus writes the state of the pod's task into the pod status through the status
+// subresource, and polls the task again until the pod reaches a terminal phase
+func (r *PodWatcherReconciler) syncStatus(ctx context.Context, pod *corev1.Pod) (ctrl.Result, error) {
+	key := client.ObjectKeyFromObject(pod)
+	r.trackPhase(key, pod.Status.Phase)
+	if isTerminal(pod.Status.Phase) {
+		return ctrl.Result{}, nil
+	}
+	log := logf.FromContext(ctx)
+
+	taskArn := pod.Annotations[ecs.AnnotationTaskArn]
+	output, err := r.Offloader.ECS.DescribeTasks(ctx, pod.Annotations[ecs.AnnotationCluster], []string{taskArn})
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to describe task %s: %w", taskArn, err)
+	}
+	var task *ecsapi.Task
+	if len(output.Tasks) > 0 {
+		task = &output.Tasks[0]
+	} else if len(output.Failures) > 0 && output.Failures[0].Reason != "MISSING" {
+		failure := output.Failures[0]
+		return ctrl.Result{}, fmt.Errorf("failed to describe task %s: %s %s", taskArn, failure.Reason, failure.Detail)
+	}
+
+	status := taskPodStatus(pod, task, time.Now())
+	if !equality.Semantic.DeepEqual(pod.Status, status) {
+		patch := client.MergeFrom(pod.DeepCopy())
+		pod.Status = status
+		if err := r.Status().Patch(ctx, pod, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info("Updated Pod status from ECS task", "task", taskArn, "phase", status.Phase)
+		r.trackPhase(key, status.Phase)
+		if isTerminal(status.Phase) {
+			eventType := corev1.EventTypeNormal
+			if status.Phase == corev1.PodFailed {
+				eventType = corev1.EventTypeWarning
+			}
+			r.event(pod, eventType, eventReasonTaskStopped, "%s", stoppedMessage(taskArn, &status))
+		}
+	}
+
+	if isTerminal(status.Phase) {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: r.Offloader.statusSyncPeriod()}, nil
+}
+
+// offload converts the pod, registers its task definition and starts its task, then records
+// both in the pod annotations. Conversion errors are terminal; ECS errors are returned so that
+// the request is retried with backoff.
+func (r *PodWatcherReconciler) offload(ctx context.Context, pod *corev1.Pod) error {
+	log := logf.FromContext(ctx)
+
+	taskDef, diagnostics, err := r.Offloader.convert(ctx, pod)
+	if err != nil {
+		log.Error(err, "Failed to convert Pod")
+		message := describeConversionError(err)
+		r.event(pod, corev1.EventTypeWarning, eventReasonConversionFailed,
+			"Pod does not convert to an ECS task definition: %s", message)
+		if err := r.setConverted(ctx, pod, false, reasonConversionFailed, message); err != nil {
+			return err
+		}
+		return reconcile.TerminalError(err)
+	}
+	for _, diagnostic := range diagnostics {
+		log.Info("Conversion diagnostic", "diagnostic", diagnostic.String())
+	}
+	message := fmt.Sprintf("Converted to task definition family %s", taskDef.Family)
+	if warnings, codes := warnings(diagnostics); len(warnings) > 0 {
+		r.event(pod, corev1.EventTypeWarning, eventReasonConversionWarnings, "%s", strings.Join(warnings, "; "))
+		message += " with warnings: " + strings.Join(codes, ", ")
+	}
+	if err := r.setConverted(ctx, pod, true, reasonConverted, message); err != nil {
+		return err
+	}
+
+	registered, created, err := r.Offloader.register(ctx, taskDef)
+	if err != nil {
+		return err
+	}
+	if created {
+		r.event(pod, corev1.EventTypeNormal, eventReasonTaskDefinitionRegistered, "Registered task definition %s",
+			registered.TaskDefinitionArn)
+	}
+	// The finalizer is in place before the task exists, so that no task outlives its pod
+	if !controllerutil.ContainsFinalizer(pod, TaskCleanupFinalizer) {
+		patch := client.MergeFrom(pod.DeepCopy())
+		controllerutil.AddFinalizer(pod, TaskCleanupFinalizer)
+		if err := r.Patch(ctx, pod, patch); err != nil {
+			return err
+		}
+	}
+	taskArn, started, err := r.Offloader.run(ctx, pod, registered)
+	if err != nil {
+		return err
+	}
+	if started {
+		r.event(pod, corev1.EventTypeNormal, eventReasonTaskStarted, "Started ECS task %s in cluster %s", taskArn,
+			r.Offloader.cluster())
+	}
+
+	if err := r.annotate(ctx, pod, map[string]string{
+		ecs.AnnotationTaskDefinitionArn: registered.TaskDefinitionArn,
+		ecs.AnnotationTaskArn:           taskArn,
+		ecs.AnnotationCluster:           r.Offloader.cluster(),
+	}); err != nil {
+		return err
+	}
+	log.Info("Offloaded Pod to ECS", "taskDefinition", registered.TaskDefinitionArn, "task", taskArn)
+	return nil
+}
+
+// trackPhase tracks the phase of an offloaded pod for the offloaded pods metric; an empty phase
+// forgets the pod
+func (r *PodWatcherReconciler) trackPhase(key types.NamespacedName, phase corev1.PodPhase) {
+	r.phasesMu.Lock()
+	defer r.phasesMu.Unlock()
+	if r.phases == nil {
+		r.phases = map[types.NamespacedName]corev1.PodPhase{}
+	}
+	if phase == "" {
+		delete(r.phases, key)
+	} else {
+		r.phases[key] = phase
+	}
+	counts := map[corev1.PodPhase]int{}
+	for _, phase := range r.phases {
+		counts[phase]++
+	}
+	for _, phase := range []corev1.PodPhase{
+		corev1.PodPending, corev1.PodRunning, corev1.PodSucceeded, corev1.PodFailed, corev1.PodUnknown,
+	} {
+		offloadedPods.WithLabelValues(string(phase)).Set(float64(counts[phase]))
+	}
+}
+
+// setConverted sets the Converted condition of the pod through the status subresource when it
+// changed
+func (r *PodWatcherReconciler) setConverted(
+	ctx context.Context,
+	pod *corev1.Pod,
+	converted bool,
+	reason, message string,
+) error {
+	status := *pod.Status.DeepCopy()
+	now := metav1.NewTime(time.Now().UTC().Truncate(time.Second))
+	setCondition(&status, ConditionConverted, converted, reason, message, now)
+	if equality.Semantic.DeepEqual(pod.Status, status) {
+		return nil
+	}
+	patch := client.MergeFrom(pod.DeepCopy())
+	pod.Status = status
+	return r.Status().Patch(ctx, pod, patch)
+}
+
+// watches reports whether the controller handles the pod: it is selected by the matcher, was
+// bound to the virtual node, or was offloaded before it stopped being selected
+func (r *PodWatcherReconciler) watches(pod *corev1.Pod) bool {
+	if r.Offloader != nil && (r.Offloader.onVirtualNode(pod) ||
+		controllerutil.ContainsFinalizer(pod, TaskCleanupFinalizer)) {
+		return true
+	}
+	matcher := r.Matcher
+	if matcher == nil {
+		matcher = watch.DefaultMatcher()
+	}
+	// Namespaces are read from the cache, so the lookup does not need a deadline
+	watched, err := matcher.Matches(context.Background(), pod, pod.Namespace)
+	if err != nil {
+		logf.Log.Error(err, "Failed to match Pod", "namespace", pod.Namespace, "name", pod.Name)
+	}
+	return watched
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -63,8 +289,8 @@ func (r *PodWatcherReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	labelPredicate := predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
 			if pod, ok := e.Object.(*corev1.Pod); ok {
-				if _, exists := pod.Labels["ecs.takutakahashi.dev/watch"]; exists {
-					logf.Log.Info("Pod with watch label created", "namespace", pod.Namespace, "name", pod.Name)
+				if r.watches(pod) {
+					logf.Log.Info("Watched Pod created", "namespace", pod.Namespace, "name", pod.Name)
 					return true
 				}
 			}
@@ -72,7 +298,7 @@ func (r *PodWatcherReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			if pod, ok := e.ObjectNew.(*corev1.Pod); ok {
-				if _, exists := pod.Labels["ecs.takutakahashi.dev/watch"]; exists {
+				if r.watches(pod) {
 					return true
 				}
 			}
@@ -80,8 +306,8 @@ func (r *PodWatcherReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
 			if pod, ok := e.Object.(*corev1.Pod); ok {
-				if _, exists := pod.Labels["ecs.takutakahashi.dev/watch"]; exists {
-					logf.Log.Info("Pod with watch label deleted", "namespace", pod.Namespace, "name", pod.Name)
+				if r.watches(pod) {
+					logf.Log.Info("Watched Pod deleted", "namespace", pod.Namespace, "name", pod.Name)
 					return true
 				}
 			}
@@ -89,8 +315,15 @@ func (r *PodWatcherReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		},
 	}
 
+	options := controller.Options{}
+	if r.Offloader != nil {
+		options.RateLimiter = workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](
+			offloadRetryBaseDelay, offloadRetryMaxDelay)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Pod{}, builder.WithPredicates(labelPredicate)).
+		WithOptions(options).
 		Named("podwatcher").
 		Complete(r)
 }
@@ -0,0 +1,236 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// Reasons of the pod status written for ECS tasks
+const (
+	reasonTaskNotFound      = "TaskNotFound"
+	reasonTaskProvisioning  = "TaskProvisioning"
+	reasonContainerCreating = "ContainerCreating"
+	reasonCompleted         = "Completed"
+	reasonError             = "Error"
+	reasonUnhealthy         = "Unhealthy"
+)
+
+// ConditionConverted tells whether a watched pod converts to an ECS task definition. It is set
+// when the controller offloads the pod; its message names the codes of the diagnostics or the
+// fields that do not convert.
+const ConditionConverted corev1.PodConditionType = "ecs.takutakahashi.dev/Converted"
+
+// Reasons of the Converted condition
+const (
+	reasonConverted        = "Converted"
+	reasonConversionFailed = "ConversionFailed"
+)
+
+// containerHealthUnhealthy is the healthStatus of containers failing their health check
+const containerHealthUnhealthy = "UNHEALTHY"
+
+// isTerminal reports whether the pod phase can no longer change
+func isTerminal(phase corev1.PodPhase) bool {
+	return phase == corev1.PodSucceeded || phase == corev1.PodFailed
+}
+
+// podTime converts an ECS timestamp to the second precision the API server stores
+func podTime(timestamp *ecs.ECSTimestamp) *metav1.Time {
+	if timestamp == nil {
+		return nil
+	}
+	converted := metav1.NewTime(timestamp.Time.UTC().Truncate(time.Second))
+	return &converted
+}
+
+// podPhase maps the task state to a pod phase. Stopping tasks are still Running; stopped
+// tasks succeeded when every container exited with 0.
+func podPhase(task *ecsapi.Task) corev1.PodPhase {
+	switch task.LastStatus {
+	case ecsapi.TaskStatusProvisioning, ecsapi.TaskStatusPending, ecsapi.TaskStatusActivating:
+		return corev1.PodPending
+	case ecsapi.TaskStatusStopped:
+		if len(task.Containers) > 0 && !slices.ContainsFunc(task.Containers, func(c ecsapi.Container) bool {
+			return c.ExitCode == nil || *c.ExitCode != 0
+		}) {
+			return corev1.PodSucceeded
+		}
+		return corev1.PodFailed
+	default:
+		return corev1.PodRunning
+	}
+}
+
+// containerStatus maps an ECS container to the status of the pod container of the same name
+func containerStatus(task *ecsapi.Task, spec corev1.Container, container *ecsapi.Container) corev1.ContainerStatus {
+	status := corev1.ContainerStatus{Name: spec.Name, Image: spec.Image}
+	if container == nil {
+		status.State.Waiting = &corev1.ContainerStateWaiting{Reason: reasonContainerCreating}
+		return status
+	}
+	status.ContainerID = fmt.Sprintf("ecs://%s/%s", task.ID(), container.Name)
+
+	switch {
+	case container.ExitCode != nil || container.LastStatus == ecsapi.TaskStatusStopped:
+		terminated := &corev1.ContainerStateTerminated{Reason: container.Reason, Message: task.StoppedReason}
+		if container.ExitCode != nil {
+			terminated.ExitCode = int32(*container.ExitCode)
+		}
+		if terminated.Reason == "" {
+			terminated.Reason = reasonCompleted
+			if terminated.ExitCode != 0 || container.ExitCode == nil {
+				terminated.Reason = reasonError
+			}
+		}
+		if startedAt := podTime(task.StartedAt); startedAt != nil {
+			terminated.StartedAt = *startedAt
+		}
+		if finishedAt := podTime(task.StoppedAt); finishedAt != nil {
+			terminated.FinishedAt = *finishedAt
+		}
+		status.State.Terminated = terminated
+	case container.LastStatus == ecsapi.TaskStatusRunning:
+		running := &corev1.ContainerStateRunning{}
+		if startedAt := podTime(task.StartedAt); startedAt != nil {
+			running.StartedAt = *startedAt
+		}
+		status.State.Running = running
+		status.Ready = container.HealthStatus != containerHealthUnhealthy
+	default:
+		status.State.Waiting = &corev1.ContainerStateWaiting{Reason: reasonContainerCreating}
+	}
+	started := status.State.Running != nil
+	status.Started = &started
+	return status
+}
+
+// setCondition sets a pod condition, keeping its transition time while the status is unchanged
+func setCondition(
+	status *corev1.PodStatus,
+	conditionType corev1.PodConditionType,
+	value bool,
+	reason, message string,
+	now metav1.Time,
+) {
+	condition := corev1.PodCondition{Type: conditionType, Status: corev1.ConditionFalse, Reason: reason,
+		Message: message, LastTransitionTime: now}
+	if value {
+		condition.Status = corev1.ConditionTrue
+	}
+
+	index := slices.IndexFunc(status.Conditions, func(c corev1.PodCondition) bool { return c.Type == conditionType })
+	if index < 0 {
+		status.Conditions = append(status.Conditions, condition)
+		return
+	}
+	if status.Conditions[index].Status == condition.Status {
+		condition.LastTransitionTime = status.Conditions[index].LastTransitionTime
+	}
+	status.Conditions[index] = condition
+}
+
+// taskPodStatus returns the pod status reflecting the task: its phase, IP, container states
+// and conditions. A nil task is one ECS no longer knows, which fails the pod.
+func taskPodStatus(pod *corev1.Pod, task *ecsapi.Task, now time.Time) corev1.PodStatus {
+	status := *pod.Status.DeepCopy()
+	transition := metav1.NewTime(now.UTC().Truncate(time.Second))
+
+	if task == nil {
+		status.Phase = corev1.PodFailed
+		status.Reason = reasonTaskNotFound
+		status.Message = fmt.Sprintf("ECS task %s was not found", pod.Annotations[ecs.AnnotationTaskArn])
+		setCondition(&status, corev1.ContainersReady, false, reasonTaskNotFound, "", transition)
+		setCondition(&status, corev1.PodReady, false, reasonTaskNotFound, "", transition)
+		return status
+	}
+
+	status.Phase = podPhase(task)
+	status.Reason, status.Message = "", ""
+	if task.LastStatus == ecsapi.TaskStatusStopped {
+		status.Reason, status.Message = task.StopCode, task.StoppedReason
+	}
+	if ip := task.PrivateIPv4Address(); ip != "" {
+		status.PodIP = ip
+		status.PodIPs = []corev1.PodIP{{IP: ip}}
+	}
+	if createdAt := podTime(task.CreatedAt); createdAt != nil {
+		status.StartTime = createdAt
+	}
+
+	status.ContainerStatuses = nil
+	allReady := true
+	for _, spec := range pod.Spec.Containers {
+		var container *ecsapi.Container
+		if index := slices.IndexFunc(task.Containers, func(c ecsapi.Container) bool {
+			return c.Name == spec.Name
+		}); index >= 0 {
+			container = &task.Containers[index]
+		}
+		containerStatus := containerStatus(task, spec, container)
+		allReady = allReady && containerStatus.Ready
+		status.ContainerStatuses = append(status.ContainerStatuses, containerStatus)
+	}
+
+	readyReason := ""
+	switch {
+	case status.Phase == corev1.PodPending:
+		readyReason = reasonTaskProvisioning
+	case !allReady && task.HealthStatus == containerHealthUnhealthy:
+		readyReason = reasonUnhealthy
+	case !allReady:
+		readyReason = "ContainersNotReady"
+	}
+	placed := fmt.Sprintf("Running as ECS task %s", task.TaskArn)
+	setCondition(&status, corev1.PodScheduled, true, "", placed, transition)
+	setCondition(&status, corev1.PodInitialized, true, "", "", transition)
+	setCondition(&status, corev1.ContainersReady, allReady, readyReason, "", transition)
+	setCondition(&status, corev1.PodReady, allReady, readyReason, "", transition)
+	return status
+}
+
+// stoppedMessage describes how the task of the pod stopped from the status it was mapped to,
+// with the exit code of each container
+func stoppedMessage(taskArn string, status *corev1.PodStatus) string {
+	if status.Reason == reasonTaskNotFound {
+		return status.Message
+	}
+	message := fmt.Sprintf("ECS task %s stopped", taskArn)
+	if reason := strings.TrimSpace(status.Reason + " " + status.Message); reason != "" {
+		message += ": " + reason
+	}
+	var exits []string
+	for _, container := range status.ContainerStatuses {
+		if terminated := container.State.Terminated; terminated != nil {
+			exits = append(exits, fmt.Sprintf("%s exited with %d (%s)", container.Name, terminated.ExitCode,
+				terminated.Reason))
+		}
+	}
+	if len(exits) > 0 {
+		message += "; " + strings.Join(exits, ", ")
+	}
+	return message
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+func exitCode(code int) *int {
+	return &code
+}
+
+func condition(status corev1.PodStatus, conditionType corev1.PodConditionType) corev1.PodCondition {
+	for _, c := range status.Conditions {
+		if c.Type == conditionType {
+			return c
+		}
+	}
+	return corev1.PodCondition{}
+}
+
+func TestTaskPodStatus(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	startedAt := &ecs.ECSTimestamp{Time: now.Add(-time.Minute)}
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "app:1.0"},
+			{Name: "sidecar", Image: "envoy:1.0"},
+		}},
+	}
+	task := func(lastStatus string, containers ...ecsapi.Container) *ecsapi.Task {
+		return &ecsapi.Task{
+			TaskArn:    "arn:aws:ecs:us-east-1:123456789012:task/apps/0123",
+			LastStatus: lastStatus,
+			StartedAt:  startedAt,
+			Containers: containers,
+			Attachments: []ecsapi.Attachment{{Type: ecsapi.AttachmentTypeENI, Details: []ecsapi.KeyValuePair{
+				{Name: ecsapi.AttachmentDetailPrivateIP, Value: "10.0.1.5"},
+			}}},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		task      *ecsapi.Task
+		wantPhase corev1.PodPhase
+		wantReady corev1.ConditionStatus
+		check     func(t *testing.T, status corev1.PodStatus)
+	}{
+		{
+			name:      "provisioning",
+			task:      task(ecsapi.TaskStatusProvisioning),
+			wantPhase: corev1.PodPending,
+			wantReady: corev1.ConditionFalse,
+			check: func(t *testing.T, status corev1.PodStatus) {
+				if waiting := status.ContainerStatuses[0].State.Waiting; waiting == nil ||
+					waiting.Reason != reasonContainerCreating {
+					t.Errorf("app state = %+v, want ContainerCreating", status.ContainerStatuses[0].State)
+				}
+			},
+		},
+		{
+			name: "running",
+			task: task(ecsapi.TaskStatusRunning,
+				ecsapi.Container{Name: "app", LastStatus: ecsapi.TaskStatusRunning},
+				ecsapi.Container{Name: "sidecar", LastStatus: ecsapi.TaskStatusRunning}),
+			wantPhase: corev1.PodRunning,
+			wantReady: corev1.ConditionTrue,
+			check: func(t *testing.T, status corev1.PodStatus) {
+				if status.PodIP != "10.0.1.5" || len(status.PodIPs) != 1 {
+					t.Errorf("podIP = %q %v, want the ENI address", status.PodIP, status.PodIPs)
+				}
+				if running := status.ContainerStatuses[1].State.Running; running == nil ||
+					!running.StartedAt.Equal(podTime(startedAt)) ||
+					status.ContainerStatuses[1].ContainerID != "ecs://0123/sidecar" {
+					t.Errorf("sidecar status = %+v", status.ContainerStatuses[1])
+				}
+			},
+		},
+		{
+			name: "unhealthy container",
+			task: task(ecsapi.TaskStatusRunning,
+				ecsapi.Container{Name: "app", LastStatus: ecsapi.TaskStatusRunning, HealthStatus: "UNHEALTHY"},
+				ecsapi.Container{Name: "sidecar", LastStatus: ecsapi.TaskStatusRunning}),
+			wantPhase: corev1.PodRunning,
+			wantReady: corev1.ConditionFalse,
+		},
+		{
+			name: "completed",
+			task: task(ecsapi.TaskStatusStopped,
+				ecsapi.Container{Name: "app", LastStatus: ecsapi.TaskStatusStopped, ExitCode: exitCode(0)},
+				ecsapi.Container{Name: "sidecar", LastStatus: ecsapi.TaskStatusStopped, ExitCode: exitCode(0)}),
+			wantPhase: corev1.PodSucceeded,
+			wantReady: corev1.ConditionFalse,
+			check: func(t *testing.T, status corev1.PodStatus) {
+				if terminated := status.ContainerStatuses[0].State.Terminated; terminated == nil ||
+					terminated.Reason != reasonCompleted {
+					t.Errorf("app state = %+v, want Completed", status.ContainerStatuses[0].State)
+				}
+			},
+		},
+		{
+			name: "failed",
+			task: task(ecsapi.TaskStatusStopped,
+				ecsapi.Container{Name: "app", LastStatus: ecsapi.TaskStatusStopped, ExitCode: exitCode(137),
+					Reason: "OutOfMemoryError: Container killed due to memory usage"},
+				ecsapi.Container{Name: "sidecar", LastStatus: ecsapi.TaskStatusStopped, ExitCode: exitCode(0)}),
+			wantPhase: corev1.PodFailed,
+			wantReady: corev1.ConditionFalse,
+			check: func(t *testing.T, status corev1.PodStatus) {
+				terminated := status.ContainerStatuses[0].State.Terminated
+				if terminated == nil || terminated.ExitCode != 137 || terminated.Reason == reasonError {
+					t.Errorf("app state = %+v, want exit code 137 with the ECS reason", status.ContainerStatuses[0].State)
+				}
+			},
+		},
+		{
+			name:      "task not found",
+			wantPhase: corev1.PodFailed,
+			wantReady: corev1.ConditionFalse,
+			check: func(t *testing.T, status corev1.PodStatus) {
+				if status.Reason != reasonTaskNotFound {
+					t.Errorf("reason = %q, want %s", status.Reason, reasonTaskNotFound)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := taskPodStatus(pod, tt.task, now)
+			if status.Phase != tt.wantPhase {
+				t.Errorf("phase = %s, want %s", status.Phase, tt.wantPhase)
+			}
+			if ready := condition(status, corev1.PodReady); ready.Status != tt.wantReady {
+				t.Errorf("Ready = %+v, want %s", ready, tt.wantReady)
+			}
+			if tt.check != nil {
+				tt.check(t, status)
+			}
+		})
+	}
+}
+
+func TestTaskPodStatus_KeepsTransitionTimes(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:1.0"}}}}
+	task := &ecsapi.Task{
+		TaskArn:    "arn:aws:ecs:us-east-1:123456789012:task/apps/0123",
+		LastStatus: ecsapi.TaskStatusRunning,
+		Containers: []ecsapi.Container{{Name: "app", LastStatus: ecsapi.TaskStatusRunning}},
+	}
+	first := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	pod.Status = taskPodStatus(pod, task, first)
+
+	status := taskPodStatus(pod, task, first.Add(time.Minute))
+	if ready := condition(status, corev1.PodReady); !ready.LastTransitionTime.Time.Equal(first) {
+		t.Errorf("Ready transitioned at %v, want the first sync %v", ready.LastTransitionTime, first)
+	}
+}
@@ -0,0 +1,120 @@
+package standin
+
+import (
+	"context"
+	"slices"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// taskDefinitionRequest is the input of the task definition operations taking a name
+type taskDefinitionRequest struct {
+	TaskDefinition string   `json:"taskDefinition"`
+	Include        []string `json:"include,omitempty"`
+}
+
+// taskDefinitionResponse returns the tags next to the task definition like ECS does
+func taskDefinitionResponse(taskDef *ecs.ECSTaskDefinition, withTags bool) map[string]any {
+	tags := taskDef.Tags
+	taskDef.Tags = nil
+	response := map[string]any{"taskDefinition": taskDef}
+	if withTags {
+		response["tags"] = tags
+	}
+	return response
+}
+
+func (s *Server) registerTaskDefinition(ctx context.Context, input []byte) (any, error) {
+	taskDef, err := decode[ecs.ECSTaskDefinition](input)
+	if err != nil {
+		return nil, err
+	}
+	registered, err := s.ECS.RegisterTaskDefinition(ctx, taskDef)
+	if err != nil {
+		return nil, err
+	}
+	return taskDefinitionResponse(registered, true), nil
+}
+
+func (s *Server) deregisterTaskDefinition(ctx context.Context, input []byte) (any, error) {
+	request, err := decode[taskDefinitionRequest](input)
+	if err != nil {
+		return nil, err
+	}
+	deregistered, err := s.ECS.DeregisterTaskDefinition(ctx, request.TaskDefinition)
+	if err != nil {
+		return nil, err
+	}
+	return taskDefinitionResponse(deregistered, false), nil
+}
+
+func (s *Server) describeTaskDefinition(ctx context.Context, input []byte) (any, error) {
+	request, err := decode[taskDefinitionRequest](input)
+	if err != nil {
+		return nil, err
+	}
+	taskDef, err := s.ECS.DescribeTaskDefinition(ctx, request.TaskDefinition)
+	if err != nil {
+		return nil, err
+	}
+	return taskDefinitionResponse(taskDef, slices.Contains(request.Include, "TAGS")), nil
+}
+
+func (s *Server) runTask(ctx context.Context, input []byte) (any, error) {
+	request, err := decode[ecsapi.RunTaskInput](input)
+	if err != nil {
+		return nil, err
+	}
+	return s.ECS.RunTask(ctx, request)
+}
+
+func (s *Server) stopTask(ctx context.Context, input []byte) (any, error) {
+	request, err := decode[struct {
+		Cluster string `json:"cluster"`
+		Task    string `json:"task"`
+		Reason  string `json:"reason"`
+	}](input)
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.ECS.StopTask(ctx, request.Cluster, request.Task, request.Reason)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"task": task}, nil
+}
+
+func (s *Server) describeTasks(ctx context.Context, input []byte) (any, error) {
+	request, err := decode[struct {
+		Cluster string   `json:"cluster"`
+		Tasks   []string `json:"tasks"`
+	}](input)
+	if err != nil {
+		return nil, err
+	}
+	return s.ECS.DescribeTasks(ctx, request.Cluster, request.Tasks)
+}
+
+func (s *Server) listTasks(ctx context.Context, input []byte) (any, error) {
+	request, err := decode[struct {
+		Cluster   string `json:"cluster"`
+		StartedBy string `json:"startedBy"`
+	}](input)
+	if err != nil {
+		return nil, err
+	}
+	taskArns, err := s.ECS.ListTasks(ctx, request.Cluster, request.StartedBy)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"taskArns": taskArns}, nil
+}
+
+func (s *Server) getLogEvents(ctx context.Context, input []byte) (any, error) {
+	request, err := decode[ecsapi.GetLogEventsInput](input)
+	if err != nil {
+		return nil, err
+	}
+	return s.Logs.GetLogEvents(ctx, request)
+}
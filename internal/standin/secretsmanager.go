@@ -0,0 +1,210 @@
+package standin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+// currentVersionStage labels the latest version of a secret
+const currentVersionStage = "AWSCURRENT"
+
+// errSecretNotFound is the message Secrets Manager returns for unknown secrets
+const errSecretNotFound = "Secrets Manager can't find the specified secret."
+
+// Secret is the current version of a Secrets Manager secret
+type Secret struct {
+	ARN          string           `json:"ARN"`
+	Name         string           `json:"Name"`
+	SecretString string           `json:"SecretString"`
+	VersionID    string           `json:"VersionId"`
+	CreatedDate  ecs.ECSTimestamp `json:"CreatedDate"`
+}
+
+// SecretStore keeps Secrets Manager secrets in memory
+type SecretStore struct {
+	region    string
+	accountID string
+
+	mu       sync.Mutex
+	secrets  map[string]*Secret
+	versions int
+}
+
+// NewSecretStore creates an empty secret store
+func NewSecretStore(region, accountID string) *SecretStore {
+	return &SecretStore{region: region, accountID: accountID, secrets: map[string]*Secret{}}
+}
+
+// lookup finds a secret given by name, ARN or partial ARN without the random suffix; s.mu
+// must be held
+func (s *SecretStore) lookup(id string) (*Secret, bool) {
+	if secret, ok := s.secrets[id]; ok {
+		return secret, true
+	}
+	if _, name, isArn := strings.Cut(id, ":secret:"); isArn {
+		for _, secret := range s.secrets {
+			if secret.ARN == id || secret.Name == name {
+				return secret, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// store saves a new version of the secret, creating it when needed; s.mu must be held
+func (s *SecretStore) store(name, value string) *Secret {
+	secret, ok := s.secrets[name]
+	if !ok {
+		// Secrets Manager appends six random characters; derive them from the name to keep
+		// ARNs stable across restarts
+		sum := sha256.Sum256([]byte(name))
+		secret = &Secret{
+			Name: name,
+			ARN: fmt.Sprintf("arn:aws:secretsmanager:%s:%s:secret:%s-%s",
+				s.region, s.accountID, name, hex.EncodeToString(sum[:3])),
+		}
+		s.secrets[name] = secret
+	}
+	s.versions++
+	secret.SecretString = value
+	secret.VersionID = fmt.Sprintf("00000000-0000-4000-8000-%012d", s.versions)
+	secret.CreatedDate = ecs.ECSTimestamp{Time: time.Now().UTC().Truncate(time.Millisecond)}
+	copied := *secret
+	return &copied
+}
+
+// Create creates a secret
+func (s *SecretStore) Create(name, value string) (*Secret, error) {
+	if name == "" {
+		return nil, apiError("InvalidParameterException", "secret name must not be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.lookup(name); exists {
+		return nil, apiError("ResourceExistsException", "The operation failed because the secret %s already exists.",
+			name)
+	}
+	return s.store(name, value), nil
+}
+
+// Put creates a secret or stores a new version of it
+func (s *SecretStore) Put(id, value string) (*Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := id
+	if secret, exists := s.lookup(id); exists {
+		name = secret.Name
+	} else if strings.HasPrefix(id, "arn:") {
+		return nil, apiError("ResourceNotFoundException", errSecretNotFound)
+	}
+	return s.store(name, value), nil
+}
+
+// Get returns the current version of a secret
+func (s *SecretStore) Get(id string) (*Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, ok := s.lookup(id)
+	if !ok {
+		return nil, apiError("ResourceNotFoundException", errSecretNotFound)
+	}
+	copied := *secret
+	return &copied, nil
+}
+
+// Delete removes a secret immediately
+func (s *SecretStore) Delete(id string) (*Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, ok := s.lookup(id)
+	if !ok {
+		return nil, apiError("ResourceNotFoundException", errSecretNotFound)
+	}
+	delete(s.secrets, secret.Name)
+	return secret, nil
+}
+
+// secretIDRequest is the input of the operations on an existing secret
+type secretIDRequest struct {
+	SecretID     string `json:"SecretId"`
+	SecretString string `json:"SecretString"`
+}
+
+func (s *Server) createSecret(_ context.Context, input []byte) (any, error) {
+	request, err := decode[struct {
+		Name         string `json:"Name"`
+		SecretString string `json:"SecretString"`
+	}](input)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := s.Secrets.Create(request.Name, request.SecretString)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"ARN": secret.ARN, "Name": secret.Name, "VersionId": secret.VersionID}, nil
+}
+
+func (s *Server) putSecretValue(_ context.Context, input []byte) (any, error) {
+	request, err := decode[secretIDRequest](input)
+	if err != nil {
+		return nil, err
+	}
+	// Unlike Put, PutSecretValue requires the secret to exist
+	if _, err := s.Secrets.Get(request.SecretID); err != nil {
+		return nil, err
+	}
+	secret, err := s.Secrets.Put(request.SecretID, request.SecretString)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"ARN":           secret.ARN,
+		"Name":          secret.Name,
+		"VersionId":     secret.VersionID,
+		"VersionStages": []string{currentVersionStage},
+	}, nil
+}
+
+func (s *Server) getSecretValue(_ context.Context, input []byte) (any, error) {
+	request, err := decode[secretIDRequest](input)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := s.Secrets.Get(request.SecretID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"ARN":           secret.ARN,
+		"Name":          secret.Name,
+		"SecretString":  secret.SecretString,
+		"VersionId":     secret.VersionID,
+		"VersionStages": []string{currentVersionStage},
+		"CreatedDate":   secret.CreatedDate,
+	}, nil
+}
+
+func (s *Server) deleteSecret(_ context.Context, input []byte) (any, error) {
+	request, err := decode[secretIDRequest](input)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := s.Secrets.Delete(request.SecretID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"ARN": secret.ARN, "Name": secret.Name, "DeletionDate": ecs.ECSTimestamp{Time: time.Now()}}, nil
+}
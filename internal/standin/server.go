@@ -0,0 +1,195 @@
+// Package standin is a local stand-in for the subset of the ECS, SSM, Secrets Manager and
+// CloudWatch Logs JSON APIs used by the project. It keeps everything in memory and accepts any credentials, so
+// tests and local development can point AWS endpoint overrides at it and run task definitions
+// offline. ECS tasks move through their lifecycle when the server advances them.
+package standin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// Prefixes of the X-Amz-Target header selecting the service of a request
+const (
+	ecsTargetPrefix            = "AmazonEC2ContainerServiceV20141113."
+	ssmTargetPrefix            = "AmazonSSM."
+	secretsManagerTargetPrefix = "secretsmanager."
+	logsTargetPrefix           = "Logs_20140328."
+)
+
+// operation handles the JSON encoded input of an API operation
+type operation func(ctx context.Context, input []byte) (any, error)
+
+// Server serves the ECS, SSM, Secrets Manager and CloudWatch Logs APIs on a single endpoint
+type Server struct {
+	ECS        *ecsapi.Fake
+	Parameters *ParameterStore
+	Secrets    *SecretStore
+	// Logs holds the container logs; tasks do not write any by themselves
+	Logs *ecsapi.FakeLogs
+
+	operations map[string]operation
+}
+
+// NewServer creates an empty server using the region and account ID in ARNs
+func NewServer(region, accountID string) *Server {
+	fake := ecsapi.NewFake()
+	fake.Region = region
+	fake.AccountID = accountID
+
+	s := &Server{
+		ECS:        fake,
+		Parameters: NewParameterStore(region, accountID),
+		Secrets:    NewSecretStore(region, accountID),
+		Logs:       ecsapi.NewFakeLogs(),
+	}
+	s.operations = map[string]operation{
+		ecsTargetPrefix + "RegisterTaskDefinition":   s.registerTaskDefinition,
+		ecsTargetPrefix + "DeregisterTaskDefinition": s.deregisterTaskDefinition,
+		ecsTargetPrefix + "DescribeTaskDefinition":   s.describeTaskDefinition,
+		ecsTargetPrefix + "RunTask":                  s.runTask,
+		ecsTargetPrefix + "StopTask":                 s.stopTask,
+		ecsTargetPrefix + "DescribeTasks":            s.describeTasks,
+		ecsTargetPrefix + "ListTasks":                s.listTasks,
+
+		ssmTargetPrefix + "PutParameter":        s.putParameter,
+		ssmTargetPrefix + "GetParameter":        s.getParameter,
+		ssmTargetPrefix + "GetParameters":       s.getParameters,
+		ssmTargetPrefix + "GetParametersByPath": s.getParametersByPath,
+		ssmTargetPrefix + "DeleteParameter":     s.deleteParameter,
+
+		secretsManagerTargetPrefix + "CreateSecret":   s.createSecret,
+		secretsManagerTargetPrefix + "PutSecretValue": s.putSecretValue,
+		secretsManagerTargetPrefix + "GetSecretValue": s.getSecretValue,
+		secretsManagerTargetPrefix + "DeleteSecret":   s.deleteSecret,
+
+		logsTargetPrefix + "GetLogEvents": s.getLogEvents,
+	}
+	return s
+}
+
+// Run advances the lifecycle of the ECS tasks every interval until ctx is done
+func (s *Server) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.ECS.Advance()
+		}
+	}
+}
+
+// ServeHTTP dispatches JSON protocol requests on their X-Amz-Target header. GET requests are
+// answered with 200 for health checks.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		_, _ = io.WriteString(w, "ok\n")
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	target := r.Header.Get("X-Amz-Target")
+	handle, ok := s.operations[target]
+	if !ok {
+		writeError(w, apiError(ecsapi.ErrorCodeUnknownOperation, "operation %q is not supported", target))
+		return
+	}
+
+	input, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, apiError("SerializationException", "%v", err))
+		return
+	}
+	output, err := handle(r.Context(), input)
+	if err != nil {
+		var apiErr *ecsapi.APIError
+		if !errors.As(err, &apiErr) {
+			apiErr = &ecsapi.APIError{StatusCode: http.StatusInternalServerError, Code: "InternalFailure",
+				Message: err.Error()}
+		}
+		writeError(w, apiErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_ = json.NewEncoder(w).Encode(output)
+}
+
+// apiError returns a client error with the given exception name
+func apiError(code, format string, args ...any) *ecsapi.APIError {
+	return &ecsapi.APIError{StatusCode: http.StatusBadRequest, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func writeError(w http.ResponseWriter, apiErr *ecsapi.APIError) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(apiErr.StatusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"__type": apiErr.Code, "message": apiErr.Message})
+}
+
+// decode unmarshals the input of an operation
+func decode[T any](input []byte) (*T, error) {
+	var value T
+	if err := json.Unmarshal(input, &value); err != nil {
+		return nil, apiError("SerializationException", "%v", err)
+	}
+	return &value, nil
+}
+
+// Seed stores the values of a KEY=VALUE secrets file as used by the compose emitter. Secrets
+// Manager ARNs, with an optional JSON key as in arn:...:secret:name:key::, become secrets;
+// every other key becomes a SecureString parameter.
+func (s *Server) Seed(values map[string]string) error {
+	jsonSecrets := map[string]map[string]string{}
+	for key, value := range values {
+		_, reference, isSecret := strings.Cut(key, ":secretsmanager:")
+		if !isSecret {
+			if _, err := s.Parameters.Put(key, value, ParameterTypeSecureString, true); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// reference is region:account:secret:name[:jsonKey:versionStage:versionId]
+		parts := strings.Split(reference, ":")
+		if len(parts) < 4 || parts[2] != "secret" {
+			return fmt.Errorf("invalid Secrets Manager reference %q", key)
+		}
+		name := parts[3]
+		if len(parts) > 4 && parts[4] != "" {
+			if jsonSecrets[name] == nil {
+				jsonSecrets[name] = map[string]string{}
+			}
+			jsonSecrets[name][parts[4]] = value
+			continue
+		}
+		if _, err := s.Secrets.Put(name, value); err != nil {
+			return err
+		}
+	}
+
+	for name, fields := range jsonSecrets {
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		if _, err := s.Secrets.Put(name, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
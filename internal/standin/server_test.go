@@ -0,0 +1,139 @@
+package standin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	server := NewServer("ap-northeast-1", "345678901234")
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return server, httpServer
+}
+
+// call sends a JSON protocol request and decodes the response into output
+func call(t *testing.T, endpoint, target string, input any, output any) int {
+	t.Helper()
+	body, _ := json.Marshal(input)
+	req, _ := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s failed: %v", target, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := json.NewDecoder(resp.Body).Decode(output); err != nil {
+		t.Fatalf("%s returned invalid JSON: %v", target, err)
+	}
+	return resp.StatusCode
+}
+
+func TestServer_ECSWithClient(t *testing.T) {
+	ctx := context.Background()
+	server, httpServer := newTestServer(t)
+	client, err := ecsapi.NewClient(ctx, ecsapi.Config{
+		Region:      "ap-northeast-1",
+		Endpoint:    httpServer.URL,
+		Credentials: aws.AnonymousCredentials{},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	registered, err := client.RegisterTaskDefinition(ctx, &ecs.ECSTaskDefinition{
+		Family:               "web",
+		ContainerDefinitions: []ecs.ECSContainerDefinition{{Name: "web", Image: "nginx", Essential: true}},
+		Tags:                 []ecs.ECSTag{{Key: "team", Value: "web"}},
+	})
+	if err != nil {
+		t.Fatalf("RegisterTaskDefinition() error = %v", err)
+	}
+	if registered.TaskDefinitionArn != "arn:aws:ecs:ap-northeast-1:345678901234:task-definition/web:1" ||
+		len(registered.Tags) != 1 {
+		t.Errorf("registered = %+v", registered)
+	}
+	if _, err := client.DescribeTaskDefinition(ctx, "api"); !ecsapi.IsNotFound(err) {
+		t.Errorf("DescribeTaskDefinition(api) error = %v, want not found", err)
+	}
+
+	output, err := client.RunTask(ctx, &ecsapi.RunTaskInput{Cluster: "apps", TaskDefinition: "web"})
+	if err != nil {
+		t.Fatalf("RunTask() error = %v", err)
+	}
+	task := output.Tasks[0]
+
+	server.ECS.Advance()
+	server.ECS.Advance()
+	described, err := client.DescribeTasks(ctx, "apps", []string{task.TaskArn})
+	if err != nil {
+		t.Fatalf("DescribeTasks() error = %v", err)
+	}
+	if status := described.Tasks[0].LastStatus; status != ecsapi.TaskStatusRunning {
+		t.Errorf("lastStatus = %s, want RUNNING", status)
+	}
+
+	stopped, err := client.StopTask(ctx, "apps", task.TaskArn, "done")
+	if err != nil {
+		t.Fatalf("StopTask() error = %v", err)
+	}
+	if stopped.DesiredStatus != ecsapi.TaskStatusStopped || stopped.StoppedReason != "done" {
+		t.Errorf("stopped = %+v", stopped)
+	}
+}
+
+func TestServer_ParametersAndSecrets(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	if err := server.Seed(map[string]string{
+		"/pods/shop/configmaps/settings/mode":                                     "fast",
+		"arn:aws:secretsmanager:ap-northeast-1:345678901234:secret:db:user::":     "admin",
+		"arn:aws:secretsmanager:ap-northeast-1:345678901234:secret:db:password::": "s3cret",
+	}); err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+
+	var parameter struct{ Parameter Parameter }
+	parameterArn := "arn:aws:ssm:ap-northeast-1:345678901234:parameter/pods/shop/configmaps/settings/mode"
+	call(t, httpServer.URL, "AmazonSSM.GetParameter", map[string]string{"Name": parameterArn}, &parameter)
+	if parameter.Parameter.Value != "fast" || parameter.Parameter.Type != ParameterTypeSecureString {
+		t.Errorf("GetParameter() = %+v", parameter.Parameter)
+	}
+
+	var failure map[string]string
+	status := call(t, httpServer.URL, "AmazonSSM.PutParameter",
+		map[string]any{"Name": "/pods/shop/configmaps/settings/mode", "Value": "slow"}, &failure)
+	if status != http.StatusBadRequest || failure["__type"] != "ParameterAlreadyExists" {
+		t.Errorf("PutParameter() without overwrite = %d %v", status, failure)
+	}
+
+	var secret map[string]any
+	call(t, httpServer.URL, "secretsmanager.GetSecretValue",
+		map[string]string{"SecretId": "arn:aws:secretsmanager:ap-northeast-1:345678901234:secret:db"}, &secret)
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(secret["SecretString"].(string)), &fields); err != nil ||
+		fields["user"] != "admin" || fields["password"] != "s3cret" {
+		t.Errorf("GetSecretValue() SecretString = %v", secret["SecretString"])
+	}
+
+	status = call(t, httpServer.URL, "secretsmanager.GetSecretValue",
+		map[string]string{"SecretId": "cache"}, &failure)
+	if status != http.StatusBadRequest || failure["__type"] != "ResourceNotFoundException" {
+		t.Errorf("GetSecretValue(cache) = %d %v", status, failure)
+	}
+
+	status = call(t, httpServer.URL, "AmazonS3.ListBuckets", map[string]string{}, &failure)
+	if status != http.StatusBadRequest || failure["__type"] != ecsapi.ErrorCodeUnknownOperation {
+		t.Errorf("unsupported operation = %d %v", status, failure)
+	}
+}
@@ -0,0 +1,225 @@
+package standin
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+// Parameter types
+const (
+	ParameterTypeString       = "String"
+	ParameterTypeStringList   = "StringList"
+	ParameterTypeSecureString = "SecureString"
+)
+
+// Parameter is an SSM Parameter Store parameter
+type Parameter struct {
+	Name             string           `json:"Name"`
+	Type             string           `json:"Type"`
+	Value            string           `json:"Value"`
+	Version          int64            `json:"Version"`
+	ARN              string           `json:"ARN"`
+	DataType         string           `json:"DataType"`
+	LastModifiedDate ecs.ECSTimestamp `json:"LastModifiedDate"`
+}
+
+// ParameterStore keeps SSM parameters in memory
+type ParameterStore struct {
+	region    string
+	accountID string
+
+	mu         sync.Mutex
+	parameters map[string]*Parameter
+}
+
+// NewParameterStore creates an empty parameter store
+func NewParameterStore(region, accountID string) *ParameterStore {
+	return &ParameterStore{region: region, accountID: accountID, parameters: map[string]*Parameter{}}
+}
+
+// parameterName returns the name of a parameter given by name or ARN
+func parameterName(name string) string {
+	if _, path, isArn := strings.Cut(name, ":parameter"); isArn && strings.HasPrefix(name, "arn:") {
+		return path
+	}
+	return name
+}
+
+// Put creates or, with overwrite, updates a parameter and returns its new version
+func (p *ParameterStore) Put(name, value, parameterType string, overwrite bool) (int64, error) {
+	name = parameterName(name)
+	if name == "" {
+		return 0, apiError("ValidationException", "parameter name must not be empty")
+	}
+	if parameterType == "" {
+		parameterType = ParameterTypeString
+	}
+	if !slices.Contains([]string{ParameterTypeString, ParameterTypeStringList, ParameterTypeSecureString},
+		parameterType) {
+		return 0, apiError("UnsupportedParameterType", "parameter type %s is not supported", parameterType)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	parameter, exists := p.parameters[name]
+	if exists && !overwrite {
+		return 0, apiError("ParameterAlreadyExists", "The parameter already exists. To overwrite this value, "+
+			"set the overwrite option in the request to true.")
+	}
+	if !exists {
+		parameter = &Parameter{
+			Name:     name,
+			ARN:      fmt.Sprintf("arn:aws:ssm:%s:%s:parameter/%s", p.region, p.accountID, strings.TrimPrefix(name, "/")),
+			DataType: "text",
+		}
+		p.parameters[name] = parameter
+	}
+	parameter.Type = parameterType
+	parameter.Value = value
+	parameter.Version++
+	parameter.LastModifiedDate = ecs.ECSTimestamp{Time: time.Now().UTC().Truncate(time.Millisecond)}
+	return parameter.Version, nil
+}
+
+// lookup finds a parameter given by name or ARN; p.mu must be held
+func (p *ParameterStore) lookup(name string) (*Parameter, error) {
+	path := parameterName(name)
+	if parameter, ok := p.parameters[path]; ok {
+		return parameter, nil
+	}
+	// The ARNs of names without a leading slash add one
+	if parameter, ok := p.parameters[strings.TrimPrefix(path, "/")]; ok && path != name {
+		return parameter, nil
+	}
+	return nil, apiError("ParameterNotFound", "Parameter %s not found.", name)
+}
+
+// Get returns a parameter given by name or ARN
+func (p *ParameterStore) Get(name string) (*Parameter, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	parameter, err := p.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	copied := *parameter
+	return &copied, nil
+}
+
+// Delete removes a parameter given by name or ARN
+func (p *ParameterStore) Delete(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	parameter, err := p.lookup(name)
+	if err != nil {
+		return err
+	}
+	delete(p.parameters, parameter.Name)
+	return nil
+}
+
+// List returns the parameters below path, sorted by name. Without recursive only parameters
+// directly below path are returned.
+func (p *ParameterStore) List(path string, recursive bool) []Parameter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	var parameters []Parameter
+	for _, name := range slices.Sorted(maps.Keys(p.parameters)) {
+		rest, below := strings.CutPrefix(name, prefix)
+		if below && (recursive || !strings.Contains(rest, "/")) {
+			parameters = append(parameters, *p.parameters[name])
+		}
+	}
+	return parameters
+}
+
+func (s *Server) putParameter(_ context.Context, input []byte) (any, error) {
+	request, err := decode[struct {
+		Name      string `json:"Name"`
+		Value     string `json:"Value"`
+		Type      string `json:"Type"`
+		Overwrite bool   `json:"Overwrite"`
+	}](input)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.Parameters.Put(request.Name, request.Value, request.Type, request.Overwrite)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"Version": version, "Tier": "Standard"}, nil
+}
+
+func (s *Server) getParameter(_ context.Context, input []byte) (any, error) {
+	request, err := decode[struct {
+		Name string `json:"Name"`
+	}](input)
+	if err != nil {
+		return nil, err
+	}
+	parameter, err := s.Parameters.Get(request.Name)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"Parameter": parameter}, nil
+}
+
+func (s *Server) getParameters(_ context.Context, input []byte) (any, error) {
+	request, err := decode[struct {
+		Names []string `json:"Names"`
+	}](input)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := []*Parameter{}
+	invalid := []string{}
+	for _, name := range request.Names {
+		if parameter, err := s.Parameters.Get(name); err == nil {
+			parameters = append(parameters, parameter)
+		} else {
+			invalid = append(invalid, name)
+		}
+	}
+	return map[string]any{"Parameters": parameters, "InvalidParameters": invalid}, nil
+}
+
+func (s *Server) getParametersByPath(_ context.Context, input []byte) (any, error) {
+	request, err := decode[struct {
+		Path      string `json:"Path"`
+		Recursive bool   `json:"Recursive"`
+	}](input)
+	if err != nil {
+		return nil, err
+	}
+	parameters := s.Parameters.List(request.Path, request.Recursive)
+	if parameters == nil {
+		parameters = []Parameter{}
+	}
+	return map[string]any{"Parameters": parameters}, nil
+}
+
+func (s *Server) deleteParameter(_ context.Context, input []byte) (any, error) {
+	request, err := decode[struct {
+		Name string `json:"Name"`
+	}](input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Parameters.Delete(request.Name); err != nil {
+		return nil, err
+	}
+	return map[string]any{}, nil
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package virtualnode registers a virtual Node standing for ECS capacity and serves the kubelet
+// API of the pods scheduled to it, so that the scheduler, kubectl and workload controllers treat
+// ECS like any other node.
+package virtualnode
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// TaintKey taints the virtual node, and labels it with "true", so that only pods tolerating
+// it are scheduled to ECS
+const TaintKey = "ecs.takutakahashi.dev/virtual-node"
+
+// taintValue is the value of the virtual node taint
+const taintValue = "ecs"
+
+const (
+	// leaseDuration is how long the node lease is valid without being renewed
+	leaseDuration = 40 * time.Second
+	// heartbeatInterval is how often the node lease is renewed
+	heartbeatInterval = 10 * time.Second
+	// statusUpdateInterval is how often the node status is reported without changes, like the
+	// node status report frequency of the kubelet
+	statusUpdateInterval = 5 * time.Minute
+	// kubeletVersion is reported as the kubelet version of the node
+	kubeletVersion = "v1.33.0-ecstask"
+)
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;create;patch
+// +kubebuilder:rbac:groups="",resources=nodes/status,verbs=get;patch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;create;patch
+
+// Node registers a virtual node advertising ECS capacity and keeps it Ready through its lease.
+// It runs on the leader, which also serves the kubelet API at Address.
+type Node struct {
+	// Client should not read from the cache, which would watch every node and lease
+	Client client.Client
+	// Name of the node
+	Name string
+	// Address is the IP address the API server reaches the kubelet API of the node at
+	Address string
+	// Port of the kubelet API
+	Port int32
+	// Capacity is the cpu, memory and pods the node advertises
+	Capacity corev1.ResourceList
+}
+
+var _ manager.LeaderElectionRunnable = &Node{}
+
+// NeedLeaderElection makes the node run only on the leader
+func (n *Node) NeedLeaderElection() bool {
+	return true
+}
+
+// Start registers the node and renews its lease until ctx is done. Its status is reported
+// again every statusUpdateInterval.
+func (n *Node) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithValues("node", n.Name)
+	if err := n.register(ctx); err != nil {
+		return fmt.Errorf("failed to register virtual node %s: %w", n.Name, err)
+	}
+	log.Info("Registered virtual node")
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	lastStatus := time.Now()
+	for {
+		if err := n.renewLease(ctx); err != nil {
+			log.Error(err, "Failed to renew the lease of the virtual node")
+		}
+		if time.Since(lastStatus) >= statusUpdateInterval {
+			if err := n.updateStatus(ctx); err != nil {
+				log.Error(err, "Failed to update the status of the virtual node")
+			} else {
+				lastStatus = time.Now()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat.C:
+		}
+	}
+}
+
+// taint is the taint keeping pods that do not tolerate ECS away from the node
+func taint() corev1.Taint {
+	return corev1.Taint{Key: TaintKey, Value: taintValue, Effect: corev1.TaintEffectNoSchedule}
+}
+
+// Toleration lets pods be scheduled to the virtual node
+func Toleration() corev1.Toleration {
+	return corev1.Toleration{Key: TaintKey, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+}
+
+// register creates the node or updates its labels and taint, then reports its status
+func (n *Node) register(ctx context.Context) error {
+	node := &corev1.Node{}
+	err := n.Client.Get(ctx, client.ObjectKey{Name: n.Name}, node)
+	if apierrors.IsNotFound(err) {
+		node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: n.Name}}
+		n.setMetadata(node)
+		if err := n.Client.Create(ctx, node); err != nil {
+			return err
+		}
+		return n.updateStatus(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	n.setMetadata(node)
+	if err := n.Client.Patch(ctx, node, patch); err != nil {
+		return err
+	}
+	return n.updateStatus(ctx)
+}
+
+// setMetadata sets the labels and taint of the node, keeping those added by others
+func (n *Node) setMetadata(node *corev1.Node) {
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	node.Labels[TaintKey] = "true"
+	node.Labels[corev1.LabelHostname] = n.Name
+	node.Labels[corev1.LabelOSStable] = "linux"
+	node.Labels["type"] = "virtual-kubelet"
+	node.Labels[corev1.LabelNodeExcludeBalancers] = "true"
+
+	if !slices.ContainsFunc(node.Spec.Taints, func(t corev1.Taint) bool { return t.MatchTaint(ptr.To(taint())) }) {
+		node.Spec.Taints = append(node.Spec.Taints, taint())
+	}
+}
+
+// updateStatus reports the node Ready with its capacity, address and kubelet endpoint
+func (n *Node) updateStatus(ctx context.Context) error {
+	node := &corev1.Node{}
+	if err := n.Client.Get(ctx, client.ObjectKey{Name: n.Name}, node); err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	now := metav1.Now()
+	node.Status.Capacity = n.Capacity
+	node.Status.Allocatable = n.Capacity
+	node.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeHostName, Address: n.Name}}
+	if n.Address != "" {
+		node.Status.Addresses = append(node.Status.Addresses,
+			corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: n.Address})
+	}
+	node.Status.DaemonEndpoints.KubeletEndpoint.Port = n.Port
+	node.Status.NodeInfo.OperatingSystem = "linux"
+	node.Status.NodeInfo.Architecture = "amd64"
+	node.Status.NodeInfo.KubeletVersion = kubeletVersion
+	node.Status.Phase = corev1.NodeRunning
+
+	conditions := []corev1.NodeCondition{
+		{Type: corev1.NodeReady, Status: corev1.ConditionTrue, Reason: "KubeletReady",
+			Message: "ECS capacity is available"},
+		{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse, Reason: "KubeletHasSufficientMemory"},
+		{Type: corev1.NodeDiskPressure, Status: corev1.ConditionFalse, Reason: "KubeletHasNoDiskPressure"},
+		{Type: corev1.NodePIDPressure, Status: corev1.ConditionFalse, Reason: "KubeletHasSufficientPID"},
+		{Type: corev1.NodeNetworkUnavailable, Status: corev1.ConditionFalse, Reason: "RouteCreated"},
+	}
+	for _, condition := range conditions {
+		condition.LastHeartbeatTime = now
+		condition.LastTransitionTime = now
+		index := slices.IndexFunc(node.Status.Conditions, func(c corev1.NodeCondition) bool {
+			return c.Type == condition.Type
+		})
+		if index < 0 {
+			node.Status.Conditions = append(node.Status.Conditions, condition)
+			continue
+		}
+		if node.Status.Conditions[index].Status == condition.Status {
+			condition.LastTransitionTime = node.Status.Conditions[index].LastTransitionTime
+		}
+		node.Status.Conditions[index] = condition
+	}
+	return n.Client.Status().Patch(ctx, node, patch)
+}
+
+// renewLease renews the lease the node lifecycle controller reads as the node heartbeat
+func (n *Node) renewLease(ctx context.Context) error {
+	now := metav1.NewMicroTime(time.Now())
+	lease := &coordinationv1.Lease{}
+	err := n.Client.Get(ctx, client.ObjectKey{Namespace: corev1.NamespaceNodeLease, Name: n.Name}, lease)
+	if apierrors.IsNotFound(err) {
+		node := &corev1.Node{}
+		if err := n.Client.Get(ctx, client.ObjectKey{Name: n.Name}, node); err != nil {
+			return err
+		}
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      n.Name,
+				Namespace: corev1.NamespaceNodeLease,
+				// The lease goes away with the node
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: node.Name,
+					UID: node.UID}},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(n.Name),
+				LeaseDurationSeconds: ptr.To(int32(leaseDuration.Seconds())),
+				RenewTime:            &now,
+			},
+		}
+		return n.Client.Create(ctx, lease)
+	}
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(lease.DeepCopy())
+	lease.Spec.HolderIdentity = ptr.To(n.Name)
+	lease.Spec.LeaseDurationSeconds = ptr.To(int32(leaseDuration.Seconds()))
+	lease.Spec.RenewTime = &now
+	return n.Client.Patch(ctx, lease, patch)
+}
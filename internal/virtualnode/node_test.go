@@ -0,0 +1,118 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virtualnode
+
+import (
+	"context"
+	"testing"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newFakeClient(t *testing.T, objects ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&corev1.Node{}, &corev1.Pod{}).
+		WithObjects(objects...).Build()
+}
+
+func TestNode_Register(t *testing.T) {
+	ctx := context.Background()
+	// An existing node keeps the taints and labels added by others
+	existing := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "ecs", Labels: map[string]string{"team": "platform"}},
+		Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+			{Key: "maintenance", Effect: corev1.TaintEffectNoExecute},
+			taint(),
+		}},
+	}
+	k8sClient := newFakeClient(t, existing)
+	n := &Node{
+		Client:  k8sClient,
+		Name:    "ecs",
+		Address: "10.0.0.5",
+		Port:    10250,
+		Capacity: corev1.ResourceList{
+			corev1.ResourceCPU:  resource.MustParse("1k"),
+			corev1.ResourcePods: resource.MustParse("1k"),
+		},
+	}
+
+	if err := n.register(ctx); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	node := &corev1.Node{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "ecs"}, node); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(node.Spec.Taints) != 2 || node.Labels["team"] != "platform" || node.Labels[TaintKey] != "true" {
+		t.Errorf("taints = %v, labels = %v", node.Spec.Taints, node.Labels)
+	}
+	if node.Status.DaemonEndpoints.KubeletEndpoint.Port != 10250 || len(node.Status.Addresses) != 2 ||
+		node.Status.Addresses[1].Address != "10.0.0.5" {
+		t.Errorf("endpoint = %+v, addresses = %v", node.Status.DaemonEndpoints, node.Status.Addresses)
+	}
+	if pods := node.Status.Allocatable[corev1.ResourcePods]; pods.Value() != 1000 {
+		t.Errorf("allocatable pods = %s, want 1k", pods.String())
+	}
+	ready := false
+	for _, condition := range node.Status.Conditions {
+		ready = ready || condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue
+	}
+	if !ready {
+		t.Errorf("conditions = %v, want Ready", node.Status.Conditions)
+	}
+}
+
+func TestNode_RenewLease(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := newFakeClient(t, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ecs", UID: "node-uid"}})
+	n := &Node{Client: k8sClient, Name: "ecs"}
+
+	if err := n.renewLease(ctx); err != nil {
+		t.Fatalf("renewLease() error = %v", err)
+	}
+	lease := &coordinationv1.Lease{}
+	key := client.ObjectKey{Namespace: corev1.NamespaceNodeLease, Name: "ecs"}
+	if err := k8sClient.Get(ctx, key, lease); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if lease.Spec.RenewTime == nil || len(lease.OwnerReferences) != 1 || lease.OwnerReferences[0].UID != "node-uid" {
+		t.Fatalf("lease = %+v", lease)
+	}
+	created := lease.Spec.RenewTime.Time
+
+	if err := n.renewLease(ctx); err != nil {
+		t.Fatalf("second renewLease() error = %v", err)
+	}
+	if err := k8sClient.Get(ctx, key, lease); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if lease.Spec.RenewTime.Time.Before(created) {
+		t.Errorf("renew time = %v, want after %v", lease.Spec.RenewTime, created)
+	}
+}
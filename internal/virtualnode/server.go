@@ -0,0 +1,382 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virtualnode
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// defaultFollowInterval is how often followed logs are polled for new events
+const defaultFollowInterval = 2 * time.Second
+
+// maxLogEvents is the most events CloudWatch Logs returns per page
+const maxLogEvents = 10000
+
+// Server serves the part of the kubelet API the API server proxies for kubectl logs, reading
+// container logs from CloudWatch Logs. The virtual node serves logs only: exec, attach, port
+// forwarding and run are answered as not implemented.
+type Server struct {
+	// Client reads the pods of the node
+	Client client.Reader
+	ECS    ecsapi.Client
+	Logs   ecsapi.LogsClient
+	// Addr is the address to listen on, such as :10250
+	Addr string
+	// CertDir holds the tls.crt and tls.key serving certificate. Without it a self-signed
+	// certificate is generated, which the API server accepts unless it verifies kubelets.
+	CertDir string
+	// ClientCAFile verifies the client certificate the API server presents to kubelets
+	ClientCAFile string
+	// FollowInterval is how often followed logs are polled (default: 2s)
+	FollowInterval time.Duration
+}
+
+var _ manager.LeaderElectionRunnable = &Server{}
+
+// NeedLeaderElection makes the server run on the leader, whose address the node advertises
+func (s *Server) NeedLeaderElection() bool {
+	return true
+}
+
+// Handler returns the routes of the kubelet API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("GET /containerLogs/{namespace}/{pod}/{container}", s.containerLogs)
+	for _, path := range []string{"/exec/", "/attach/", "/portForward/", "/run/"} {
+		mux.HandleFunc(path, s.notSupported)
+	}
+	return mux
+}
+
+// Start serves the kubelet API over TLS, requiring client certificates signed by ClientCAFile,
+// until ctx is done
+func (s *Server) Start(ctx context.Context) error {
+	caData, err := os.ReadFile(s.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client CA: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caData) {
+		return fmt.Errorf("no certificates in client CA %s", s.ClientCAFile)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+
+	if s.CertDir != "" {
+		watcher, err := certwatcher.New(filepath.Join(s.CertDir, "tls.crt"), filepath.Join(s.CertDir, "tls.key"))
+		if err != nil {
+			return fmt.Errorf("failed to load serving certificate: %w", err)
+		}
+		go func() {
+			if err := watcher.Start(ctx); err != nil {
+				logf.FromContext(ctx).Error(err, "Failed to watch the kubelet API serving certificate")
+			}
+		}()
+		tlsConfig.GetCertificate = watcher.GetCertificate
+	} else {
+		certificate, err := selfSignedCertificate()
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	server := &http.Server{
+		Addr:              s.Addr,
+		Handler:           s.Handler(),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	logf.FromContext(ctx).Info("Serving the kubelet API of the virtual node", "addr", s.Addr)
+	if err := server.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// selfSignedCertificate generates a serving certificate valid for a year
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "ecstask-virtual-node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create self-signed certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// logOptions are the query parameters of a container logs request
+type logOptions struct {
+	follow     bool
+	timestamps bool
+	tailLines  *int64
+	since      *time.Time
+}
+
+// parseLogOptions reads the PodLogOptions query parameters the API server forwards
+func parseLogOptions(r *http.Request) (*logOptions, error) {
+	query := r.URL.Query()
+	options := &logOptions{}
+	var err error
+	if value := query.Get("follow"); value != "" {
+		if options.follow, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("invalid follow %q", value)
+		}
+	}
+	if value := query.Get("timestamps"); value != "" {
+		if options.timestamps, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("invalid timestamps %q", value)
+		}
+	}
+	if value := query.Get("tailLines"); value != "" {
+		lines, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || lines < 0 {
+			return nil, fmt.Errorf("invalid tailLines %q", value)
+		}
+		options.tailLines = &lines
+	}
+	if value := query.Get("sinceSeconds"); value != "" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid sinceSeconds %q", value)
+		}
+		since := time.Now().Add(-time.Duration(seconds) * time.Second)
+		options.since = &since
+	}
+	if value := query.Get("sinceTime"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sinceTime %q", value)
+		}
+		options.since = &since
+	}
+	return options, nil
+}
+
+// httpError is an error with the status it is answered with
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func newHTTPError(status int, format string, args ...any) *httpError {
+	return &httpError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+// writeError answers the request with the status of an httpError, and 500 for other errors
+func writeError(w http.ResponseWriter, err error) {
+	var httpErr *httpError
+	if !errors.As(err, &httpErr) {
+		httpErr = &httpError{status: http.StatusInternalServerError, message: err.Error()}
+	}
+	http.Error(w, httpErr.message, httpErr.status)
+}
+
+// offloadedPod returns the pod and, from its annotations, its cluster and task ARN
+func (s *Server) offloadedPod(ctx context.Context, namespace, name string) (*corev1.Pod, string, string, error) {
+	pod := &corev1.Pod{}
+	if err := s.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, "", "", newHTTPError(http.StatusNotFound, "pod %s/%s not found", namespace, name)
+		}
+		return nil, "", "", err
+	}
+	taskArn := pod.Annotations[ecs.AnnotationTaskArn]
+	if taskArn == "" {
+		return nil, "", "", newHTTPError(http.StatusBadRequest, "pod %s/%s has no ECS task yet", namespace, name)
+	}
+	return pod, pod.Annotations[ecs.AnnotationCluster], taskArn, nil
+}
+
+// logStream returns the CloudWatch Logs group and stream of a container of the pod's task
+func (s *Server) logStream(ctx context.Context, pod *corev1.Pod, taskArn, container string) (string, string, error) {
+	taskDef, err := s.ECS.DescribeTaskDefinition(ctx, pod.Annotations[ecs.AnnotationTaskDefinitionArn])
+	if err != nil {
+		return "", "", fmt.Errorf("failed to describe task definition: %w", err)
+	}
+	index := slices.IndexFunc(taskDef.ContainerDefinitions, func(c ecs.ECSContainerDefinition) bool {
+		return c.Name == container
+	})
+	if index < 0 {
+		return "", "", newHTTPError(http.StatusNotFound, "container %s is not valid for pod %s", container, pod.Name)
+	}
+	logConfig := taskDef.ContainerDefinitions[index].LogConfiguration
+	if logConfig == nil || logConfig.LogDriver != ecsapi.LogDriverAWSLogs {
+		return "", "", newHTTPError(http.StatusBadRequest,
+			"container %s does not send its logs to CloudWatch Logs with the %s driver", container,
+			ecsapi.LogDriverAWSLogs)
+	}
+	group, prefix := logConfig.Options[ecsapi.AWSLogsGroupOption], logConfig.Options[ecsapi.AWSLogsStreamPrefixOption]
+	if group == "" || prefix == "" {
+		return "", "", newHTTPError(http.StatusBadRequest, "container %s has no %s and %s log options",
+			container, ecsapi.AWSLogsGroupOption, ecsapi.AWSLogsStreamPrefixOption)
+	}
+	task := ecsapi.Task{TaskArn: taskArn}
+	return group, ecsapi.AWSLogsStream(prefix, container, task.ID()), nil
+}
+
+// containerLogs writes the CloudWatch Logs events of a container, polling for new events
+// while following until the pod stops or the request ends
+func (s *Server) containerLogs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	options, err := parseLogOptions(r)
+	if err != nil {
+		writeError(w, newHTTPError(http.StatusBadRequest, "%v", err))
+		return
+	}
+	pod, _, taskArn, err := s.offloadedPod(ctx, r.PathValue("namespace"), r.PathValue("pod"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	group, stream, err := s.logStream(ctx, pod, taskArn, r.PathValue("container"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	input := &ecsapi.GetLogEventsInput{LogGroupName: group, LogStreamName: stream, StartFromHead: true}
+	if options.since != nil {
+		input.StartTime = ptr.To(options.since.UnixMilli())
+	}
+	if options.tailLines != nil {
+		input.StartFromHead = false
+		input.Limit = int32(min(*options.tailLines, maxLogEvents))
+	}
+	output, err := s.Logs.GetLogEvents(ctx, input)
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to get log events of %s: %w", stream, err))
+		return
+	}
+	if options.tailLines != nil && *options.tailLines == 0 {
+		output.Events = nil
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	flusher, _ := w.(http.Flusher)
+	interval := s.FollowInterval
+	if interval == 0 {
+		interval = defaultFollowInterval
+	}
+	for {
+		for _, event := range output.Events {
+			if options.timestamps {
+				_, _ = fmt.Fprintf(w, "%s ", event.Time().Format(time.RFC3339Nano))
+			}
+			_, _ = fmt.Fprintln(w, event.Message)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		// Reads from the head page on until the forward token stops changing; a tail is one page
+		caughtUp := output.NextForwardToken == input.NextToken || !input.StartFromHead
+		if caughtUp {
+			if !options.follow || s.stopped(ctx, pod) {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+		input = &ecsapi.GetLogEventsInput{LogGroupName: group, LogStreamName: stream, StartFromHead: true,
+			NextToken: output.NextForwardToken}
+		if output, err = s.Logs.GetLogEvents(ctx, input); err != nil {
+			logf.FromContext(ctx).Error(err, "Failed to follow log events", "stream", stream)
+			return
+		}
+	}
+}
+
+// stopped reports whether the pod has reached a terminal phase, after which its logs no
+// longer grow
+func (s *Server) stopped(ctx context.Context, pod *corev1.Pod) bool {
+	current := &corev1.Pod{}
+	if err := s.Client.Get(ctx, client.ObjectKeyFromObject(pod), current); err != nil {
+		return true
+	}
+	return current.Status.Phase == corev1.PodSucceeded || current.Status.Phase == corev1.PodFailed
+}
+
+// notSupported answers exec, attach, port forwarding and run, which the virtual node does not serve
+func (s *Server) notSupported(w http.ResponseWriter, r *http.Request) {
+	// Paths are /exec/namespace/pod/container and the like
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 {
+		http.NotFound(w, r)
+		return
+	}
+	http.Error(w, fmt.Sprintf("%s is not supported for pods running on ECS; the virtual node serves logs only",
+		parts[0]), http.StatusNotImplemented)
+}
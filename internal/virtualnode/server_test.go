@@ -0,0 +1,153 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virtualnode
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
+)
+
+// newTestServer serves the kubelet API of a pod running as an ECS task logging to /ecs/web
+func newTestServer(t *testing.T) (*ecsapi.FakeLogs, *httptest.Server) {
+	t.Helper()
+	ctx := context.Background()
+	ecsClient := ecsapi.NewFake()
+	taskDef, err := ecsClient.RegisterTaskDefinition(ctx, &ecs.ECSTaskDefinition{
+		Family: "web",
+		ContainerDefinitions: []ecs.ECSContainerDefinition{{
+			Name:      "app",
+			Image:     "nginx:1.27",
+			Essential: true,
+			LogConfiguration: &ecs.ECSLogConfiguration{LogDriver: "awslogs", Options: map[string]string{
+				"awslogs-group": "/ecs/web", "awslogs-stream-prefix": "web", "awslogs-region": "us-east-1",
+			}},
+		}, {
+			Name:  "sidecar",
+			Image: "busybox",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("RegisterTaskDefinition() error = %v", err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", Annotations: map[string]string{
+			ecs.AnnotationTaskDefinitionArn: taskDef.TaskDefinitionArn,
+			ecs.AnnotationTaskArn:           "arn:aws:ecs:us-east-1:123456789012:task/apps/0123abcd",
+			ecs.AnnotationCluster:           "apps",
+		}},
+	}
+
+	logs := ecsapi.NewFakeLogs()
+	server := &Server{Client: newFakeClient(t, pod), ECS: ecsClient, Logs: logs}
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+	return logs, httpServer
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestServer_ContainerLogs(t *testing.T) {
+	logs, server := newTestServer(t)
+	logs.PutLogEvents("/ecs/web", "web/app/0123abcd", "starting", "listening on :80", "GET /")
+
+	status, body := get(t, server.URL+"/containerLogs/shop/web/app")
+	if status != http.StatusOK || body != "starting\nlistening on :80\nGET /\n" {
+		t.Errorf("logs = %d %q", status, body)
+	}
+
+	status, body = get(t, server.URL+"/containerLogs/shop/web/app?tailLines=1&timestamps=true")
+	if status != http.StatusOK || !strings.HasSuffix(body, "Z GET /\n") || strings.Count(body, "\n") != 1 {
+		t.Errorf("tail with timestamps = %d %q", status, body)
+	}
+
+	for path, want := range map[string]int{
+		"/containerLogs/shop/missing/app":         http.StatusNotFound,
+		"/containerLogs/shop/web/db":              http.StatusNotFound,
+		"/containerLogs/shop/web/sidecar":         http.StatusBadRequest,
+		"/containerLogs/shop/web/app?tailLines=x": http.StatusBadRequest,
+	} {
+		if status, body := get(t, server.URL+path); status != want {
+			t.Errorf("GET %s = %d %q, want %d", path, status, body, want)
+		}
+	}
+}
+
+func TestServer_ContainerLogsWithDefaultLogOptions(t *testing.T) {
+	ctx := context.Background()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx:1.27"}}},
+	}
+	converter := ecs.NewConverter(ecs.ConversionOptions{DefaultCPU: "256", DefaultMemory: "512"})
+	taskDef, err := converter.ConvertPod(pod, &pod.Spec, &ecs.ECSConfig{}, "shop")
+	if err != nil {
+		t.Fatalf("ConvertPod() error = %v", err)
+	}
+	ecsClient := ecsapi.NewFake()
+	registered, err := ecsClient.RegisterTaskDefinition(ctx, taskDef)
+	if err != nil {
+		t.Fatalf("RegisterTaskDefinition() error = %v", err)
+	}
+	pod.Annotations = map[string]string{
+		ecs.AnnotationTaskDefinitionArn: registered.TaskDefinitionArn,
+		ecs.AnnotationTaskArn:           "arn:aws:ecs:us-east-1:123456789012:task/apps/0123abcd",
+		ecs.AnnotationCluster:           "apps",
+	}
+
+	// The converter defaults send the logs to the stream the server reads
+	logs := ecsapi.NewFakeLogs()
+	logs.PutLogEvents("/ecs/task", "ecs/app/0123abcd", "starting")
+	server := httptest.NewServer((&Server{Client: newFakeClient(t, pod), ECS: ecsClient, Logs: logs}).Handler())
+	t.Cleanup(server.Close)
+
+	if status, body := get(t, server.URL+"/containerLogs/shop/web/app"); status != http.StatusOK || body != "starting\n" {
+		t.Errorf("logs = %d %q", status, body)
+	}
+}
+
+func TestServer_ExecIsNotSupported(t *testing.T) {
+	_, server := newTestServer(t)
+
+	resp, err := http.Post(server.URL+"/exec/shop/web/app?command=sh&stdin=true", "", nil)
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusNotImplemented || !strings.Contains(string(body), "exec is not supported") {
+		t.Errorf("exec = %d %q, want not implemented", resp.StatusCode, body)
+	}
+}
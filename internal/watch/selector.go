@@ -0,0 +1,123 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package watch selects the pods the controller and the webhooks handle
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Label is the label selecting watched pods by default
+const Label = "ecs.takutakahashi.dev/watch"
+
+// Selector selects watched pods by their labels and the labels of their namespace
+type Selector struct {
+	Pods       metav1.LabelSelector
+	Namespaces metav1.LabelSelector
+}
+
+// DefaultSelector selects the pods with the watch label in every namespace
+func DefaultSelector() Selector {
+	return Selector{Pods: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: Label, Operator: metav1.LabelSelectorOpExists},
+	}}}
+}
+
+// ParseSelector parses selectors in the syntax of kubectl --selector, such as
+// "ecs.takutakahashi.dev/watch,team in (web,batch)". An empty namespace selector selects every
+// namespace; the pod selector must not be empty.
+func ParseSelector(pods, namespaces string) (Selector, error) {
+	if pods == "" {
+		return Selector{}, errors.New("the pod selector must not be empty")
+	}
+	podSelector, err := metav1.ParseToLabelSelector(pods)
+	if err != nil {
+		return Selector{}, fmt.Errorf("invalid pod selector %q: %w", pods, err)
+	}
+	namespaceSelector, err := metav1.ParseToLabelSelector(namespaces)
+	if err != nil {
+		return Selector{}, fmt.Errorf("invalid namespace selector %q: %w", namespaces, err)
+	}
+	return Selector{Pods: *podSelector, Namespaces: *namespaceSelector}, nil
+}
+
+// Matcher tells whether a pod is watched
+type Matcher struct {
+	selector   Selector
+	pods       labels.Selector
+	namespaces labels.Selector
+	// reader reads the labels of namespaces; it is only used with a namespace selector
+	reader client.Reader
+}
+
+// NewMatcher returns the matcher of the selector. reader reads namespaces and may be nil when
+// the selector does not select namespaces.
+func NewMatcher(selector Selector, reader client.Reader) (*Matcher, error) {
+	pods, err := metav1.LabelSelectorAsSelector(&selector.Pods)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod selector: %w", err)
+	}
+	namespaces, err := metav1.LabelSelectorAsSelector(&selector.Namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace selector: %w", err)
+	}
+	if !namespaces.Empty() && reader == nil {
+		return nil, errors.New("a namespace selector requires a reader of namespaces")
+	}
+	return &Matcher{selector: selector, pods: pods, namespaces: namespaces, reader: reader}, nil
+}
+
+// DefaultMatcher returns the matcher of DefaultSelector
+func DefaultMatcher() *Matcher {
+	matcher, err := NewMatcher(DefaultSelector(), nil)
+	if err != nil {
+		panic(err)
+	}
+	return matcher
+}
+
+// Selector returns the selector the matcher evaluates
+func (m *Matcher) Selector() Selector {
+	return m.selector
+}
+
+// Matches reports whether the pod in the namespace is watched. The namespace is passed
+// separately because pods being admitted may not name it yet.
+func (m *Matcher) Matches(ctx context.Context, pod *corev1.Pod, namespace string) (bool, error) {
+	if !m.pods.Matches(labels.Set(pod.Labels)) {
+		return false, nil
+	}
+	if m.namespaces.Empty() {
+		return true, nil
+	}
+	ns := &corev1.Namespace{}
+	if err := m.reader.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+	return m.namespaces.Matches(labels.Set(ns.Labels)), nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newFakeClient(t *testing.T, objects ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func pod(labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Labels: labels}}
+}
+
+func TestMatcher_Matches(t *testing.T) {
+	ctx := context.Background()
+	reader := newFakeClient(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop", Labels: map[string]string{"ecs": "enabled"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+	)
+	selector, err := ParseSelector("team in (web,batch),!legacy", "ecs=enabled")
+	if err != nil {
+		t.Fatalf("ParseSelector() error = %v", err)
+	}
+	matcher, err := NewMatcher(selector, reader)
+	if err != nil {
+		t.Fatalf("NewMatcher() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		labels    map[string]string
+		namespace string
+		want      bool
+	}{
+		{"selected pod in a selected namespace", map[string]string{"team": "web"}, "shop", true},
+		{"pod not selected", map[string]string{"team": "web", "legacy": "true"}, "shop", false},
+		{"namespace not selected", map[string]string{"team": "web"}, "kube-system", false},
+		{"missing namespace", map[string]string{"team": "batch"}, "gone", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matcher.Matches(ctx, pod(tt.labels), tt.namespace)
+			if err != nil || got != tt.want {
+				t.Errorf("Matches() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultMatcher(t *testing.T) {
+	matcher := DefaultMatcher()
+	// The default matcher does not read namespaces
+	if got, err := matcher.Matches(context.Background(), pod(map[string]string{Label: ""}), "shop"); err != nil || !got {
+		t.Errorf("Matches() of a pod with the watch label = %v, %v", got, err)
+	}
+	if got, _ := matcher.Matches(context.Background(), pod(nil), "shop"); got {
+		t.Error("Matches() of a pod without the watch label = true")
+	}
+}
+
+func TestParseSelector_Invalid(t *testing.T) {
+	for _, selectors := range [][2]string{{"", ""}, {"team in web", ""}, {Label, "=="}} {
+		if _, err := ParseSelector(selectors[0], selectors[1]); err == nil {
+			t.Errorf("ParseSelector(%q, %q) error = nil", selectors[0], selectors[1])
+		}
+	}
+	selector, _ := ParseSelector(Label, "")
+	if _, err := NewMatcher(Selector{Pods: selector.Pods, Namespaces: metav1.LabelSelector{
+		MatchLabels: map[string]string{"ecs": "enabled"},
+	}}, nil); err == nil {
+		t.Error("NewMatcher() with a namespace selector and no reader error = nil")
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// webhookSyncRetryInterval is how often a failed update of the webhook selectors is retried
+const webhookSyncRetryInterval = 10 * time.Second
+
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations,verbs=get;patch
+
+// WebhookSelectors sets the objectSelector and namespaceSelector of the pod webhooks to the
+// selector of watched pods when the manager becomes the leader, so that the API server only
+// sends watched pods to the webhooks and filters them like the controller does. The workload
+// webhooks get the namespace selector.
+type WebhookSelectors struct {
+	Client   client.Client
+	Selector Selector
+	// MutatingConfiguration and ValidatingConfiguration name the webhook configurations; missing
+	// ones are skipped
+	MutatingConfiguration   string
+	ValidatingConfiguration string
+	// PodWebhooks name the webhooks of the configurations that admit pods
+	PodWebhooks []string
+	// WorkloadWebhooks name the webhooks that admit workloads by their pod templates; the labels
+	// of the workloads are not the ones of their pods, so only the namespace selector is set
+	WorkloadWebhooks []string
+}
+
+var _ manager.LeaderElectionRunnable = &WebhookSelectors{}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (w *WebhookSelectors) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable. It returns once the selectors are set.
+func (w *WebhookSelectors) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("webhook-selectors")
+	for {
+		err := w.sync(ctx)
+		if err == nil {
+			return nil
+		}
+		log.Error(err, "Failed to set the selectors of the webhooks")
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(webhookSyncRetryInterval):
+		}
+	}
+}
+
+// sync sets the selectors of both configurations
+func (w *WebhookSelectors) sync(ctx context.Context) error {
+	if w.MutatingConfiguration != "" {
+		configuration := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		err := w.update(ctx, w.MutatingConfiguration, configuration, func() {
+			for i := range configuration.Webhooks {
+				webhook := &configuration.Webhooks[i]
+				w.apply(webhook.Name, &webhook.ObjectSelector, &webhook.NamespaceSelector)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if w.ValidatingConfiguration != "" {
+		configuration := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		return w.update(ctx, w.ValidatingConfiguration, configuration, func() {
+			for i := range configuration.Webhooks {
+				webhook := &configuration.Webhooks[i]
+				w.apply(webhook.Name, &webhook.ObjectSelector, &webhook.NamespaceSelector)
+			}
+		})
+	}
+	return nil
+}
+
+// update reads the configuration, lets mutate set the selectors and patches it when they
+// changed. The patch fails instead of overwriting a configuration changed in between.
+func (w *WebhookSelectors) update(ctx context.Context, name string, configuration client.Object, mutate func()) error {
+	if err := w.Client.Get(ctx, client.ObjectKey{Name: name}, configuration); err != nil {
+		if apierrors.IsNotFound(err) {
+			logf.FromContext(ctx).Info("Webhook configuration not found; its selectors are not set", "name", name)
+			return nil
+		}
+		return err
+	}
+	original := configuration.DeepCopyObject().(client.Object)
+	mutate()
+	if equality.Semantic.DeepEqual(original, configuration) {
+		return nil
+	}
+	patch := client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})
+	if err := w.Client.Patch(ctx, configuration, patch); err != nil {
+		return err
+	}
+	logf.FromContext(ctx).Info("Set the selectors of the webhooks", "configuration", name)
+	return nil
+}
+
+// apply sets the selectors of the webhook when it admits pods or workloads
+func (w *WebhookSelectors) apply(name string, objectSelector, namespaceSelector **metav1.LabelSelector) {
+	switch {
+	case slices.Contains(w.PodWebhooks, name):
+		*objectSelector = w.Selector.Pods.DeepCopy()
+		*namespaceSelector = w.Selector.Namespaces.DeepCopy()
+	case slices.Contains(w.WorkloadWebhooks, name):
+		*namespaceSelector = w.Selector.Namespaces.DeepCopy()
+	}
+}
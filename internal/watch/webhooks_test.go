@@ -0,0 +1,105 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestWebhookSelectors_Start(t *testing.T) {
+	ctx := context.Background()
+	other := &metav1.LabelSelector{MatchLabels: map[string]string{"other": "operator"}}
+	k8sClient := newFakeClient(t, &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "mutating"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "mpod-v1.kb.io"},
+			{Name: "other.example.com", ObjectSelector: other},
+		},
+	})
+	selector, _ := ParseSelector(Label+",team=web", "ecs=enabled")
+	w := &WebhookSelectors{
+		Client:   k8sClient,
+		Selector: selector,
+		// The validating configuration is not installed
+		MutatingConfiguration:   "mutating",
+		ValidatingConfiguration: "validating",
+		PodWebhooks:             []string{"mpod-v1.kb.io", "vpod-v1.kb.io"},
+	}
+
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	configuration := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "mutating"}, configuration); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	pods := configuration.Webhooks[0]
+	if !equality.Semantic.DeepEqual(*pods.ObjectSelector, selector.Pods) ||
+		!equality.Semantic.DeepEqual(*pods.NamespaceSelector, selector.Namespaces) {
+		t.Errorf("selectors = %v, %v", pods.ObjectSelector, pods.NamespaceSelector)
+	}
+	if !equality.Semantic.DeepEqual(configuration.Webhooks[1].ObjectSelector, other) {
+		t.Errorf("selector of another webhook = %v", configuration.Webhooks[1].ObjectSelector)
+	}
+
+	// Selectors already in place are not patched again
+	version := configuration.ResourceVersion
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("second Start() error = %v", err)
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "mutating"}, configuration); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if configuration.ResourceVersion != version {
+		t.Errorf("resource version = %s, want %s", configuration.ResourceVersion, version)
+	}
+}
+
+func TestWebhookSelectors_WorkloadWebhooks(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := newFakeClient(t, &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "validating"},
+		Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "vdeployment-v1.kb.io"}},
+	})
+	selector, _ := ParseSelector(Label, "ecs=enabled")
+	w := &WebhookSelectors{
+		Client:                  k8sClient,
+		Selector:                selector,
+		ValidatingConfiguration: "validating",
+		WorkloadWebhooks:        []string{"vdeployment-v1.kb.io"},
+	}
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// The labels of workloads are not the ones of their pods
+	configuration := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "validating"}, configuration); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	deployments := configuration.Webhooks[0]
+	if deployments.ObjectSelector != nil ||
+		!equality.Semantic.DeepEqual(*deployments.NamespaceSelector, selector.Namespaces) {
+		t.Errorf("selectors = %v, %v", deployments.ObjectSelector, deployments.NamespaceSelector)
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/takutakahashi/k8s-ecstask/internal/controller"
+)
+
+// Admission decisions on watched objects
+const (
+	// decisionAllowed admits an object that converts
+	decisionAllowed = "allowed"
+	// decisionDenied rejects an object that does not convert
+	decisionDenied = "denied"
+	// decisionWarned admits an object that does not convert with warnings
+	decisionWarned = "warned"
+	// decisionAudited admits an object that does not convert, only logging it
+	decisionAudited = "audited"
+)
+
+// admissionDecisions counts the decisions of the validating webhooks on watched objects
+var admissionDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: controller.MetricsNamespace,
+	Name:      "webhook_decisions_total",
+	Help:      "Number of admission decisions on watched objects by namespace, admission mode, kind and decision.",
+}, []string{"namespace", "mode", "kind", "decision"})
+
+func init() {
+	metrics.Registry.MustRegister(admissionDecisions)
+}
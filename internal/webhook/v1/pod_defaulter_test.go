@@ -0,0 +1,140 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/takutakahashi/k8s-ecstask/internal/controller"
+	"github.com/takutakahashi/k8s-ecstask/internal/virtualnode"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+func newTestDefaulter() *PodCustomDefaulter {
+	return &PodCustomDefaulter{
+		Converter: ecs.NewConverter(ecs.ConversionOptions{
+			DefaultCPU:    "256",
+			DefaultMemory: "512",
+			DefaultRequests: map[string]corev1.ResourceList{
+				ecs.AllNamespaces: {corev1.ResourceMemory: resource.MustParse("128Mi")},
+				"batch":           {corev1.ResourceCPU: resource.MustParse("500m")},
+			},
+		}),
+		Offload: true,
+	}
+}
+
+func TestPodCustomDefaulter_Default(t *testing.T) {
+	ctx := context.Background()
+	defaulter := newTestDefaulter()
+	pod := watchedPod("shop", true)
+	pod.Spec.TerminationGracePeriodSeconds = ptr.To[int64](60)
+	limits := corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")}
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+		Name:      "sidecar",
+		Image:     "busybox",
+		Resources: corev1.ResourceRequirements{Limits: limits},
+	})
+
+	if err := defaulter.Default(ctx, pod); err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+	// Requests are filled from the namespace profile, except for resources with a limit
+	if memory := pod.Spec.Containers[0].Resources.Requests[corev1.ResourceMemory]; memory.String() != "128Mi" {
+		t.Errorf("memory request = %s, want 128Mi", memory.String())
+	}
+	if _, ok := pod.Spec.Containers[1].Resources.Requests[corev1.ResourceMemory]; ok {
+		t.Errorf("requests of a limited container = %v", pod.Spec.Containers[1].Resources.Requests)
+	}
+	if len(pod.Spec.SchedulingGates) != 1 || pod.Spec.SchedulingGates[0].Name != controller.OffloadSchedulingGate ||
+		len(pod.Finalizers) != 1 || pod.Finalizers[0] != controller.TaskCleanupFinalizer ||
+		len(pod.Spec.Tolerations) != 1 || pod.Spec.Tolerations[0] != virtualnode.Toleration() {
+		t.Errorf("gates = %v, finalizers = %v, tolerations = %v", pod.Spec.SchedulingGates, pod.Finalizers,
+			pod.Spec.Tolerations)
+	}
+	family, err := defaulter.Converter.FamilyName(watchedPod("shop", true), "shop")
+	if err != nil {
+		t.Fatalf("FamilyName() error = %v", err)
+	}
+	if pod.Annotations[ecs.AnnotationFamily] != family ||
+		pod.Annotations[ecs.AnnotationRequiresCompatibilities] != "FARGATE" {
+		t.Errorf("annotations = %v", pod.Annotations)
+	}
+
+	// The hash is the one of the task definition the controller registers for the pod
+	taskDef, _, err := defaulter.Converter.ConvertPodWithDiagnostics(ctx, pod, &pod.Spec,
+		controller.ManagedECSConfig(), "shop")
+	if err != nil {
+		t.Fatalf("ConvertPodWithDiagnostics() error = %v", err)
+	}
+	controller.FinishTaskDefinition(pod, taskDef)
+	if hash, _ := ecs.TaskDefinitionHash(taskDef); pod.Annotations[ecs.AnnotationTaskDefinitionHash] != hash {
+		t.Errorf("hash = %s, want %s", pod.Annotations[ecs.AnnotationTaskDefinitionHash], hash)
+	}
+
+	// Defaulting again changes nothing
+	defaulted := pod.DeepCopy()
+	if err := defaulter.Default(ctx, pod); err != nil || !equality.Semantic.DeepEqual(pod, defaulted) {
+		t.Errorf("second Default() = %v, changed the pod to %+v", err, pod)
+	}
+}
+
+func TestPodCustomDefaulter_LeavesPodsAlone(t *testing.T) {
+	defaulter := newTestDefaulter()
+	create := admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Create, Namespace: "batch"},
+	})
+
+	// Pods without the watch label
+	unwatched := watchedPod("batch", true)
+	unwatched.Labels = nil
+	want := unwatched.DeepCopy()
+	if err := defaulter.Default(create, unwatched); err != nil || !equality.Semantic.DeepEqual(unwatched, want) {
+		t.Errorf("Default() of an unwatched pod = %v, %+v", err, unwatched)
+	}
+
+	// Updates, such as the controller removing the gate and the finalizer
+	updated := watchedPod("batch", true)
+	want = updated.DeepCopy()
+	update := admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Update},
+	})
+	if err := defaulter.Default(update, updated); err != nil || !equality.Semantic.DeepEqual(updated, want) {
+		t.Errorf("Default() on update = %v, %+v", err, updated)
+	}
+
+	// Without offloading, pods that do not convert only get their requests
+	defaulter.Offload = false
+	unconvertible := watchedPod("", false)
+	if err := defaulter.Default(create, unconvertible); err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+	if cpu := unconvertible.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU]; cpu.String() != "500m" {
+		t.Errorf("cpu request in the namespace of the request = %s, want 500m", cpu.String())
+	}
+	if unconvertible.Annotations != nil || unconvertible.Finalizers != nil || unconvertible.Spec.SchedulingGates != nil {
+		t.Errorf("pod = %+v", unconvertible)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/takutakahashi/k8s-ecstask/internal/watch"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+func newTestValidator(t *testing.T, namespaces ...client.Object) *PodCustomValidator {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+	return &PodCustomValidator{
+		Converter:   ecs.NewConverter(ecs.ConversionOptions{DefaultCPU: "256", DefaultMemory: "512"}),
+		Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(namespaces...).Build(),
+		DefaultMode: AdmissionModeEnforce,
+	}
+}
+
+// watchedPod returns a watched pod in the namespace; init containers and secret volumes do not
+// convert
+func watchedPod(namespace string, convertible bool) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace, Labels: map[string]string{watch.Label: "true"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:           "web",
+			Image:          "nginx:1.27",
+			ReadinessProbe: &corev1.Probe{},
+		}}},
+	}
+	if !convertible {
+		pod.Spec.InitContainers = []corev1.Container{{Name: "migrate", Image: "migrate:1.0"}}
+		pod.Spec.Volumes = []corev1.Volume{{Name: "tls", VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: "tls"},
+		}}}
+	}
+	return pod
+}
+
+func TestPodCustomValidator_ValidateCreate(t *testing.T) {
+	ctx := context.Background()
+	validator := newTestValidator(t)
+
+	// Convertible pods are admitted with the lossy mappings and other diagnostics as warnings
+	warnings, err := validator.ValidateCreate(ctx, watchedPod("shop", true))
+	if err != nil || len(warnings) != 2 || !strings.HasPrefix(warnings[0], "spec.containers[0]: probes") ||
+		!strings.HasPrefix(warnings[1], "taskRoleArn: ") {
+		t.Errorf("ValidateCreate() = %v, %v", warnings, err)
+	}
+
+	// Every field preventing the conversion is named
+	_, err = validator.ValidateCreate(ctx, watchedPod("shop", false))
+	statusErr, ok := err.(*apierrors.StatusError)
+	if !ok || !apierrors.IsInvalid(err) {
+		t.Fatalf("ValidateCreate() error = %v, want Invalid", err)
+	}
+	causes := statusErr.Status().Details.Causes
+	if len(causes) != 2 || causes[0].Field != "spec.initContainers" || causes[1].Field != "spec.volumes[0]" ||
+		causes[0].Type != metav1.CauseTypeForbidden {
+		t.Errorf("causes = %+v", causes)
+	}
+
+	// The managed-by tag the controller adds counts towards the tag limit
+	overtagged := watchedPod("shop", true)
+	tags := make([]string, ecs.MaxTagsPerResource)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("tag%d=value", i)
+	}
+	overtagged.Annotations = map[string]string{ecs.AnnotationTags: strings.Join(tags, ",")}
+	if _, err := validator.ValidateCreate(ctx, overtagged); !apierrors.IsInvalid(err) ||
+		!strings.Contains(err.Error(), "51 tags") {
+		t.Errorf("ValidateCreate() of a pod with %d tags error = %v, want Invalid", len(tags), err)
+	}
+
+	// Pods without the watch label are not checked
+	unwatched := watchedPod("shop", false)
+	unwatched.Labels = nil
+	if warnings, err := validator.ValidateCreate(ctx, unwatched); err != nil || warnings != nil {
+		t.Errorf("ValidateCreate() of an unwatched pod = %v, %v", warnings, err)
+	}
+}
+
+func TestPodCustomValidator_NamespaceModes(t *testing.T) {
+	ctx := context.Background()
+	namespace := func(name, mode string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name: name, Labels: map[string]string{AdmissionModeLabel: mode},
+		}}
+	}
+	validator := newTestValidator(t, namespace("staging", "warn"), namespace("legacy", "audit"),
+		namespace("typo", "enforced"))
+
+	warnings, err := validator.ValidateCreate(ctx, watchedPod("staging", false))
+	if err != nil || len(warnings) != 3 || !strings.HasPrefix(warnings[1], "spec.initContainers: does not convert") {
+		t.Errorf("warn mode = %v, %v", warnings, err)
+	}
+	warnings, err = validator.ValidateCreate(ctx, watchedPod("legacy", false))
+	if err != nil || len(warnings) != 1 {
+		t.Errorf("audit mode = %v, %v", warnings, err)
+	}
+	// Unknown modes fall back to the default
+	if _, err := validator.ValidateCreate(ctx, watchedPod("typo", false)); !apierrors.IsInvalid(err) {
+		t.Errorf("unknown mode error = %v, want Invalid", err)
+	}
+
+	validator.DefaultMode = AdmissionModeWarn
+	warned := admissionDecisions.WithLabelValues("shop", string(AdmissionModeWarn), "Pod", decisionWarned)
+	before := testutil.ToFloat64(warned)
+	if _, err := validator.ValidateCreate(ctx, watchedPod("shop", false)); err != nil {
+		t.Errorf("default warn mode error = %v", err)
+	}
+	// Decisions are counted by namespace and mode
+	if got := testutil.ToFloat64(warned) - before; got != 1 {
+		t.Errorf("warned decisions increased by %v, want 1", got)
+	}
+}
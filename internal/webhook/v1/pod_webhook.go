@@ -19,53 +19,374 @@ package v1
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/takutakahashi/k8s-ecstask/internal/controller"
+	"github.com/takutakahashi/k8s-ecstask/internal/virtualnode"
+	"github.com/takutakahashi/k8s-ecstask/internal/watch"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
 )
 
 // nolint:unused
 // log is for logging in this package.
 var podlog = logf.Log.WithName("pod-resource")
 
+// Names of the pod webhooks, whose selectors follow the selector of watched pods
+const (
+	MutatingPodWebhookName   = "mpod-v1.kb.io"
+	ValidatingPodWebhookName = "vpod-v1.kb.io"
+)
+
+// AdmissionModeLabel is the namespace label overriding the admission mode of its pods
+const AdmissionModeLabel = "ecs.takutakahashi.dev/admission-mode"
+
+// AdmissionMode is how the validating webhook treats watched pods that do not convert to a task
+// definition
+type AdmissionMode string
+
+const (
+	// AdmissionModeEnforce rejects the pods
+	AdmissionModeEnforce AdmissionMode = "enforce"
+	// AdmissionModeWarn admits the pods, returning the conversion errors as warnings
+	AdmissionModeWarn AdmissionMode = "warn"
+	// AdmissionModeAudit admits the pods and only logs the conversion errors
+	AdmissionModeAudit AdmissionMode = "audit"
+)
+
+// AdmissionModes lists the valid admission modes
+var AdmissionModes = []AdmissionMode{AdmissionModeEnforce, AdmissionModeWarn, AdmissionModeAudit}
+
+// PodWebhookOptions configure the Pod webhooks
+type PodWebhookOptions struct {
+	// Converter converts watched pods like the controller does
+	Converter *ecs.Converter
+	// DefaultMode applies to namespaces without the admission mode label (default: enforce)
+	DefaultMode AdmissionMode
+	// Offload tells that the controller runs watched pods on ECS, so that the defaulter adds the
+	// scheduling gate, finalizer and toleration it needs
+	Offload bool
+	// Matcher selects the watched pods like the controller does (default: the pods with the
+	// watch label)
+	Matcher *watch.Matcher
+}
+
+// complete fills in the defaults of the options
+func (o *PodWebhookOptions) complete() error {
+	if o.Converter == nil {
+		o.Converter = ecs.NewConverter(ecs.ConversionOptions{})
+	}
+	if o.DefaultMode == "" {
+		o.DefaultMode = AdmissionModeEnforce
+	}
+	if !slices.Contains(AdmissionModes, o.DefaultMode) {
+		return fmt.Errorf("unknown admission mode %q", o.DefaultMode)
+	}
+	if o.Matcher == nil {
+		o.Matcher = watch.DefaultMatcher()
+	}
+	return nil
+}
+
+// validator returns the validator of watched pods
+func (o *PodWebhookOptions) validator(mgr ctrl.Manager) *PodCustomValidator {
+	return &PodCustomValidator{
+		Converter:   o.Converter,
+		Client:      mgr.GetClient(),
+		DefaultMode: o.DefaultMode,
+		Matcher:     o.Matcher,
+	}
+}
+
 // SetupPodWebhookWithManager registers the webhook for Pod in the manager.
-func SetupPodWebhookWithManager(mgr ctrl.Manager) error {
+func SetupPodWebhookWithManager(mgr ctrl.Manager, options PodWebhookOptions) error {
+	if err := options.complete(); err != nil {
+		return err
+	}
 	return ctrl.NewWebhookManagedBy(mgr).For(&corev1.Pod{}).
-		WithDefaulter(&PodCustomDefaulter{}).
+		WithDefaulter(&PodCustomDefaulter{
+			Converter: options.Converter,
+			Offload:   options.Offload,
+			Matcher:   options.Matcher,
+		}).
+		WithValidator(options.validator(mgr)).
 		Complete()
 }
 
-// TODO(user): EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
-
-// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create;update,versions=v1,name=mpod-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod-v1.kb.io,admissionReviewVersions=v1
 
-// PodCustomDefaulter struct is responsible for setting default values on the custom resource of the
-// Kind Pod when those are created or updated.
-//
-// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
-// as it is used only for temporary operations and does not need to be deeply copied.
+// PodCustomDefaulter completes watched pods on creation with what their conversion needs: the
+// resource requests the profile defaults for the namespace, and annotations pinning the family
+// and launch types the pod converts to with the content hash of its task definition. When the
+// controller offloads pods it also adds the offload scheduling gate, the task cleanup finalizer
+// and the toleration of the virtual node. Updates are left alone: the controller removes the
+// gate and the finalizer itself.
 type PodCustomDefaulter struct {
-	// TODO(user): Add more fields as needed for defaulting
+	Converter *ecs.Converter
+	Offload   bool
+	// Matcher selects the watched pods (default: the pods with the watch label)
+	Matcher *watch.Matcher
 }
 
 var _ webhook.CustomDefaulter = &PodCustomDefaulter{}
 
 // Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind Pod.
-func (d *PodCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+func (d *PodCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
 	pod, ok := obj.(*corev1.Pod)
 
 	if !ok {
 		return fmt.Errorf("expected an Pod object but got %T", obj)
 	}
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Operation != admissionv1.Create {
+		return nil
+	}
+	namespace := requestNamespace(ctx, pod)
+	if watched, err := watches(ctx, d.Matcher, pod, namespace); err != nil || !watched {
+		return err
+	}
 
-	// Check if Pod has the watch label - if so, block its creation
-	if _, hasWatchLabel := pod.Labels["ecs.takutakahashi.dev/watch"]; hasWatchLabel {
-		podlog.Info("Blocking Pod creation due to watch label", "name", pod.GetName(), "namespace", pod.GetNamespace())
-		return fmt.Errorf("pod creation blocked: pods with label 'ecs.takutakahashi.dev/watch' are not allowed")
+	d.Converter.DefaultRequests(&pod.Spec, namespace)
+	if d.Offload {
+		controllerutil.AddFinalizer(pod, controller.TaskCleanupFinalizer)
+		gated := slices.ContainsFunc(pod.Spec.SchedulingGates,
+			func(gate corev1.PodSchedulingGate) bool { return gate.Name == controller.OffloadSchedulingGate })
+		// Pods bound to a node on creation cannot be gated
+		if !gated && pod.Spec.NodeName == "" {
+			pod.Spec.SchedulingGates = append(pod.Spec.SchedulingGates,
+				corev1.PodSchedulingGate{Name: controller.OffloadSchedulingGate})
+		}
+		if toleration := virtualnode.Toleration(); !slices.Contains(pod.Spec.Tolerations, toleration) {
+			pod.Spec.Tolerations = append(pod.Spec.Tolerations, toleration)
+		}
 	}
 
+	// Pinning the family and the launch types keeps the conversion by the controller from
+	// resolving them differently, such as a family named after the pod before it got its name.
+	// Pods that do not convert are left to the validating webhook.
+	taskDef, err := d.convert(ctx, pod, namespace)
+	if err != nil || taskDef == nil {
+		return err
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[ecs.AnnotationFamily] = taskDef.Family
+	pod.Annotations[ecs.AnnotationRequiresCompatibilities] = strings.Join(taskDef.RequiresCompatibilities, ",")
+	// The annotations may be propagated into the task definition, so it is converted again
+	if taskDef, err = d.convert(ctx, pod, namespace); err != nil || taskDef == nil {
+		return err
+	}
+	hash, err := ecs.TaskDefinitionHash(taskDef)
+	if err != nil {
+		return err
+	}
+	pod.Annotations[ecs.AnnotationTaskDefinitionHash] = hash
 	return nil
 }
+
+// convert returns the task definition the controller would register for the pod, or nil when
+// the pod does not convert
+func (d *PodCustomDefaulter) convert(
+	ctx context.Context,
+	pod *corev1.Pod,
+	namespace string,
+) (*ecs.ECSTaskDefinition, error) {
+	pod = pod.DeepCopy()
+	pod.Namespace = namespace
+	check := d.Converter.CheckPod(ctx, pod, controller.ManagedECSConfig())
+	if !check.Convertible() {
+		return nil, nil
+	}
+	controller.FinishTaskDefinition(pod, check.TaskDefinition)
+	return check.TaskDefinition, nil
+}
+
+// +kubebuilder:webhook:path=/validate--v1-pod,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=vpod-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// PodCustomValidator admits watched pods only if they convert to a task definition ECS accepts,
+// naming every field that prevents the conversion. Fields whose mapping loses information are
+// returned as warnings. Updates are not checked: the spec of a pod hardly changes after creation
+// and the controller updates watched pods itself.
+type PodCustomValidator struct {
+	Converter *ecs.Converter
+	// Client reads the admission mode label of namespaces
+	Client      client.Reader
+	DefaultMode AdmissionMode
+	// Matcher selects the watched pods (default: the pods with the watch label)
+	Matcher *watch.Matcher
+}
+
+var _ webhook.CustomValidator = &PodCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Pod.
+func (v *PodCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("expected a Pod object but got %T", obj)
+	}
+	gk := corev1.SchemeGroupVersion.WithKind("Pod").GroupKind()
+	return v.admit(ctx, gk, objectName(pod), requestNamespace(ctx, pod), pod, "")
+}
+
+// admit decides on the admission of an object by checking the conversion of pod, which is the
+// object itself or its pod template at path. Errors and warnings name the fields of the object.
+func (v *PodCustomValidator) admit(
+	ctx context.Context,
+	gk schema.GroupKind,
+	name, namespace string,
+	pod *corev1.Pod,
+	path string,
+) (admission.Warnings, error) {
+	if watched, err := watches(ctx, v.Matcher, pod, namespace); err != nil || !watched {
+		return nil, err
+	}
+	mode, err := v.mode(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	pod = pod.DeepCopy()
+	pod.Namespace = namespace
+	check := v.Converter.CheckPod(ctx, pod, controller.ManagedECSConfig())
+	var warnings admission.Warnings
+	for _, diagnostic := range check.Warnings() {
+		warnings = append(warnings, formatWarning(templateField(path, diagnostic.Field), diagnostic.Message))
+	}
+	decide := func(decision string) {
+		admissionDecisions.WithLabelValues(namespace, string(mode), gk.Kind, decision).Inc()
+	}
+	if check.Convertible() {
+		decide(decisionAllowed)
+		return warnings, nil
+	}
+
+	switch mode {
+	case AdmissionModeEnforce:
+		decide(decisionDenied)
+		return warnings, apierrors.NewInvalid(gk, name, fieldErrors(path, check.Errors))
+	case AdmissionModeWarn:
+		decide(decisionWarned)
+		for _, validationErr := range check.Errors {
+			warnings = append(warnings, formatWarning(templateField(path, validationErr.Field),
+				"does not convert to ECS: "+validationErr.Message))
+		}
+		return warnings, nil
+	default:
+		decide(decisionAudited)
+		podlog.Info("Admitting object that does not convert to an ECS task definition", "kind", gk.Kind,
+			"name", name, "namespace", namespace, "errors", check.Errors.Error())
+		return warnings, nil
+	}
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Pod.
+func (v *PodCustomValidator) ValidateUpdate(_ context.Context, _, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Pod.
+func (v *PodCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// mode returns the admission mode of the namespace
+func (v *PodCustomValidator) mode(ctx context.Context, namespace string) (AdmissionMode, error) {
+	ns := &corev1.Namespace{}
+	if err := v.Client.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return v.DefaultMode, nil
+		}
+		return "", fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+	value, ok := ns.Labels[AdmissionModeLabel]
+	if !ok {
+		return v.DefaultMode, nil
+	}
+	mode := AdmissionMode(value)
+	if !slices.Contains(AdmissionModes, mode) {
+		podlog.Info("Ignoring unknown admission mode", "namespace", namespace, "mode", value)
+		return v.DefaultMode, nil
+	}
+	return mode, nil
+}
+
+// watches reports whether the matcher, or the default one when it is nil, selects the pod. The
+// API server only sends the pods the webhook selectors select, but they are set by the manager
+// and may lag behind its selector.
+func watches(ctx context.Context, matcher *watch.Matcher, pod *corev1.Pod, namespace string) (bool, error) {
+	if matcher == nil {
+		matcher = watch.DefaultMatcher()
+	}
+	return matcher.Matches(ctx, pod, namespace)
+}
+
+// requestNamespace returns the namespace of the object, which is only set on the request when
+// the object does not name it
+func requestNamespace(ctx context.Context, obj metav1.Object) string {
+	if namespace := obj.GetNamespace(); namespace != "" {
+		return namespace
+	}
+	if req, err := admission.RequestFromContext(ctx); err == nil {
+		return req.Namespace
+	}
+	return ""
+}
+
+// objectName names the object in messages; generated names are only known after admission
+func objectName(obj metav1.Object) string {
+	if obj.GetName() == "" && obj.GetGenerateName() != "" {
+		return obj.GetGenerateName() + "*"
+	}
+	return obj.GetName()
+}
+
+// templateField returns the field of a pod template at path, such as spec.template, that is the
+// field of the pod; the fields of the task definition are left as they are
+func templateField(path, podField string) string {
+	if path == "" || !strings.HasPrefix(podField, "spec") && !strings.HasPrefix(podField, "metadata") {
+		return podField
+	}
+	return path + "." + podField
+}
+
+func formatWarning(field, message string) string {
+	if field == "" {
+		return message
+	}
+	return field + ": " + message
+}
+
+// fieldErrors converts conversion errors of the pod template at path, or of the pod when it is
+// empty, to the errors of an Invalid status. Fields ECS has no equivalent for are forbidden;
+// other values are invalid.
+func fieldErrors(path string, errs ecs.ValidationErrors) field.ErrorList {
+	var list field.ErrorList
+	for _, validationErr := range errs {
+		errType := field.ErrorTypeInvalid
+		if validationErr.Code == ecs.ValidationUnsupported {
+			errType = field.ErrorTypeForbidden
+		}
+		list = append(list, &field.Error{
+			Type:     errType,
+			Field:    templateField(path, validationErr.Field),
+			BadValue: field.OmitValueType{},
+			Detail:   validationErr.Message,
+		})
+	}
+	return list
+}
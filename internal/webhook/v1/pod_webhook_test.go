@@ -24,6 +24,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
 )
 
 var _ = Describe("Pod Webhook", func() {
@@ -36,7 +38,9 @@ var _ = Describe("Pod Webhook", func() {
 	BeforeEach(func() {
 		obj = &corev1.Pod{}
 		oldObj = &corev1.Pod{}
-		defaulter = PodCustomDefaulter{}
+		defaulter = PodCustomDefaulter{
+			Converter: ecs.NewConverter(ecs.ConversionOptions{DefaultCPU: "256", DefaultMemory: "512"}),
+		}
 		Expect(defaulter).NotTo(BeNil(), "Expected defaulter to be initialized")
 		Expect(oldObj).NotTo(BeNil(), "Expected oldObj to be initialized")
 		Expect(obj).NotTo(BeNil(), "Expected obj to be initialized")
@@ -48,7 +52,7 @@ var _ = Describe("Pod Webhook", func() {
 	})
 
 	Context("When creating Pod under Defaulting Webhook", func() {
-		It("Should block Pod with watch label", func() {
+		It("Should pin the conversion of Pod with watch label", func() {
 			By("creating a Pod with watch label")
 			obj = &corev1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
@@ -71,24 +75,19 @@ var _ = Describe("Pod Webhook", func() {
 			By("calling the Default method")
 			err := defaulter.Default(context.Background(), obj)
 
-			By("checking that the Pod creation is blocked")
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("pods with label 'ecs.takutakahashi.dev/watch' are not allowed"))
+			By("checking that the family and the task definition hash are recorded")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(obj.Annotations).To(HaveKeyWithValue(ecs.AnnotationFamily, "default-test-pod-47fb0c00"))
+			Expect(obj.Annotations).To(HaveKeyWithValue(ecs.AnnotationRequiresCompatibilities, "FARGATE"))
+			Expect(obj.Annotations).To(HaveKey(ecs.AnnotationTaskDefinitionHash))
 		})
 
-		It("Should also block Pod with watch label and additional annotations", func() {
-			By("creating a Pod with watch label and other annotations")
+		It("Should ignore Pod without watch label", func() {
+			By("creating a Pod without watch label")
 			obj = &corev1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      "blocked-pod",
+					Name:      "normal-pod",
 					Namespace: "default",
-					Labels: map[string]string{
-						"ecs.takutakahashi.dev/watch": "true",
-						"app":                         "test",
-					},
-					Annotations: map[string]string{
-						"some-annotation": "value",
-					},
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
@@ -103,34 +102,40 @@ var _ = Describe("Pod Webhook", func() {
 			By("calling the Default method")
 			err := defaulter.Default(context.Background(), obj)
 
-			By("checking that the Pod creation is blocked")
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("pods with label 'ecs.takutakahashi.dev/watch' are not allowed"))
+			By("checking that the Pod is allowed without modifications")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(obj.Annotations).To(BeNil())
 		})
+	})
 
-		It("Should ignore Pod without watch label", func() {
-			By("creating a Pod without watch label")
+	Context("When creating Pod under Validating Webhook", func() {
+		It("Should reject a watched Pod that does not convert, naming every field", func() {
+			validator := PodCustomValidator{
+				Converter:   ecs.NewConverter(ecs.ConversionOptions{}),
+				Client:      k8sClient,
+				DefaultMode: AdmissionModeEnforce,
+			}
 			obj = &corev1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      "normal-pod",
+					Name:      "unconvertible-pod",
 					Namespace: "default",
+					Labels: map[string]string{
+						"ecs.takutakahashi.dev/watch": "true",
+					},
 				},
 				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "nginx",
-							Image: "nginx:alpine",
-						},
-					},
+					InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+					Containers:     []corev1.Container{{Name: "nginx", Image: "nginx:alpine"}},
+					Volumes: []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{},
+					}}},
 				},
 			}
 
-			By("calling the Default method")
-			err := defaulter.Default(context.Background(), obj)
-
-			By("checking that the Pod is allowed without modifications")
-			Expect(err).NotTo(HaveOccurred())
-			Expect(obj.Annotations).To(BeNil())
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.initContainers"))
+			Expect(err.Error()).To(ContainSubstring("spec.volumes[0]"))
 		})
 	})
 
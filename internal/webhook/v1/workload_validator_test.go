@@ -0,0 +1,101 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// template returns the pod template of a watched pod
+func template(convertible bool) corev1.PodTemplateSpec {
+	pod := watchedPod("", convertible)
+	return corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: pod.Labels}, Spec: pod.Spec}
+}
+
+func TestWorkloadCustomValidator_ValidateCreate(t *testing.T) {
+	ctx := context.Background()
+	validator := &WorkloadCustomValidator{Pods: newTestValidator(t), Scheme: clientgoscheme.Scheme}
+
+	// Errors name the fields of the template in the workload
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec:       appsv1.DeploymentSpec{Template: template(false)},
+	}
+	_, err := validator.ValidateCreate(ctx, deployment)
+	statusErr, ok := err.(*apierrors.StatusError)
+	if !ok || !apierrors.IsInvalid(err) {
+		t.Fatalf("ValidateCreate() error = %v, want Invalid", err)
+	}
+	details := statusErr.Status().Details
+	if details.Kind != "Deployment" || details.Group != "apps" || details.Name != "web" || len(details.Causes) != 2 ||
+		details.Causes[0].Field != "spec.template.spec.initContainers" {
+		t.Errorf("details = %+v", details)
+	}
+
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "shop"},
+		Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{
+			Spec: batchv1.JobSpec{Template: template(true)},
+		}},
+	}
+	warnings, err := validator.ValidateCreate(ctx, cronJob)
+	if err != nil || len(warnings) == 0 ||
+		!strings.HasPrefix(warnings[0], "spec.jobTemplate.spec.template.spec.containers[0]: probes") {
+		t.Errorf("ValidateCreate() of a CronJob = %v, %v", warnings, err)
+	}
+
+	// Templates without the watch label are not checked
+	unwatched := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "shop"},
+		Spec:       batchv1.JobSpec{Template: template(false)},
+	}
+	unwatched.Spec.Template.Labels = nil
+	if warnings, err := validator.ValidateCreate(ctx, unwatched); err != nil || warnings != nil {
+		t.Errorf("ValidateCreate() of an unwatched template = %v, %v", warnings, err)
+	}
+}
+
+func TestWorkloadCustomValidator_ValidateUpdate(t *testing.T) {
+	ctx := context.Background()
+	validator := &WorkloadCustomValidator{Pods: newTestValidator(t), Scheme: clientgoscheme.Scheme}
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shop"},
+		Spec:       appsv1.StatefulSetSpec{Template: template(false)},
+	}
+
+	// Updates leaving the template alone, such as scaling, are admitted
+	scaled := statefulSet.DeepCopy()
+	scaled.Spec.Replicas = new(int32)
+	if warnings, err := validator.ValidateUpdate(ctx, statefulSet, scaled); err != nil || warnings != nil {
+		t.Errorf("ValidateUpdate() of a scaled StatefulSet = %v, %v", warnings, err)
+	}
+
+	changed := statefulSet.DeepCopy()
+	changed.Spec.Template.Spec.Containers[0].Image = "nginx:1.28"
+	if _, err := validator.ValidateUpdate(ctx, statefulSet, changed); !apierrors.IsInvalid(err) {
+		t.Errorf("ValidateUpdate() of a changed template error = %v, want Invalid", err)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// WorkloadWebhookNames name the webhooks validating the pod templates of workloads. The API
+// server cannot select workloads by the labels of their templates, so only the namespace
+// selector of watched pods applies to them, and they ignore failures: every workload reaches
+// them, and the pod webhooks still reject the pods that do not convert.
+var WorkloadWebhookNames = []string{
+	"vcronjob-v1.kb.io",
+	"vdeployment-v1.kb.io",
+	"vjob-v1.kb.io",
+	"vstatefulset-v1.kb.io",
+}
+
+// SetupWorkloadWebhooksWithManager registers the webhooks validating the pod templates of
+// Deployments, StatefulSets, Jobs and CronJobs in the manager
+func SetupWorkloadWebhooksWithManager(mgr ctrl.Manager, options PodWebhookOptions) error {
+	if err := options.complete(); err != nil {
+		return err
+	}
+	validator := &WorkloadCustomValidator{Pods: options.validator(mgr), Scheme: mgr.GetScheme()}
+	for _, workload := range []client.Object{
+		&appsv1.Deployment{}, &appsv1.StatefulSet{}, &batchv1.Job{}, &batchv1.CronJob{},
+	} {
+		if err := ctrl.NewWebhookManagedBy(mgr).For(workload).WithValidator(validator).Complete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-apps-v1-deployment,mutating=false,failurePolicy=ignore,sideEffects=None,groups=apps,resources=deployments,verbs=create;update,versions=v1,name=vdeployment-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-apps-v1-statefulset,mutating=false,failurePolicy=ignore,sideEffects=None,groups=apps,resources=statefulsets,verbs=create;update,versions=v1,name=vstatefulset-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-batch-v1-job,mutating=false,failurePolicy=ignore,sideEffects=None,groups=batch,resources=jobs,verbs=create;update,versions=v1,name=vjob-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-batch-v1-cronjob,mutating=false,failurePolicy=ignore,sideEffects=None,groups=batch,resources=cronjobs,verbs=create;update,versions=v1,name=vcronjob-v1.kb.io,admissionReviewVersions=v1
+
+// WorkloadCustomValidator checks the pod templates of workloads like the pods they create, so
+// that a template that does not convert is reported on the workload that was applied rather
+// than on its pods, which are rejected later without the user seeing it. Templates are checked
+// on creation and when an update changes them.
+type WorkloadCustomValidator struct {
+	// Pods checks the pods of the templates and decides on the admission of the workloads
+	Pods *PodCustomValidator
+	// Scheme resolves the kinds of the workloads
+	Scheme *runtime.Scheme
+}
+
+var _ webhook.CustomValidator = &WorkloadCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the workload types.
+func (v *WorkloadCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj, nil)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the workload types.
+func (v *WorkloadCustomValidator) ValidateUpdate(
+	ctx context.Context,
+	oldObj, newObj runtime.Object,
+) (admission.Warnings, error) {
+	return v.validate(ctx, newObj, oldObj)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the workload types.
+func (v *WorkloadCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the pod template of the workload, unless it is the same as in the old object
+func (v *WorkloadCustomValidator) validate(
+	ctx context.Context,
+	obj, oldObj runtime.Object,
+) (admission.Warnings, error) {
+	workload, ok := obj.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("expected a workload object but got %T", obj)
+	}
+	template, path, err := podTemplate(workload)
+	if err != nil {
+		return nil, err
+	}
+	if oldObj != nil {
+		if oldWorkload, ok := oldObj.(client.Object); ok {
+			if oldTemplate, _, err := podTemplate(oldWorkload); err == nil &&
+				equality.Semantic.DeepEqual(template, oldTemplate) {
+				return nil, nil
+			}
+		}
+	}
+	gvk, err := apiutil.GVKForObject(workload, v.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	return v.Pods.admit(ctx, gvk.GroupKind(), objectName(workload), requestNamespace(ctx, workload),
+		templatePod(workload, gvk, template), path)
+}
+
+// podTemplate returns the pod template of the workload and its path
+func podTemplate(workload client.Object) (*corev1.PodTemplateSpec, string, error) {
+	switch workload := workload.(type) {
+	case *appsv1.Deployment:
+		return &workload.Spec.Template, "spec.template", nil
+	case *appsv1.StatefulSet:
+		return &workload.Spec.Template, "spec.template", nil
+	case *batchv1.Job:
+		return &workload.Spec.Template, "spec.template", nil
+	case *batchv1.CronJob:
+		return &workload.Spec.JobTemplate.Spec.Template, "spec.jobTemplate.spec.template", nil
+	default:
+		return nil, "", fmt.Errorf("expected a workload object but got %T", workload)
+	}
+}
+
+// templatePod returns a pod of the template as the workload creates it. The pod is owned by the
+// workload so that its family is the one of the workload's pods; the pods of a Deployment are
+// owned by a ReplicaSet, which the family collapses to the Deployment.
+func templatePod(
+	workload client.Object,
+	gvk schema.GroupVersionKind,
+	template *corev1.PodTemplateSpec,
+) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: *template.ObjectMeta.DeepCopy(), Spec: *template.Spec.DeepCopy()}
+	pod.Name = ""
+	pod.GenerateName = workload.GetName() + "-"
+	pod.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Name:       workload.GetName(),
+		UID:        workload.GetUID(),
+		Controller: ptr.To(true),
+	}}
+	return pod
+}
@@ -0,0 +1,375 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// AnnotationPrefix is the annotation namespace read by the converter
+	AnnotationPrefix = "ecs.takutakahashi.dev/"
+
+	// Task-level annotations. Each one sets the ECSConfig field of the same name.
+	AnnotationConfig                  = AnnotationPrefix + "config"
+	AnnotationFamily                  = AnnotationPrefix + "family"
+	AnnotationTaskRoleArn             = AnnotationPrefix + "task-role-arn"
+	AnnotationExecutionRoleArn        = AnnotationPrefix + "execution-role-arn"
+	AnnotationNetworkMode             = AnnotationPrefix + "network-mode"
+	AnnotationRequiresCompatibilities = AnnotationPrefix + "requires-compatibilities"
+	AnnotationCPU                     = AnnotationPrefix + "cpu"
+	AnnotationMemory                  = AnnotationPrefix + "memory"
+	AnnotationTags                    = AnnotationPrefix + "tags"
+
+	// AnnotationContainerPrefix starts per-container annotations of the form
+	// ecs.takutakahashi.dev/container.<container-name>.<setting>
+	AnnotationContainerPrefix = AnnotationPrefix + "container."
+
+	// Annotations in which the controller records the ECS resources of an offloaded pod
+	AnnotationTaskDefinitionArn = AnnotationPrefix + "task-definition-arn"
+	AnnotationTaskArn           = AnnotationPrefix + "task-arn"
+	AnnotationCluster           = AnnotationPrefix + "cluster"
+
+	// AnnotationTaskDefinitionHash records the content hash of the task definition a pod
+	// converted to on admission
+	AnnotationTaskDefinitionHash = AnnotationPrefix + "task-definition-hash"
+)
+
+var iamRoleArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+
+// ECSContainerOverride overrides settings of a converted container
+type ECSContainerOverride struct {
+	CPU                    *int    `json:"cpu,omitempty"`
+	Memory                 *int    `json:"memory,omitempty"`
+	MemoryReservation      *int    `json:"memoryReservation,omitempty"`
+	Essential              *bool   `json:"essential,omitempty"`
+	StartTimeout           *int    `json:"startTimeout,omitempty"`
+	StopTimeout            *int    `json:"stopTimeout,omitempty"`
+	ReadonlyRootFilesystem *bool   `json:"readonlyRootFilesystem,omitempty"`
+	User                   *string `json:"user,omitempty"`
+	WorkingDirectory       *string `json:"workingDirectory,omitempty"`
+}
+
+// containerAnnotationSettings maps the <setting> part of per-container annotations to a
+// setter on ECSContainerOverride
+var containerAnnotationSettings = map[string]func(o *ECSContainerOverride, value string) error{
+	"cpu":                      intSetting(func(o *ECSContainerOverride) **int { return &o.CPU }),
+	"memory":                   intSetting(func(o *ECSContainerOverride) **int { return &o.Memory }),
+	"memory-reservation":       intSetting(func(o *ECSContainerOverride) **int { return &o.MemoryReservation }),
+	"essential":                boolSetting(func(o *ECSContainerOverride) **bool { return &o.Essential }),
+	"start-timeout":            intSetting(func(o *ECSContainerOverride) **int { return &o.StartTimeout }),
+	"stop-timeout":             intSetting(func(o *ECSContainerOverride) **int { return &o.StopTimeout }),
+	"readonly-root-filesystem": boolSetting(func(o *ECSContainerOverride) **bool { return &o.ReadonlyRootFilesystem }),
+	"user":                     stringSetting(func(o *ECSContainerOverride) **string { return &o.User }),
+	"working-directory":        stringSetting(func(o *ECSContainerOverride) **string { return &o.WorkingDirectory }),
+}
+
+func intSetting(field func(o *ECSContainerOverride) **int) func(o *ECSContainerOverride, value string) error {
+	return func(o *ECSContainerOverride, value string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return fmt.Errorf("%q is not a non-negative integer", value)
+		}
+		*field(o) = &n
+		return nil
+	}
+}
+
+func boolSetting(field func(o *ECSContainerOverride) **bool) func(o *ECSContainerOverride, value string) error {
+	return func(o *ECSContainerOverride, value string) error {
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		*field(o) = &b
+		return nil
+	}
+}
+
+func stringSetting(field func(o *ECSContainerOverride) **string) func(o *ECSContainerOverride, value string) error {
+	return func(o *ECSContainerOverride, value string) error {
+		*field(o) = &value
+		return nil
+	}
+}
+
+// ParseAnnotationConfig reads the ECS configuration from pod annotations. The JSON object in
+// ecs.takutakahashi.dev/config is applied first and individual annotations override it.
+// Invalid values are returned as ValidationErrors; unknown annotations in the
+// ecs.takutakahashi.dev namespace are reported as diagnostics.
+func ParseAnnotationConfig(annotations map[string]string) (*ECSConfig, []Diagnostic, error) {
+	config := &ECSConfig{}
+	var diagnostics []Diagnostic
+	var errs ValidationErrors
+
+	if blob, ok := annotations[AnnotationConfig]; ok {
+		decoder := json.NewDecoder(bytes.NewReader([]byte(blob)))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(config); err != nil {
+			errs.add(annotationField(AnnotationConfig), ValidationInvalidFormat, "invalid JSON: %v", err)
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(annotations)) {
+		value := annotations[key]
+		field := annotationField(key)
+
+		switch key {
+		case AnnotationConfig:
+			// Already applied above
+		case AnnotationFamily:
+			config.Family = strings.TrimSpace(value)
+		case AnnotationTaskRoleArn:
+			config.TaskRoleArn = strings.TrimSpace(value)
+		case AnnotationExecutionRoleArn:
+			config.ExecutionRoleArn = strings.TrimSpace(value)
+		case AnnotationNetworkMode:
+			config.NetworkMode = strings.TrimSpace(value)
+		case AnnotationRequiresCompatibilities:
+			config.RequiresCompatibilities = splitList(value)
+		case AnnotationCPU:
+			config.CPU = strings.TrimSpace(value)
+		case AnnotationMemory:
+			config.Memory = strings.TrimSpace(value)
+		case AnnotationTags:
+			tags, err := parseKeyValueList(value)
+			if err != nil {
+				errs.add(field, ValidationInvalidFormat, "%v", err)
+				continue
+			}
+			if config.Tags == nil {
+				config.Tags = map[string]string{}
+			}
+			maps.Copy(config.Tags, tags)
+		default:
+			if rest, ok := strings.CutPrefix(key, AnnotationContainerPrefix); ok {
+				parseContainerAnnotation(config, rest, value, field, &errs)
+				continue
+			}
+			if strings.HasPrefix(key, AnnotationPrefix) && !ignoredAnnotations[key] {
+				diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, DiagUnknownAnnotation, field,
+					"unknown annotation %s is ignored", key))
+			}
+		}
+	}
+
+	config.validate("", &errs)
+	if len(errs) > 0 {
+		return nil, diagnostics, errs
+	}
+	return config, diagnostics, nil
+}
+
+// ignoredAnnotations lists annotations in the ecs.takutakahashi.dev namespace that are not
+// part of the ECS configuration
+var ignoredAnnotations = map[string]bool{
+	AnnotationTaskDefinitionArn:  true,
+	AnnotationTaskArn:            true,
+	AnnotationCluster:            true,
+	AnnotationTaskDefinitionHash: true,
+}
+
+func annotationField(key string) string {
+	return fmt.Sprintf("metadata.annotations[%s]", key)
+}
+
+func parseContainerAnnotation(config *ECSConfig, rest, value, field string, errs *ValidationErrors) {
+	separator := strings.LastIndex(rest, ".")
+	if separator <= 0 {
+		errs.add(field, ValidationInvalidFormat, "expected %s<container>.<setting>", AnnotationContainerPrefix)
+		return
+	}
+	name, setting := rest[:separator], rest[separator+1:]
+
+	set, ok := containerAnnotationSettings[setting]
+	if !ok {
+		errs.add(field, ValidationInvalidValue, "unknown container setting %q (one of %s)",
+			setting, strings.Join(slices.Sorted(maps.Keys(containerAnnotationSettings)), ", "))
+		return
+	}
+
+	if config.Containers == nil {
+		config.Containers = map[string]ECSContainerOverride{}
+	}
+	override := config.Containers[name]
+	if err := set(&override, value); err != nil {
+		errs.add(field, ValidationInvalidFormat, "%v", err)
+		return
+	}
+	config.Containers[name] = override
+}
+
+// validate checks the values of the configuration. Field names are prefixed with prefix.
+func (c *ECSConfig) validate(prefix string, errs *ValidationErrors) {
+	if c.Family != "" && (len(c.Family) > MaxNameLength || !ecsNamePattern.MatchString(c.Family)) {
+		errs.add(prefix+"family", ValidationInvalidFormat,
+			"%q must be at most %d letters, numbers, hyphens and underscores", c.Family, MaxNameLength)
+	}
+	if c.TaskRoleArn != "" && !iamRoleArnPattern.MatchString(c.TaskRoleArn) {
+		errs.add(prefix+"taskRoleArn", ValidationInvalidFormat, "%q is not an IAM role ARN", c.TaskRoleArn)
+	}
+	if c.ExecutionRoleArn != "" && !iamRoleArnPattern.MatchString(c.ExecutionRoleArn) {
+		errs.add(prefix+"executionRoleArn", ValidationInvalidFormat, "%q is not an IAM role ARN", c.ExecutionRoleArn)
+	}
+	errs.checkEnum(prefix+"networkMode", c.NetworkMode, NetworkModes)
+	for i, compat := range c.RequiresCompatibilities {
+		errs.checkEnum(fmt.Sprintf("%srequiresCompatibilities[%d]", prefix, i), compat, LaunchTypes)
+	}
+	if _, ok := parseTaskCPU(c.CPU); c.CPU != "" && !ok {
+		errs.add(prefix+"cpu", ValidationInvalidFormat, "%q is not a valid CPU value", c.CPU)
+	}
+	if _, ok := parseTaskMemory(c.Memory); c.Memory != "" && !ok {
+		errs.add(prefix+"memory", ValidationInvalidFormat, "%q is not a valid memory value", c.Memory)
+	}
+}
+
+// MergeECSConfig combines two configurations, such as the explicit configuration and the one
+// read from annotations. Fields set in explicit take precedence over those in annotated.
+func MergeECSConfig(explicit, annotated *ECSConfig) *ECSConfig {
+	merged := *annotated
+
+	if explicit.Family != "" {
+		merged.Family = explicit.Family
+	}
+	if explicit.TaskRoleArn != "" {
+		merged.TaskRoleArn = explicit.TaskRoleArn
+	}
+	if explicit.ExecutionRoleArn != "" {
+		merged.ExecutionRoleArn = explicit.ExecutionRoleArn
+	}
+	if explicit.NetworkMode != "" {
+		merged.NetworkMode = explicit.NetworkMode
+	}
+	if len(explicit.RequiresCompatibilities) > 0 {
+		merged.RequiresCompatibilities = explicit.RequiresCompatibilities
+	}
+	if explicit.CPU != "" {
+		merged.CPU = explicit.CPU
+	}
+	if explicit.Memory != "" {
+		merged.Memory = explicit.Memory
+	}
+
+	if len(explicit.Tags) > 0 {
+		merged.Tags = maps.Clone(annotated.Tags)
+		if merged.Tags == nil {
+			merged.Tags = map[string]string{}
+		}
+		maps.Copy(merged.Tags, explicit.Tags)
+	}
+
+	if len(explicit.Containers) > 0 {
+		merged.Containers = maps.Clone(annotated.Containers)
+		if merged.Containers == nil {
+			merged.Containers = map[string]ECSContainerOverride{}
+		}
+		for name, override := range explicit.Containers {
+			merged.Containers[name] = merged.Containers[name].merge(override)
+		}
+	}
+
+	return &merged
+}
+
+// merge returns o with the fields set in over replaced
+func (o ECSContainerOverride) merge(over ECSContainerOverride) ECSContainerOverride {
+	if over.CPU != nil {
+		o.CPU = over.CPU
+	}
+	if over.Memory != nil {
+		o.Memory = over.Memory
+	}
+	if over.MemoryReservation != nil {
+		o.MemoryReservation = over.MemoryReservation
+	}
+	if over.Essential != nil {
+		o.Essential = over.Essential
+	}
+	if over.StartTimeout != nil {
+		o.StartTimeout = over.StartTimeout
+	}
+	if over.StopTimeout != nil {
+		o.StopTimeout = over.StopTimeout
+	}
+	if over.ReadonlyRootFilesystem != nil {
+		o.ReadonlyRootFilesystem = over.ReadonlyRootFilesystem
+	}
+	if over.User != nil {
+		o.User = over.User
+	}
+	if over.WorkingDirectory != nil {
+		o.WorkingDirectory = over.WorkingDirectory
+	}
+	return o
+}
+
+// apply sets the overridden fields on the container definition
+func (o ECSContainerOverride) apply(container *ECSContainerDefinition) {
+	if o.CPU != nil {
+		container.CPU = *o.CPU
+	}
+	if o.Memory != nil {
+		container.Memory = *o.Memory
+	}
+	if o.MemoryReservation != nil {
+		container.MemoryReservation = *o.MemoryReservation
+	}
+	if o.Essential != nil {
+		container.Essential = *o.Essential
+	}
+	if o.StartTimeout != nil {
+		container.StartTimeout = *o.StartTimeout
+	}
+	if o.StopTimeout != nil {
+		container.StopTimeout = *o.StopTimeout
+	}
+	if o.ReadonlyRootFilesystem != nil {
+		container.ReadonlyRootFilesystem = o.ReadonlyRootFilesystem
+	}
+	if o.User != nil {
+		container.User = *o.User
+	}
+	if o.WorkingDirectory != nil {
+		container.WorkingDirectory = *o.WorkingDirectory
+	}
+}
+
+// applyContainerOverrides applies the per-container overrides of the configuration
+func applyContainerOverrides(ecsConfig *ECSConfig, containers []ECSContainerDefinition) error {
+	for _, name := range slices.Sorted(maps.Keys(ecsConfig.Containers)) {
+		index := slices.IndexFunc(containers, func(c ECSContainerDefinition) bool { return c.Name == name })
+		if index < 0 {
+			return fmt.Errorf("container override refers to unknown container %q", name)
+		}
+		ecsConfig.Containers[name].apply(&containers[index])
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseKeyValueList parses "key=value,key2=value2"
+func parseKeyValueList(value string) (map[string]string, error) {
+	pairs := map[string]string{}
+	for _, item := range splitList(value) {
+		key, val, found := strings.Cut(item, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("expected key=value pairs separated by commas, got %q", item)
+		}
+		pairs[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return pairs, nil
+}
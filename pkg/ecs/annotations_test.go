@@ -0,0 +1,192 @@
+package ecs
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newAnnotatedPod(annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "shop",
+			Annotations: annotations,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", Image: "app:1.0"},
+				{Name: "sidecar", Image: "proxy:1.0"},
+			},
+		},
+	}
+}
+
+func TestParseAnnotationConfig(t *testing.T) {
+	config, diagnostics, err := ParseAnnotationConfig(map[string]string{
+		AnnotationConfig: `{"family": "from-blob", "cpu": "1024",
+			"containers": {"sidecar": {"essential": false, "memory": 128}}}`,
+		AnnotationFamily:                               "from-annotation",
+		AnnotationTaskRoleArn:                          "arn:aws:iam::123456789012:role/web",
+		AnnotationExecutionRoleArn:                     "arn:aws:iam::123456789012:role/exec",
+		AnnotationNetworkMode:                          "bridge",
+		AnnotationRequiresCompatibilities:              "EC2, EXTERNAL",
+		AnnotationMemory:                               "2 GB",
+		AnnotationTags:                                 "team=payments, env=prod",
+		AnnotationContainerPrefix + "sidecar.cpu":      "64",
+		AnnotationContainerPrefix + "app.stop-timeout": "30",
+		AnnotationPrefix + "unknown":                   "x",
+		"example.com/other":                            "ignored",
+	})
+	if err != nil {
+		t.Fatalf("ParseAnnotationConfig() error = %v", err)
+	}
+
+	if config.Family != "from-annotation" {
+		t.Errorf("Family = %q, individual annotations should override the JSON blob", config.Family)
+	}
+	if config.CPU != "1024" || config.Memory != "2 GB" || config.NetworkMode != "bridge" {
+		t.Errorf("CPU/Memory/NetworkMode = %q/%q/%q", config.CPU, config.Memory, config.NetworkMode)
+	}
+	if !reflect.DeepEqual(config.RequiresCompatibilities, []string{"EC2", "EXTERNAL"}) {
+		t.Errorf("RequiresCompatibilities = %v", config.RequiresCompatibilities)
+	}
+	if !reflect.DeepEqual(config.Tags, map[string]string{"team": "payments", "env": "prod"}) {
+		t.Errorf("Tags = %v", config.Tags)
+	}
+
+	sidecar := config.Containers["sidecar"]
+	if sidecar.Essential == nil || *sidecar.Essential || *sidecar.Memory != 128 || *sidecar.CPU != 64 {
+		t.Errorf("sidecar override = %+v", sidecar)
+	}
+	if app := config.Containers["app"]; app.StopTimeout == nil || *app.StopTimeout != 30 {
+		t.Errorf("app override = %+v", app)
+	}
+
+	if len(diagnostics) != 1 || diagnostics[0].Code != DiagUnknownAnnotation {
+		t.Errorf("diagnostics = %v, want one UnknownAnnotation", diagnostics)
+	}
+}
+
+func TestParseAnnotationConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		want  string
+	}{
+		{"malformed blob", AnnotationConfig, `{"family": `,
+			"metadata.annotations[" + AnnotationConfig + "]:InvalidFormat"},
+		{"unknown blob field", AnnotationConfig, `{"cpus": "256"}`,
+			"metadata.annotations[" + AnnotationConfig + "]:InvalidFormat"},
+		{"family", AnnotationFamily, "web/app", "family:InvalidFormat"},
+		{"task role", AnnotationTaskRoleArn, "web-role", "taskRoleArn:InvalidFormat"},
+		{"network mode", AnnotationNetworkMode, "overlay", "networkMode:InvalidValue"},
+		{"compatibility", AnnotationRequiresCompatibilities, "FARGATE,LAMBDA",
+			"requiresCompatibilities[1]:InvalidValue"},
+		{"cpu", AnnotationCPU, "a lot", "cpu:InvalidFormat"},
+		{"memory", AnnotationMemory, "-1", "memory:InvalidFormat"},
+		{"tags", AnnotationTags, "team", "metadata.annotations[" + AnnotationTags + "]:InvalidFormat"},
+		{"container setting", AnnotationContainerPrefix + "app.gpu", "1",
+			"metadata.annotations[" + AnnotationContainerPrefix + "app.gpu]:InvalidValue"},
+		{"container value", AnnotationContainerPrefix + "app.essential", "maybe",
+			"metadata.annotations[" + AnnotationContainerPrefix + "app.essential]:InvalidFormat"},
+		{"container name", AnnotationContainerPrefix + "cpu", "1",
+			"metadata.annotations[" + AnnotationContainerPrefix + "cpu]:InvalidFormat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseAnnotationConfig(map[string]string{tt.key: tt.value})
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("ParseAnnotationConfig() error = %v, want ValidationErrors", err)
+			}
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Field+":"+string(e.Code))
+			}
+			if !slices.Contains(got, tt.want) {
+				t.Errorf("errors = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertPod_AnnotationPrecedence(t *testing.T) {
+	pod := newAnnotatedPod(map[string]string{
+		AnnotationFamily:                  "annotated",
+		AnnotationCPU:                     "512",
+		AnnotationMemory:                  "1024",
+		AnnotationNetworkMode:             "bridge",
+		AnnotationRequiresCompatibilities: "EC2",
+		AnnotationTags:                    "team=payments,env=dev",
+		AnnotationContainerPrefix + "sidecar.essential": "false",
+		AnnotationContainerPrefix + "sidecar.memory":    "128",
+	})
+
+	converter := NewConverter(ConversionOptions{DefaultCPU: "256", DefaultMemory: "512"})
+	sidecarMemory := 256
+	explicit := &ECSConfig{
+		Memory: "2048",
+		Tags:   map[string]string{"env": "prod"},
+		Containers: map[string]ECSContainerOverride{
+			"sidecar": {Memory: &sidecarMemory},
+		},
+	}
+	taskDef, err := converter.ConvertPod(pod, &pod.Spec, explicit, pod.Namespace)
+	if err != nil {
+		t.Fatalf("ConvertPod() error = %v", err)
+	}
+
+	if taskDef.Family != "annotated" || taskDef.CPU != "512" || taskDef.NetworkMode != "bridge" {
+		t.Errorf("Family/CPU/NetworkMode = %q/%q/%q, want values from annotations",
+			taskDef.Family, taskDef.CPU, taskDef.NetworkMode)
+	}
+	if taskDef.Memory != "2048" {
+		t.Errorf("Memory = %q, explicit config should win over annotations", taskDef.Memory)
+	}
+	if !reflect.DeepEqual(taskDef.RequiresCompatibilities, []string{"EC2"}) {
+		t.Errorf("RequiresCompatibilities = %v", taskDef.RequiresCompatibilities)
+	}
+	wantTags := []ECSTag{{Key: "env", Value: "prod"}, {Key: "team", Value: "payments"}}
+	if !reflect.DeepEqual(taskDef.Tags, wantTags) {
+		t.Errorf("Tags = %v, want %v", taskDef.Tags, wantTags)
+	}
+
+	sidecar := taskDef.ContainerDefinitions[1]
+	if sidecar.Essential || sidecar.Memory != 256 {
+		t.Errorf("sidecar essential/memory = %v/%d, want false/256", sidecar.Essential, sidecar.Memory)
+	}
+	if !taskDef.ContainerDefinitions[0].Essential {
+		t.Error("app container should stay essential")
+	}
+
+	// Without annotations the converter defaults apply
+	plain := newAnnotatedPod(nil)
+	taskDef, err = converter.ConvertPod(plain, &plain.Spec, &ECSConfig{}, plain.Namespace)
+	if err != nil {
+		t.Fatalf("ConvertPod() error = %v", err)
+	}
+	if taskDef.CPU != "256" || taskDef.Memory != "512" {
+		t.Errorf("CPU/Memory = %q/%q, want the ConversionOptions defaults", taskDef.CPU, taskDef.Memory)
+	}
+}
+
+func TestConvertPod_AnnotationErrors(t *testing.T) {
+	converter := NewConverter(ConversionOptions{})
+
+	pod := newAnnotatedPod(map[string]string{AnnotationNetworkMode: "overlay"})
+	if _, err := converter.ConvertPod(pod, &pod.Spec, &ECSConfig{}, pod.Namespace); err == nil {
+		t.Error("ConvertPod() should reject an invalid network mode annotation")
+	}
+
+	pod = newAnnotatedPod(map[string]string{AnnotationContainerPrefix + "missing.cpu": "128"})
+	if _, err := converter.ConvertPod(pod, &pod.Spec, &ECSConfig{}, pod.Namespace); err == nil {
+		t.Error("ConvertPod() should reject overrides for unknown containers")
+	}
+}
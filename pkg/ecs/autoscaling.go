@@ -0,0 +1,274 @@
+package ecs
+
+import (
+	"fmt"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ECSServiceNamespace is the Application Auto Scaling namespace for ECS services
+	ECSServiceNamespace = "ecs"
+	// ECSServiceDesiredCountDimension is the scalable dimension for ECS service task counts
+	ECSServiceDesiredCountDimension = "ecs:service:DesiredCount"
+
+	// PredefinedMetricCPUUtilization tracks the average CPU utilization of an ECS service
+	PredefinedMetricCPUUtilization = "ECSServiceAverageCPUUtilization"
+	// PredefinedMetricMemoryUtilization tracks the average memory utilization of an ECS service
+	PredefinedMetricMemoryUtilization = "ECSServiceAverageMemoryUtilization"
+
+	// hpaDefaultCPUUtilization is the target used by the HPA controller when no metrics are given
+	hpaDefaultCPUUtilization = 80
+)
+
+// AutoScalingTarget identifies the ECS service that an HPA is converted for
+type AutoScalingTarget struct {
+	// ClusterName is the ECS cluster running the service (default: "default")
+	ClusterName string
+
+	// ServiceName is the ECS service name (default: the HPA scaleTargetRef name)
+	ServiceName string
+}
+
+// AutoScalingConfiguration holds the Application Auto Scaling artifacts for an ECS service
+type AutoScalingConfiguration struct {
+	ScalableTarget  ScalableTarget  `json:"ScalableTarget"`
+	ScalingPolicies []ScalingPolicy `json:"ScalingPolicies,omitempty"`
+}
+
+// ScalableTarget represents an Application Auto Scaling scalable target
+type ScalableTarget struct {
+	ServiceNamespace  string `json:"ServiceNamespace"`
+	ResourceID        string `json:"ResourceId"`
+	ScalableDimension string `json:"ScalableDimension"`
+	MinCapacity       int32  `json:"MinCapacity"`
+	MaxCapacity       int32  `json:"MaxCapacity"`
+}
+
+// ScalingPolicy represents an Application Auto Scaling scaling policy
+type ScalingPolicy struct {
+	PolicyName        string `json:"PolicyName"`
+	ServiceNamespace  string `json:"ServiceNamespace"`
+	ResourceID        string `json:"ResourceId"`
+	ScalableDimension string `json:"ScalableDimension"`
+	PolicyType        string `json:"PolicyType"`
+	// TargetTracking is the TargetTrackingScalingPolicyConfiguration of the policy
+	TargetTracking *TargetTrackingScalingPolicyConfiguration `json:"TargetTrackingScalingPolicyConfiguration,omitempty"`
+}
+
+// TargetTrackingScalingPolicyConfiguration represents a target tracking policy configuration
+type TargetTrackingScalingPolicyConfiguration struct {
+	TargetValue                   float64                        `json:"TargetValue"`
+	PredefinedMetricSpecification *PredefinedMetricSpecification `json:"PredefinedMetricSpecification,omitempty"`
+	ScaleInCooldown               *int32                         `json:"ScaleInCooldown,omitempty"`
+	ScaleOutCooldown              *int32                         `json:"ScaleOutCooldown,omitempty"`
+	DisableScaleIn                bool                           `json:"DisableScaleIn,omitempty"`
+}
+
+// PredefinedMetricSpecification represents a predefined metric for target tracking
+type PredefinedMetricSpecification struct {
+	PredefinedMetricType string `json:"PredefinedMetricType"`
+}
+
+// ConvertHPA converts an autoscaling/v2 HorizontalPodAutoscaler targeting a Deployment into
+// an Application Auto Scaling scalable target and target tracking policies. Metrics and
+// behaviors that have no ECS equivalent are reported as diagnostics.
+func (c *Converter) ConvertHPA(
+	hpa *autoscalingv2.HorizontalPodAutoscaler,
+	target AutoScalingTarget,
+) (*AutoScalingConfiguration, []Diagnostic, error) {
+	if hpa == nil {
+		return nil, nil, fmt.Errorf("horizontal pod autoscaler is nil")
+	}
+
+	ref := hpa.Spec.ScaleTargetRef
+	if ref.Kind != "Deployment" {
+		return nil, nil, fmt.Errorf("horizontal pod autoscaler %s targets %s %q, only Deployments can be converted",
+			hpa.Name, ref.Kind, ref.Name)
+	}
+
+	if target.ClusterName == "" {
+		target.ClusterName = "default"
+	}
+	if target.ServiceName == "" {
+		target.ServiceName = ref.Name
+	}
+
+	var diagnostics []Diagnostic
+
+	minReplicas := int32(1)
+	if hpa.Spec.MinReplicas != nil {
+		minReplicas = *hpa.Spec.MinReplicas
+	}
+
+	resourceID := fmt.Sprintf("service/%s/%s", target.ClusterName, target.ServiceName)
+	config := &AutoScalingConfiguration{
+		ScalableTarget: ScalableTarget{
+			ServiceNamespace:  ECSServiceNamespace,
+			ResourceID:        resourceID,
+			ScalableDimension: ECSServiceDesiredCountDimension,
+			MinCapacity:       minReplicas,
+			MaxCapacity:       hpa.Spec.MaxReplicas,
+		},
+	}
+
+	metrics := hpa.Spec.Metrics
+	if len(metrics) == 0 {
+		diagnostics = append(diagnostics, newDiagnostic(SeverityInfo, DiagDefaultedValue, "spec.metrics",
+			"no metrics specified, using the HPA default of %d%% CPU utilization", hpaDefaultCPUUtilization))
+		metrics = []autoscalingv2.MetricSpec{
+			{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{
+					Name: corev1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{
+						Type:               autoscalingv2.UtilizationMetricType,
+						AverageUtilization: ptrInt32(hpaDefaultCPUUtilization),
+					},
+				},
+			},
+		}
+	}
+
+	trackingTemplate, behaviorDiagnostics := convertScalingBehavior(hpa.Spec.Behavior)
+	diagnostics = append(diagnostics, behaviorDiagnostics...)
+
+	// HPA metrics mapping to the same predefined metric would produce policies of the same name,
+	// so they are merged into one policy tracking the lowest target, as the HPA scales to the
+	// highest replica count its metrics propose
+	policies := map[string]int{}
+	for i, metric := range metrics {
+		field := fmt.Sprintf("spec.metrics[%d]", i)
+		metricType, targetValue, diagnostic := convertMetric(metric, field)
+		if diagnostic != nil {
+			diagnostics = append(diagnostics, *diagnostic)
+		}
+		if metricType == "" {
+			continue
+		}
+		if index, ok := policies[metricType]; ok {
+			tracking := config.ScalingPolicies[index].TargetTracking
+			tracking.TargetValue = min(tracking.TargetValue, targetValue)
+			diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, DiagDuplicateMetric, field,
+				"%s is already tracked by another metric; one policy tracks the lower target %v",
+				metricType, tracking.TargetValue))
+			continue
+		}
+
+		trackingConfig := trackingTemplate
+		trackingConfig.TargetValue = targetValue
+		trackingConfig.PredefinedMetricSpecification = &PredefinedMetricSpecification{
+			PredefinedMetricType: metricType,
+		}
+
+		policies[metricType] = len(config.ScalingPolicies)
+		config.ScalingPolicies = append(config.ScalingPolicies, ScalingPolicy{
+			PolicyName:        fmt.Sprintf("%s-%s", target.ServiceName, metricType),
+			ServiceNamespace:  ECSServiceNamespace,
+			ResourceID:        resourceID,
+			ScalableDimension: ECSServiceDesiredCountDimension,
+			PolicyType:        "TargetTrackingScaling",
+			TargetTracking:    &trackingConfig,
+		})
+	}
+
+	return config, diagnostics, nil
+}
+
+// convertMetric maps a single HPA metric to a predefined ECS metric type and target value.
+// An empty metric type means the metric was not converted.
+func convertMetric(metric autoscalingv2.MetricSpec, field string) (string, float64, *Diagnostic) {
+	var (
+		name   corev1.ResourceName
+		target autoscalingv2.MetricTarget
+	)
+
+	switch metric.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if metric.Resource == nil {
+			return "", 0, nil
+		}
+		name, target = metric.Resource.Name, metric.Resource.Target
+	case autoscalingv2.ContainerResourceMetricSourceType:
+		if metric.ContainerResource == nil {
+			return "", 0, nil
+		}
+		name, target = metric.ContainerResource.Name, metric.ContainerResource.Target
+	default:
+		diagnostic := newDiagnostic(SeverityWarning, DiagUnsupportedMetric, field,
+			"%s metrics cannot be mapped to ECS predefined metrics, create a custom target tracking policy instead",
+			metric.Type)
+		return "", 0, &diagnostic
+	}
+
+	if target.Type != autoscalingv2.UtilizationMetricType || target.AverageUtilization == nil {
+		diagnostic := newDiagnostic(SeverityWarning, DiagUnsupportedMetric, field,
+			"%s target type %s is not supported, only Utilization targets can be converted", name, target.Type)
+		return "", 0, &diagnostic
+	}
+
+	var metricType string
+	switch name {
+	case corev1.ResourceCPU:
+		metricType = PredefinedMetricCPUUtilization
+	case corev1.ResourceMemory:
+		metricType = PredefinedMetricMemoryUtilization
+	default:
+		diagnostic := newDiagnostic(SeverityWarning, DiagUnsupportedMetric, field,
+			"resource %s has no ECS predefined metric", name)
+		return "", 0, &diagnostic
+	}
+
+	var diagnostic *Diagnostic
+	if metric.Type == autoscalingv2.ContainerResourceMetricSourceType {
+		d := newDiagnostic(SeverityWarning, DiagUnsupportedMetric, field,
+			"container %s utilization is tracked for the whole task in ECS", metric.ContainerResource.Container)
+		diagnostic = &d
+	}
+
+	return metricType, float64(*target.AverageUtilization), diagnostic
+}
+
+// convertScalingBehavior maps HPA scaling behavior to target tracking cooldowns
+func convertScalingBehavior(
+	behavior *autoscalingv2.HorizontalPodAutoscalerBehavior,
+) (TargetTrackingScalingPolicyConfiguration, []Diagnostic) {
+	var (
+		config      TargetTrackingScalingPolicyConfiguration
+		diagnostics []Diagnostic
+	)
+
+	if behavior == nil {
+		return config, nil
+	}
+
+	if rules := behavior.ScaleDown; rules != nil {
+		config.ScaleInCooldown = rules.StabilizationWindowSeconds
+		if rules.SelectPolicy != nil && *rules.SelectPolicy == autoscalingv2.DisabledPolicySelect {
+			config.DisableScaleIn = true
+		}
+		if len(rules.Policies) > 0 {
+			diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, DiagUnsupportedScalingBehavior,
+				"spec.behavior.scaleDown.policies", "scaling rate policies are not supported by target tracking"))
+		}
+	}
+
+	if rules := behavior.ScaleUp; rules != nil {
+		config.ScaleOutCooldown = rules.StabilizationWindowSeconds
+		if rules.SelectPolicy != nil && *rules.SelectPolicy == autoscalingv2.DisabledPolicySelect {
+			diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, DiagUnsupportedScalingBehavior,
+				"spec.behavior.scaleUp.selectPolicy", "scale-out cannot be disabled for target tracking policies"))
+		}
+		if len(rules.Policies) > 0 {
+			diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, DiagUnsupportedScalingBehavior,
+				"spec.behavior.scaleUp.policies", "scaling rate policies are not supported by target tracking"))
+		}
+	}
+
+	return config, diagnostics
+}
+
+func ptrInt32(v int32) *int32 {
+	return &v
+}
@@ -0,0 +1,236 @@
+package ecs
+
+import (
+	"testing"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConverter_ConvertHPA(t *testing.T) {
+	disabled := autoscalingv2.DisabledPolicySelect
+
+	tests := []struct {
+		name            string
+		hpa             *autoscalingv2.HorizontalPodAutoscaler
+		target          AutoScalingTarget
+		wantResourceID  string
+		wantMin         int32
+		wantMax         int32
+		wantPolicies    map[string]float64
+		wantDiagnostics []DiagnosticCode
+		wantErr         bool
+	}{
+		{
+			name: "cpu and memory utilization",
+			hpa: newTestHPA(func(hpa *autoscalingv2.HorizontalPodAutoscaler) {
+				hpa.Spec.MinReplicas = ptrInt32(2)
+				hpa.Spec.Metrics = []autoscalingv2.MetricSpec{
+					resourceMetric(corev1.ResourceCPU, 60),
+					resourceMetric(corev1.ResourceMemory, 75),
+				}
+			}),
+			target:         AutoScalingTarget{ClusterName: "prod"},
+			wantResourceID: "service/prod/web",
+			wantMin:        2,
+			wantMax:        10,
+			wantPolicies: map[string]float64{
+				PredefinedMetricCPUUtilization:    60,
+				PredefinedMetricMemoryUtilization: 75,
+			},
+		},
+		{
+			name:            "default metrics",
+			hpa:             newTestHPA(nil),
+			target:          AutoScalingTarget{ServiceName: "web-svc"},
+			wantResourceID:  "service/default/web-svc",
+			wantMin:         1,
+			wantMax:         10,
+			wantPolicies:    map[string]float64{PredefinedMetricCPUUtilization: 80},
+			wantDiagnostics: []DiagnosticCode{DiagDefaultedValue},
+		},
+		{
+			name: "unsupported metric types",
+			hpa: newTestHPA(func(hpa *autoscalingv2.HorizontalPodAutoscaler) {
+				hpa.Spec.Metrics = []autoscalingv2.MetricSpec{
+					resourceMetric(corev1.ResourceCPU, 50),
+					{
+						Type: autoscalingv2.PodsMetricSourceType,
+						Pods: &autoscalingv2.PodsMetricSource{
+							Metric: autoscalingv2.MetricIdentifier{Name: "requests_per_second"},
+						},
+					},
+					{
+						Type: autoscalingv2.ExternalMetricSourceType,
+						External: &autoscalingv2.ExternalMetricSource{
+							Metric: autoscalingv2.MetricIdentifier{Name: "queue_depth"},
+						},
+					},
+				}
+			}),
+			wantResourceID:  "service/default/web",
+			wantMin:         1,
+			wantMax:         10,
+			wantPolicies:    map[string]float64{PredefinedMetricCPUUtilization: 50},
+			wantDiagnostics: []DiagnosticCode{DiagUnsupportedMetric, DiagUnsupportedMetric},
+		},
+		{
+			name: "metrics mapping to the same predefined metric",
+			hpa: newTestHPA(func(hpa *autoscalingv2.HorizontalPodAutoscaler) {
+				hpa.Spec.Metrics = []autoscalingv2.MetricSpec{
+					resourceMetric(corev1.ResourceCPU, 70),
+					{
+						Type: autoscalingv2.ContainerResourceMetricSourceType,
+						ContainerResource: &autoscalingv2.ContainerResourceMetricSource{
+							Name:      corev1.ResourceCPU,
+							Container: "app",
+							Target: autoscalingv2.MetricTarget{
+								Type:               autoscalingv2.UtilizationMetricType,
+								AverageUtilization: ptrInt32(55),
+							},
+						},
+					},
+				}
+			}),
+			wantResourceID:  "service/default/web",
+			wantMin:         1,
+			wantMax:         10,
+			wantPolicies:    map[string]float64{PredefinedMetricCPUUtilization: 55},
+			wantDiagnostics: []DiagnosticCode{DiagUnsupportedMetric, DiagDuplicateMetric},
+		},
+		{
+			name: "non-deployment target",
+			hpa: newTestHPA(func(hpa *autoscalingv2.HorizontalPodAutoscaler) {
+				hpa.Spec.ScaleTargetRef.Kind = "StatefulSet"
+			}),
+			wantErr: true,
+		},
+		{
+			name: "behavior with disabled scale down",
+			hpa: newTestHPA(func(hpa *autoscalingv2.HorizontalPodAutoscaler) {
+				hpa.Spec.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{
+					ScaleDown: &autoscalingv2.HPAScalingRules{SelectPolicy: &disabled},
+				}
+			}),
+			wantResourceID:  "service/default/web",
+			wantMin:         1,
+			wantMax:         10,
+			wantPolicies:    map[string]float64{PredefinedMetricCPUUtilization: 80},
+			wantDiagnostics: []DiagnosticCode{DiagDefaultedValue},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConverter(ConversionOptions{})
+			got, diagnostics, err := c.ConvertHPA(tt.hpa, tt.target)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertHPA() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got.ScalableTarget.ResourceID != tt.wantResourceID {
+				t.Errorf("ResourceID = %v, want %v", got.ScalableTarget.ResourceID, tt.wantResourceID)
+			}
+			if got.ScalableTarget.MinCapacity != tt.wantMin || got.ScalableTarget.MaxCapacity != tt.wantMax {
+				t.Errorf("capacity = %d-%d, want %d-%d",
+					got.ScalableTarget.MinCapacity, got.ScalableTarget.MaxCapacity, tt.wantMin, tt.wantMax)
+			}
+
+			if len(got.ScalingPolicies) != len(tt.wantPolicies) {
+				t.Fatalf("ScalingPolicies count = %d, want %d", len(got.ScalingPolicies), len(tt.wantPolicies))
+			}
+			for _, policy := range got.ScalingPolicies {
+				config := policy.TargetTracking
+				want, ok := tt.wantPolicies[config.PredefinedMetricSpecification.PredefinedMetricType]
+				if !ok {
+					t.Errorf("unexpected policy %s", policy.PolicyName)
+					continue
+				}
+				if config.TargetValue != want {
+					t.Errorf("policy %s TargetValue = %v, want %v", policy.PolicyName, config.TargetValue, want)
+				}
+				if policy.ResourceID != tt.wantResourceID {
+					t.Errorf("policy %s ResourceID = %v, want %v", policy.PolicyName, policy.ResourceID, tt.wantResourceID)
+				}
+			}
+
+			if len(diagnostics) != len(tt.wantDiagnostics) {
+				t.Fatalf("diagnostics = %v, want codes %v", diagnostics, tt.wantDiagnostics)
+			}
+			for i, code := range tt.wantDiagnostics {
+				if diagnostics[i].Code != code {
+					t.Errorf("diagnostics[%d].Code = %v, want %v", i, diagnostics[i].Code, code)
+				}
+			}
+		})
+	}
+}
+
+func TestConverter_ConvertHPABehavior(t *testing.T) {
+	hpa := newTestHPA(func(hpa *autoscalingv2.HorizontalPodAutoscaler) {
+		hpa.Spec.Metrics = []autoscalingv2.MetricSpec{resourceMetric(corev1.ResourceCPU, 70)}
+		hpa.Spec.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{
+			ScaleUp: &autoscalingv2.HPAScalingRules{
+				StabilizationWindowSeconds: ptrInt32(30),
+			},
+			ScaleDown: &autoscalingv2.HPAScalingRules{
+				StabilizationWindowSeconds: ptrInt32(600),
+				Policies: []autoscalingv2.HPAScalingPolicy{
+					{Type: autoscalingv2.PodsScalingPolicy, Value: 1, PeriodSeconds: 60},
+				},
+			},
+		}
+	})
+
+	got, diagnostics, err := NewConverter(ConversionOptions{}).ConvertHPA(hpa, AutoScalingTarget{})
+	if err != nil {
+		t.Fatalf("ConvertHPA() error = %v", err)
+	}
+
+	config := got.ScalingPolicies[0].TargetTracking
+	if config.ScaleInCooldown == nil || *config.ScaleInCooldown != 600 {
+		t.Errorf("ScaleInCooldown = %v, want 600", config.ScaleInCooldown)
+	}
+	if config.ScaleOutCooldown == nil || *config.ScaleOutCooldown != 30 {
+		t.Errorf("ScaleOutCooldown = %v, want 30", config.ScaleOutCooldown)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Code != DiagUnsupportedScalingBehavior {
+		t.Errorf("diagnostics = %v, want one %s", diagnostics, DiagUnsupportedScalingBehavior)
+	}
+}
+
+func newTestHPA(mutate func(*autoscalingv2.HorizontalPodAutoscaler)) *autoscalingv2.HorizontalPodAutoscaler {
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       "web",
+			},
+			MaxReplicas: 10,
+		},
+	}
+	if mutate != nil {
+		mutate(hpa)
+	}
+	return hpa
+}
+
+func resourceMetric(name corev1.ResourceName, utilization int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: ptrInt32(utilization),
+			},
+		},
+	}
+}
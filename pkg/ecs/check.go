@@ -0,0 +1,163 @@
+package ecs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodCheck is the result of checking whether a pod converts to a task definition ECS accepts
+type PodCheck struct {
+	// TaskDefinition is the converted task definition; it is nil when Errors stopped the
+	// conversion
+	TaskDefinition *ECSTaskDefinition
+	// Errors name the pod fields preventing the conversion, and the task definition fields ECS
+	// would reject
+	Errors ValidationErrors
+	// Diagnostics report defaulted values and lossy mappings
+	Diagnostics []Diagnostic
+}
+
+// Convertible reports whether the pod converts to a task definition ECS accepts
+func (c *PodCheck) Convertible() bool {
+	return len(c.Errors) == 0
+}
+
+// Warnings returns the diagnostics that need attention, leaving out informational ones
+func (c *PodCheck) Warnings() []Diagnostic {
+	var warnings []Diagnostic
+	for _, diagnostic := range c.Diagnostics {
+		if diagnostic.Severity != SeverityInfo {
+			warnings = append(warnings, diagnostic)
+		}
+	}
+	return warnings
+}
+
+// unsupportedField is a pod field the converter cannot convert
+type unsupportedField struct {
+	field   string
+	message string
+}
+
+// unsupportedFields returns every field of the pod spec the converter rejects unless
+// SkipUnsupportedFeatures is set
+func unsupportedFields(spec *corev1.PodSpec) []unsupportedField {
+	var fields []unsupportedField
+	if len(spec.InitContainers) > 0 {
+		fields = append(fields, unsupportedField{"spec.initContainers", "init containers are not supported in ECS"})
+	}
+	for i, container := range spec.Containers {
+		for j, env := range container.Env {
+			if env.ValueFrom != nil && (env.ValueFrom.FieldRef != nil || env.ValueFrom.ResourceFieldRef != nil) {
+				fields = append(fields, unsupportedField{
+					fmt.Sprintf("spec.containers[%d].env[%d].valueFrom", i, j),
+					"field references are not supported in ECS",
+				})
+			}
+		}
+	}
+	for i, volume := range spec.Volumes {
+		field := fmt.Sprintf("spec.volumes[%d]", i)
+		switch {
+		case volume.HostPath != nil, volume.EmptyDir != nil:
+		case volume.Secret != nil, volume.ConfigMap != nil:
+			fields = append(fields, unsupportedField{field,
+				"secret/configmap volumes are not supported in ECS, use Parameter Store instead"})
+		default:
+			fields = append(fields, unsupportedField{field, fmt.Sprintf("unsupported volume type for volume %s",
+				volume.Name)})
+		}
+	}
+	return fields
+}
+
+// lossyMappings reports the fields of the pod spec that the converter ignores or converts only
+// in part
+func lossyMappings(spec *corev1.PodSpec) []Diagnostic {
+	var diagnostics []Diagnostic
+	lossy := func(field, format string, args ...any) {
+		diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, DiagLossyMapping, field, format, args...))
+	}
+
+	if spec.HostNetwork {
+		lossy("spec.hostNetwork", "host networking is not converted; the network mode comes from the profile")
+	}
+	if spec.Affinity != nil {
+		lossy("spec.affinity", "affinity is not converted; ECS places tasks with placement constraints")
+	}
+	if len(spec.ImagePullSecrets) > 0 {
+		lossy("spec.imagePullSecrets", "image pull secrets are not converted; use repositoryCredentials")
+	}
+	for i, container := range spec.Containers {
+		field := fmt.Sprintf("spec.containers[%d]", i)
+		if container.LivenessProbe != nil || container.ReadinessProbe != nil || container.StartupProbe != nil {
+			lossy(field, "probes of container %s are not converted to ECS health checks", container.Name)
+		}
+		if container.Lifecycle != nil {
+			lossy(field+".lifecycle", "lifecycle hooks of container %s are not supported in ECS", container.Name)
+		}
+		if container.SecurityContext != nil {
+			lossy(field+".securityContext", "the security context of container %s is not converted",
+				container.Name)
+		}
+		_, cpuRequest := container.Resources.Requests[corev1.ResourceCPU]
+		if _, cpuLimit := container.Resources.Limits[corev1.ResourceCPU]; cpuRequest && !cpuLimit {
+			lossy(field+".resources.requests.cpu", "the CPU request of container %s is not converted; only the "+
+				"CPU limit is", container.Name)
+		}
+	}
+	for i, volume := range spec.Volumes {
+		if volume.EmptyDir != nil && (volume.EmptyDir.Medium != "" || volume.EmptyDir.SizeLimit != nil) {
+			lossy(fmt.Sprintf("spec.volumes[%d].emptyDir", i),
+				"emptyDir %s becomes a task volume without its medium and size limit", volume.Name)
+		}
+	}
+	return diagnostics
+}
+
+// CheckPod converts the pod like ConvertPodWithDiagnostics and validates the result, reporting
+// every pod field that prevents the conversion rather than the first one, and the fields whose
+// mapping loses information. ecsConfig may be nil.
+func (c *Converter) CheckPod(ctx context.Context, pod *corev1.Pod, ecsConfig *ECSConfig) *PodCheck {
+	check := &PodCheck{}
+	if ecsConfig == nil {
+		ecsConfig = &ECSConfig{}
+	}
+	namespace := pod.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	for _, unsupported := range unsupportedFields(&pod.Spec) {
+		if c.options.SkipUnsupportedFeatures {
+			check.Diagnostics = append(check.Diagnostics, newDiagnostic(SeverityWarning, DiagUnsupportedField,
+				unsupported.field, "%s; it is dropped", unsupported.message))
+			continue
+		}
+		check.Errors.add(unsupported.field, ValidationUnsupported, "%s", unsupported.message)
+	}
+	check.Diagnostics = append(check.Diagnostics, lossyMappings(&pod.Spec)...)
+	if len(check.Errors) > 0 {
+		return check
+	}
+
+	taskDef, diagnostics, err := c.ConvertPodWithDiagnostics(ctx, pod, &pod.Spec, ecsConfig, namespace)
+	if err != nil {
+		var errs ValidationErrors
+		if errors.As(err, &errs) {
+			check.Errors = append(check.Errors, errs...)
+		} else {
+			check.Errors.add("spec", ValidationInvalidValue, "%v", err)
+		}
+		return check
+	}
+	check.TaskDefinition = taskDef
+	check.Diagnostics = append(check.Diagnostics, diagnostics...)
+	for _, validationErr := range taskDef.Validate() {
+		check.Errors.add("taskDefinition."+validationErr.Field, validationErr.Code, "%s", validationErr.Message)
+	}
+	return check
+}
@@ -0,0 +1,81 @@
+package ecs
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConverter_CheckPod(t *testing.T) {
+	converter := NewConverter(ConversionOptions{DefaultCPU: "256", DefaultMemory: "512"})
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name:  "web",
+				Image: "nginx:1.27",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+				},
+				ReadinessProbe: &corev1.Probe{},
+			}}},
+		}
+	}
+
+	check := converter.CheckPod(context.Background(), newPod(), nil)
+	if !check.Convertible() || check.TaskDefinition == nil {
+		t.Fatalf("CheckPod() errors = %v", check.Errors)
+	}
+	fields := map[string]bool{}
+	for _, warning := range check.Warnings() {
+		fields[warning.Field] = true
+	}
+	if !fields["spec.containers[0]"] || !fields["spec.containers[0].resources.requests.cpu"] {
+		t.Errorf("warnings = %v, want the probe and the requests", check.Warnings())
+	}
+
+	// Every unsupported field is reported, not only the first
+	unsupported := newPod()
+	unsupported.Spec.InitContainers = []corev1.Container{{Name: "migrate", Image: "migrate:1.0"}}
+	unsupported.Spec.Volumes = []corev1.Volume{
+		{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}}},
+	}
+	unsupported.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "NODE", ValueFrom: &corev1.EnvVarSource{
+		FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+	}}}
+	check = converter.CheckPod(context.Background(), unsupported, nil)
+	want := []string{"spec.initContainers", "spec.containers[0].env[0].valueFrom", "spec.volumes[1]"}
+	if len(check.Errors) != len(want) || check.TaskDefinition != nil {
+		t.Fatalf("errors = %v, want %v", check.Errors, want)
+	}
+	for i, field := range want {
+		if check.Errors[i].Field != field || check.Errors[i].Code != ValidationUnsupported {
+			t.Errorf("errors[%d] = %+v, want %s", i, check.Errors[i], field)
+		}
+	}
+
+	// Skipped features are dropped with a warning
+	skipping := NewConverter(ConversionOptions{DefaultCPU: "256", DefaultMemory: "512", SkipUnsupportedFeatures: true})
+	check = skipping.CheckPod(context.Background(), unsupported, nil)
+	if !check.Convertible() || len(check.Warnings()) < 3 {
+		t.Errorf("CheckPod() with skipped features = %v, %v", check.Errors, check.Warnings())
+	}
+
+	// Invalid annotations and task definitions ECS rejects are reported by field
+	annotated := newPod()
+	annotated.Annotations = map[string]string{AnnotationTags: "team"}
+	check = converter.CheckPod(context.Background(), annotated, nil)
+	if check.Convertible() || check.Errors[0].Field != "metadata.annotations["+AnnotationTags+"]" {
+		t.Errorf("errors of invalid annotations = %v", check.Errors)
+	}
+	oversized := newPod()
+	oversized.Annotations = map[string]string{AnnotationRequiresCompatibilities: "FARGATE", AnnotationCPU: "300"}
+	check = converter.CheckPod(context.Background(), oversized, nil)
+	if check.Convertible() || check.Errors[0].Field != "taskDefinition.cpu" {
+		t.Errorf("errors of an invalid task size = %v", check.Errors)
+	}
+}
@@ -1,15 +1,30 @@
 package ecs
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"text/template"
 
 	corev1 "k8s.io/api/core/v1"
 )
 
+// Defaults used when ConversionOptions leaves the log configuration empty
+const (
+	DefaultLogGroup = "/ecs/task"
+	DefaultRegion   = "us-east-1"
+	// DefaultLogStreamPrefix names the log streams <prefix>/<container>/<task ID>, which the
+	// virtual node reads the logs of kubectl logs from
+	DefaultLogStreamPrefix = "ecs"
+)
+
 // Converter handles the conversion from Kubernetes Pod spec to ECS task definition
 type Converter struct {
 	options ConversionOptions
+
+	familyTemplate        *template.Template
+	familyTemplateErr     error
+	familyIdentifiesOwner bool
 }
 
 // NewConverter creates a new converter with the given options
@@ -20,16 +35,25 @@ func NewConverter(options ConversionOptions) *Converter {
 	}
 	if options.DefaultLogOptions == nil {
 		options.DefaultLogOptions = map[string]string{
-			"awslogs-group":  "/ecs/task",
-			"awslogs-region": "us-east-1",
+			"awslogs-group":         DefaultLogGroup,
+			"awslogs-region":        DefaultRegion,
+			"awslogs-stream-prefix": DefaultLogStreamPrefix,
 		}
 	}
 	if options.ParameterStorePrefix == "" {
 		options.ParameterStorePrefix = "/pods"
 	}
+	if options.SecretBackend == "" {
+		options.SecretBackend = SecretBackendParameterStore
+	}
+
+	familyTemplate, err := parseFamilyTemplate(options.FamilyTemplate)
 
 	return &Converter{
-		options: options,
+		options:               options,
+		familyTemplate:        familyTemplate,
+		familyTemplateErr:     err,
+		familyIdentifiesOwner: err == nil && identifiesOwner(familyTemplate),
 	}
 }
 
@@ -49,58 +73,98 @@ func (c *Converter) ConvertPod(
 	ecsConfig *ECSConfig,
 	namespace string,
 ) (*ECSTaskDefinition, error) {
+	taskDef, _, err := c.ConvertPodWithDiagnostics(context.Background(), pod, podSpec, ecsConfig, namespace)
+	return taskDef, err
+}
+
+// ConvertPodWithDiagnostics converts a Kubernetes Pod (with metadata) to an ECS task
+// definition and reports settings that were defaulted or need attention
+func (c *Converter) ConvertPodWithDiagnostics(
+	ctx context.Context,
+	pod *corev1.Pod,
+	podSpec *corev1.PodSpec,
+	ecsConfig *ECSConfig,
+	namespace string,
+) (*ECSTaskDefinition, []Diagnostic, error) {
+	var diagnostics []Diagnostic
+	if pod != nil {
+		annotated, annotationDiagnostics, err := ParseAnnotationConfig(pod.Annotations)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid annotations: %w", err)
+		}
+		ecsConfig = MergeECSConfig(ecsConfig, annotated)
+		diagnostics = annotationDiagnostics
+	}
+
+	// Requests missing from the containers are filled like the mutating webhook does, so that
+	// pods created before it was installed convert alike
+	defaulted := podSpec.DeepCopy()
+	if defaults := c.DefaultRequests(defaulted, namespace); len(defaults) > 0 {
+		podSpec = defaulted
+		diagnostics = append(diagnostics, defaults...)
+	}
+
 	// Get compatibility requirements first to determine network mode
-	compatibilities := c.getRequiresCompatibilities(ecsConfig, pod)
+	compatibilities := c.getRequiresCompatibilities(ecsConfig)
+
+	family, err := c.getFamily(ecsConfig, pod, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	taskRoleArn, roleDiagnostics, err := c.resolveTaskRoleArn(ctx, podSpec, ecsConfig, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	diagnostics = append(diagnostics, roleDiagnostics...)
 
 	taskDef := &ECSTaskDefinition{
-		Family:                  ecsConfig.Family,
-		TaskRoleArn:             c.getTaskRoleArn(ecsConfig),
+		Family:                  family,
+		TaskRoleArn:             taskRoleArn,
 		ExecutionRoleArn:        c.getExecutionRoleArn(ecsConfig),
 		NetworkMode:             c.getNetworkMode(ecsConfig, compatibilities),
 		RequiresCompatibilities: compatibilities,
-		CPU:                     ecsConfig.CPU,
-		Memory:                  ecsConfig.Memory,
+		CPU:                     valueOrDefault(ecsConfig.CPU, c.options.DefaultCPU),
+		Memory:                  valueOrDefault(ecsConfig.Memory, c.options.DefaultMemory),
 	}
 
 	// Convert containers
 	containerDefs, err := c.convertContainers(podSpec.Containers, namespace, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert containers: %w", err)
+		return nil, nil, fmt.Errorf("failed to convert containers: %w", err)
+	}
+	if err := applyContainerOverrides(ecsConfig, containerDefs); err != nil {
+		return nil, nil, err
 	}
 	taskDef.ContainerDefinitions = containerDefs
 
 	// Convert init containers (ECS doesn't support init containers directly)
 	if len(podSpec.InitContainers) > 0 && !c.options.SkipUnsupportedFeatures {
-		return nil, fmt.Errorf("init containers are not supported in ECS")
+		return nil, nil, fmt.Errorf("init containers are not supported in ECS")
 	}
 
 	// Convert volumes
 	volumes, err := c.convertVolumes(podSpec.Volumes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert volumes: %w", err)
+		return nil, nil, fmt.Errorf("failed to convert volumes: %w", err)
 	}
 	taskDef.Volumes = volumes
 
-	// Convert tags
-	if len(ecsConfig.Tags) > 0 {
-		tags := make([]ECSTag, 0, len(ecsConfig.Tags))
-		for key, value := range ecsConfig.Tags {
-			tags = append(tags, ECSTag{
-				Key:   key,
-				Value: value,
-			})
-		}
-		taskDef.Tags = tags
+	// Convert tags and propagate pod metadata
+	metadataDiagnostics, err := c.applyMetadata(pod, ecsConfig, taskDef)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert metadata: %w", err)
 	}
+	diagnostics = append(diagnostics, metadataDiagnostics...)
 
-	return taskDef, nil
+	return taskDef, diagnostics, nil
 }
 
-func (c *Converter) getTaskRoleArn(ecsConfig *ECSConfig) string {
-	if ecsConfig.TaskRoleArn != "" {
-		return ecsConfig.TaskRoleArn
+func (c *Converter) getFamily(ecsConfig *ECSConfig, pod *corev1.Pod, namespace string) (string, error) {
+	if ecsConfig.Family != "" || pod == nil {
+		return ecsConfig.Family, nil
 	}
-	return c.options.DefaultTaskRoleArn
+	return c.FamilyName(pod, namespace)
 }
 
 func (c *Converter) getExecutionRoleArn(ecsConfig *ECSConfig) string {
@@ -125,19 +189,18 @@ func (c *Converter) getNetworkMode(ecsConfig *ECSConfig, compatibilities []strin
 	return "awsvpc"
 }
 
-func (c *Converter) getRequiresCompatibilities(ecsConfig *ECSConfig, pod *corev1.Pod) []string {
+func (c *Converter) getRequiresCompatibilities(ecsConfig *ECSConfig) []string {
 	if len(ecsConfig.RequiresCompatibilities) > 0 {
 		return ecsConfig.RequiresCompatibilities
 	}
+	return []string{"FARGATE"}
+}
 
-	// Check for annotation-based compatibility requirements
-	if pod != nil && pod.Annotations != nil {
-		if compatibilities, exists := pod.Annotations["ecs.takutakahashi.dev/requires-compatibilities"]; exists {
-			return strings.Split(compatibilities, ",")
-		}
+func valueOrDefault(value, defaultValue string) string {
+	if value != "" {
+		return value
 	}
-
-	return []string{"FARGATE"}
+	return defaultValue
 }
 
 func (c *Converter) convertContainers(
@@ -310,6 +373,12 @@ func (c *Converter) getParameterStorePathForSecret(namespace, secretName, key st
 	if namespace == "" {
 		namespace = "default"
 	}
+	if c.options.SecretBackend == SecretBackendSecretsManager {
+		// One secret per Kubernetes Secret; the key is selected with the json-key suffix
+		name := strings.TrimPrefix(fmt.Sprintf("%s/%s/%s", c.options.ParameterStorePrefix, namespace, secretName), "/")
+		return fmt.Sprintf("arn:%s:secretsmanager:%s:%s:secret:%s:%s::",
+			partitionForRegion(c.options.Region), c.options.Region, c.options.AccountID, name, key)
+	}
 	return fmt.Sprintf("%s/%s/secrets/%s/%s", c.options.ParameterStorePrefix, namespace, secretName, key)
 }
 
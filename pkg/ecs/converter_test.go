@@ -70,8 +70,9 @@ func TestConverter_Convert(t *testing.T) {
 						LogConfiguration: &ECSLogConfiguration{
 							LogDriver: "awslogs",
 							Options: map[string]string{
-								"awslogs-group":  "/ecs/task",
-								"awslogs-region": "us-east-1",
+								"awslogs-group":         "/ecs/task",
+								"awslogs-region":        "us-east-1",
+								"awslogs-stream-prefix": "ecs",
 							},
 						},
 					},
@@ -150,8 +151,9 @@ func TestConverter_Convert(t *testing.T) {
 						LogConfiguration: &ECSLogConfiguration{
 							LogDriver: "awslogs",
 							Options: map[string]string{
-								"awslogs-group":  "/ecs/task",
-								"awslogs-region": "us-east-1",
+								"awslogs-group":         "/ecs/task",
+								"awslogs-region":        "us-east-1",
+								"awslogs-stream-prefix": "ecs",
 							},
 						},
 					},
@@ -0,0 +1,47 @@
+package ecs
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// AllNamespaces keys the settings applying to namespaces without their own
+const AllNamespaces = "*"
+
+// DefaultRequests fills the resource requests missing from the containers of the pod spec with
+// the defaults of the namespace, and reports each filled request as a diagnostic. Requests are
+// not filled for resources with a limit, which Kubernetes already uses as the request.
+func (c *Converter) DefaultRequests(spec *corev1.PodSpec, namespace string) []Diagnostic {
+	defaults, ok := c.options.DefaultRequests[namespace]
+	if !ok {
+		defaults = c.options.DefaultRequests[AllNamespaces]
+	}
+	if len(defaults) == 0 {
+		return nil
+	}
+
+	var diagnostics []Diagnostic
+	for i := range spec.Containers {
+		container := &spec.Containers[i]
+		for _, name := range slices.Sorted(maps.Keys(defaults)) {
+			if _, set := container.Resources.Requests[name]; set {
+				continue
+			}
+			if _, limited := container.Resources.Limits[name]; limited {
+				continue
+			}
+			if container.Resources.Requests == nil {
+				container.Resources.Requests = corev1.ResourceList{}
+			}
+			quantity := defaults[name]
+			container.Resources.Requests[name] = quantity.DeepCopy()
+			diagnostics = append(diagnostics, newDiagnostic(SeverityInfo, DiagDefaultedValue,
+				fmt.Sprintf("spec.containers[%d].resources.requests.%s", i, name),
+				"%s request of container %s defaulted to %s", name, container.Name, quantity.String()))
+		}
+	}
+	return diagnostics
+}
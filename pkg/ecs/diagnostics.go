@@ -0,0 +1,66 @@
+package ecs
+
+import "fmt"
+
+// Severity indicates how serious a conversion diagnostic is
+type Severity string
+
+const (
+	// SeverityInfo marks diagnostics that are purely informational
+	SeverityInfo Severity = "Info"
+	// SeverityWarning marks lossy or approximated mappings
+	SeverityWarning Severity = "Warning"
+	// SeverityError marks settings that could not be converted at all
+	SeverityError Severity = "Error"
+)
+
+// DiagnosticCode identifies the kind of issue found during conversion
+type DiagnosticCode string
+
+const (
+	// DiagUnsupportedMetric is reported for HPA metrics that have no ECS equivalent
+	DiagUnsupportedMetric DiagnosticCode = "UnsupportedMetric"
+	// DiagUnsupportedScalingBehavior is reported for HPA behavior settings that are dropped
+	DiagUnsupportedScalingBehavior DiagnosticCode = "UnsupportedScalingBehavior"
+	// DiagDuplicateMetric is reported for HPA metrics mapping to an ECS predefined metric that an
+	// earlier metric already maps to
+	DiagDuplicateMetric DiagnosticCode = "DuplicateMetric"
+	// DiagDefaultedValue is reported when a value was filled in from a default
+	DiagDefaultedValue DiagnosticCode = "DefaultedValue"
+	// DiagServiceAccountNotFound is reported when the pod's ServiceAccount does not exist
+	DiagServiceAccountNotFound DiagnosticCode = "ServiceAccountNotFound"
+	// DiagMissingTaskRole is reported when no task role could be determined for the pod
+	DiagMissingTaskRole DiagnosticCode = "MissingTaskRole"
+	// DiagUnknownAnnotation is reported for unrecognized annotations in the ecs.takutakahashi.dev namespace
+	DiagUnknownAnnotation DiagnosticCode = "UnknownAnnotation"
+	// DiagUnsupportedField is reported for pod fields that are dropped because ECS has no equivalent and
+	// SkipUnsupportedFeatures is set
+	DiagUnsupportedField DiagnosticCode = "UnsupportedField"
+	// DiagLossyMapping is reported for pod fields that are converted only in part or ignored
+	DiagLossyMapping DiagnosticCode = "LossyMapping"
+)
+
+// Diagnostic describes a single issue found while converting a Kubernetes object
+type Diagnostic struct {
+	Severity Severity       `json:"severity"`
+	Code     DiagnosticCode `json:"code"`
+	Field    string         `json:"field,omitempty"`
+	Message  string         `json:"message"`
+}
+
+// String returns a human readable representation of the diagnostic
+func (d Diagnostic) String() string {
+	if d.Field != "" {
+		return fmt.Sprintf("%s [%s] %s: %s", d.Severity, d.Code, d.Field, d.Message)
+	}
+	return fmt.Sprintf("%s [%s] %s", d.Severity, d.Code, d.Message)
+}
+
+func newDiagnostic(severity Severity, code DiagnosticCode, field, format string, args ...any) Diagnostic {
+	return Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Field:    field,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
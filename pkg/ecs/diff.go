@@ -0,0 +1,303 @@
+package ecs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ChangeType is the kind of a task definition change
+type ChangeType string
+
+const (
+	// ChangeAdded is a field or list item that only exists in the current definition
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved is a field or list item that only exists in the previous definition
+	ChangeRemoved ChangeType = "removed"
+	// ChangeModified is a field whose value differs between the definitions
+	ChangeModified ChangeType = "modified"
+)
+
+// Change is a single field-level difference between two task definitions
+type Change struct {
+	// Path locates the field in the task definition JSON. Items of lists of named objects,
+	// such as containers, environment variables and tags, are addressed by name:
+	// containerDefinitions[web].environment[LOG_LEVEL].value
+	Path string
+	Type ChangeType
+	// Old and New are the JSON decoded values; Old is nil for additions and New for removals
+	Old any
+	New any
+}
+
+// String formats the change as a single line prefixed with +, - or ~
+func (c Change) String() string {
+	switch c.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Path, formatDiffValue(c.New))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Path, formatDiffValue(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, formatDiffValue(c.Old), formatDiffValue(c.New))
+	}
+}
+
+func formatDiffValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// diffListKeys are the fields identifying the items of lists of objects, in order of preference
+var diffListKeys = []string{"name", "key", "containerName", "sourceVolume"}
+
+// NormalizeTaskDefinition returns a copy of the task definition in the form ECS stores it:
+// without the read-only fields set on registration, with task CPU and memory in units and
+// MiB, and with the values ECS fills in when they are omitted set explicitly.
+func NormalizeTaskDefinition(t *ECSTaskDefinition) (*ECSTaskDefinition, error) {
+	// Round trip through JSON for a deep copy
+	data, err := json.Marshal(t.RegisterInput())
+	if err != nil {
+		return nil, err
+	}
+	normalized := &ECSTaskDefinition{}
+	if err := json.Unmarshal(data, normalized); err != nil {
+		return nil, err
+	}
+
+	normalized.NetworkMode = normalized.networkModeOrDefault()
+	if cpu, ok := parseTaskCPU(normalized.CPU); ok {
+		normalized.CPU = strconv.Itoa(cpu)
+	}
+	if memory, ok := parseTaskMemory(normalized.Memory); ok {
+		normalized.Memory = strconv.Itoa(memory)
+	}
+	slices.Sort(normalized.RequiresCompatibilities)
+
+	for i := range normalized.ContainerDefinitions {
+		container := &normalized.ContainerDefinitions[i]
+		for j := range container.PortMappings {
+			mapping := &container.PortMappings[j]
+			if mapping.Protocol == "" && mapping.ContainerPortRange == "" {
+				mapping.Protocol = "tcp"
+			}
+			// With awsvpc and host networking ECS records the container port as host port
+			if mapping.HostPort == 0 && (normalized.NetworkMode == "awsvpc" || normalized.NetworkMode == "host") {
+				mapping.HostPort = mapping.ContainerPort
+			}
+		}
+	}
+
+	return normalized, nil
+}
+
+// DiffTaskDefinitions compares two task definitions after normalizing both, returning the
+// changes that turn previous into current. Fields ECS sets on registration, such as the
+// revision, status and registeredAt, and values ECS defaults are not reported. Containers and
+// other named list items are matched by name, so reordering them is not a change either.
+func DiffTaskDefinitions(previous, current *ECSTaskDefinition) ([]Change, error) {
+	previousValue, err := diffValue(previous)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize previous task definition: %w", err)
+	}
+	currentValue, err := diffValue(current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize current task definition: %w", err)
+	}
+
+	var changes []Change
+	diffValues("", previousValue, currentValue, &changes)
+	return changes, nil
+}
+
+// TaskDefinitionHash returns the SHA-256 of the normalized task definition. Task definitions
+// DiffTaskDefinitions finds no changes between hash alike, unless they list containers or other
+// named items in a different order.
+func TaskDefinitionHash(t *ECSTaskDefinition) (string, error) {
+	value, err := diffValue(t)
+	if err != nil {
+		return "", fmt.Errorf("failed to normalize task definition: %w", err)
+	}
+	// Object keys are sorted when marshaled, so equal values always give the same JSON
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// diffValue normalizes the task definition and decodes it into generic JSON values
+func diffValue(t *ECSTaskDefinition) (any, error) {
+	normalized, err := NormalizeTaskDefinition(t)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return pruneEmpty(value), nil
+}
+
+// pruneEmpty drops null values and empty lists and objects, which ECS does not distinguish
+// from omitted fields
+func pruneEmpty(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			item = pruneEmpty(item)
+			if isEmptyDiffValue(item) {
+				delete(v, key)
+			} else {
+				v[key] = item
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = pruneEmpty(item)
+		}
+	}
+	return value
+}
+
+func isEmptyDiffValue(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+func diffValues(path string, previous, current any, changes *[]Change) {
+	switch previousValue := previous.(type) {
+	case map[string]any:
+		if currentValue, ok := current.(map[string]any); ok {
+			diffObjects(path, previousValue, currentValue, changes)
+			return
+		}
+	case []any:
+		if currentValue, ok := current.([]any); ok {
+			diffLists(path, previousValue, currentValue, changes)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(previous, current) {
+		*changes = append(*changes, Change{Path: path, Type: ChangeModified, Old: previous, New: current})
+	}
+}
+
+func diffObjects(path string, previous, current map[string]any, changes *[]Change) {
+	keys := slices.Collect(maps.Keys(previous))
+	for key := range current {
+		if _, ok := previous[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		field := key
+		if path != "" {
+			field = path + "." + key
+		}
+		previousItem, inPrevious := previous[key]
+		currentItem, inCurrent := current[key]
+		switch {
+		case !inPrevious:
+			*changes = append(*changes, Change{Path: field, Type: ChangeAdded, New: currentItem})
+		case !inCurrent:
+			*changes = append(*changes, Change{Path: field, Type: ChangeRemoved, Old: previousItem})
+		default:
+			diffValues(field, previousItem, currentItem, changes)
+		}
+	}
+}
+
+// diffLists matches the items of lists of named objects by name, in the order of the current
+// list followed by removed items, and other lists by position
+func diffLists(path string, previous, current []any, changes *[]Change) {
+	if key := listKey(previous, current); key != "" {
+		previousItems := map[string]any{}
+		for _, item := range previous {
+			previousItems[item.(map[string]any)[key].(string)] = item
+		}
+		currentItems := map[string]bool{}
+		for _, item := range current {
+			name := item.(map[string]any)[key].(string)
+			currentItems[name] = true
+			field := fmt.Sprintf("%s[%s]", path, name)
+			if previousItem, ok := previousItems[name]; ok {
+				diffValues(field, previousItem, item, changes)
+			} else {
+				*changes = append(*changes, Change{Path: field, Type: ChangeAdded, New: item})
+			}
+		}
+		for _, item := range previous {
+			name := item.(map[string]any)[key].(string)
+			if !currentItems[name] {
+				field := fmt.Sprintf("%s[%s]", path, name)
+				*changes = append(*changes, Change{Path: field, Type: ChangeRemoved, Old: item})
+			}
+		}
+		return
+	}
+
+	for i := 0; i < max(len(previous), len(current)); i++ {
+		field := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(previous):
+			*changes = append(*changes, Change{Path: field, Type: ChangeAdded, New: current[i]})
+		case i >= len(current):
+			*changes = append(*changes, Change{Path: field, Type: ChangeRemoved, Old: previous[i]})
+		default:
+			diffValues(field, previous[i], current[i], changes)
+		}
+	}
+}
+
+// listKey returns the field naming every item of both lists uniquely, or "" if there is none
+func listKey(lists ...[]any) string {
+	for _, key := range diffListKeys {
+		if namedBy(key, lists...) {
+			return key
+		}
+	}
+	return ""
+}
+
+func namedBy(key string, lists ...[]any) bool {
+	for _, list := range lists {
+		seen := map[string]bool{}
+		for _, item := range list {
+			object, ok := item.(map[string]any)
+			if !ok {
+				return false
+			}
+			name, ok := object[key].(string)
+			if !ok || name == "" || seen[name] || strings.ContainsAny(name, "[]") {
+				return false
+			}
+			seen[name] = true
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTaskDefinitions_IgnoresRegistrationFields(t *testing.T) {
+	registered, err := ParseTaskDefinition([]byte(describedTaskDefinition))
+	if err != nil {
+		t.Fatalf("ParseTaskDefinition() error = %v", err)
+	}
+
+	// The generated definition omits what ECS fills in and lists containers in another order
+	generated := registered.RegisterInput()
+	generated.CPU = "0.5 vCPU"
+	generated.Memory = "1 GB"
+	generated.ContainerDefinitions = []ECSContainerDefinition{
+		registered.ContainerDefinitions[1],
+		registered.ContainerDefinitions[0],
+	}
+	app := &generated.ContainerDefinitions[1]
+	app.PortMappings = []ECSPortMapping{{ContainerPort: 8080, Name: "http", AppProtocol: "http"}}
+	app.Environment = []ECSKeyValuePair{}
+
+	changes, err := DiffTaskDefinitions(registered, generated)
+	if err != nil {
+		t.Fatalf("DiffTaskDefinitions() error = %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("DiffTaskDefinitions() = %v, want no changes", changes)
+	}
+}
+
+func TestDiffTaskDefinitions_Changes(t *testing.T) {
+	previous := &ECSTaskDefinition{
+		Family:   "web",
+		CPU:      "256",
+		Revision: 4,
+		Status:   "ACTIVE",
+		ContainerDefinitions: []ECSContainerDefinition{
+			{
+				Name:        "web",
+				Image:       "nginx:1.25",
+				Essential:   true,
+				Environment: []ECSKeyValuePair{{Name: "MODE", Value: "a"}, {Name: "OLD", Value: "x"}},
+			},
+			{Name: "sidecar", Image: "envoy:1.0"},
+		},
+		Tags: []ECSTag{{Key: "team", Value: "web"}},
+	}
+	current := &ECSTaskDefinition{
+		Family: "web",
+		CPU:    "512",
+		ContainerDefinitions: []ECSContainerDefinition{
+			{
+				Name:        "web",
+				Image:       "nginx:1.27",
+				Essential:   true,
+				Environment: []ECSKeyValuePair{{Name: "NEW", Value: "y"}, {Name: "MODE", Value: "b"}},
+			},
+		},
+		Tags: []ECSTag{{Key: "team", Value: "web"}},
+	}
+
+	changes, err := DiffTaskDefinitions(previous, current)
+	if err != nil {
+		t.Fatalf("DiffTaskDefinitions() error = %v", err)
+	}
+
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.String())
+	}
+	want := []string{
+		`+ containerDefinitions[web].environment[NEW]: {"name":"NEW","value":"y"}`,
+		`~ containerDefinitions[web].environment[MODE].value: "a" -> "b"`,
+		`- containerDefinitions[web].environment[OLD]: {"name":"OLD","value":"x"}`,
+		`~ containerDefinitions[web].image: "nginx:1.25" -> "nginx:1.27"`,
+		`- containerDefinitions[sidecar]: {"essential":false,"image":"envoy:1.0","name":"sidecar"}`,
+		`~ cpu: "256" -> "512"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffTaskDefinitions() =\n%v\nwant\n%v", got, want)
+	}
+}
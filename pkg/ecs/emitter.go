@@ -0,0 +1,190 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Emitter renders a converted task definition into a deployable output format
+type Emitter interface {
+	// Emit writes the rendered task definition to w
+	Emit(w io.Writer, taskDef *ECSTaskDefinition) error
+
+	// Extension returns the file extension used for the output format
+	Extension() string
+}
+
+// EmitterFactory creates a new Emitter
+type EmitterFactory func() Emitter
+
+var emitterFactories = map[string]EmitterFactory{
+	"json":           func() Emitter { return &JSONEmitter{} },
+	"terraform":      func() Emitter { return &TerraformEmitter{} },
+	"cloudformation": func() Emitter { return &CloudFormationEmitter{} },
+	"compose":        func() Emitter { return &ComposeEmitter{} },
+}
+
+// RegisterEmitter registers an emitter factory under the given format name
+func RegisterEmitter(format string, factory EmitterFactory) {
+	emitterFactories[format] = factory
+}
+
+// NewEmitter returns the emitter registered for the given format name
+func NewEmitter(format string) (Emitter, error) {
+	factory, ok := emitterFactories[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q (available: %s)",
+			format, strings.Join(EmitterFormats(), ", "))
+	}
+	return factory(), nil
+}
+
+// EmitterFormats returns the sorted list of registered output formats
+func EmitterFormats() []string {
+	formats := make([]string, 0, len(emitterFactories))
+	for format := range emitterFactories {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// JSONEmitter renders the task definition as RegisterTaskDefinition input JSON
+type JSONEmitter struct{}
+
+// Emit implements Emitter
+func (e *JSONEmitter) Emit(w io.Writer, taskDef *ECSTaskDefinition) error {
+	jsonData, err := json.MarshalIndent(taskDef, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	if _, err := w.Write(jsonData); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
+
+// Extension implements Emitter
+func (e *JSONEmitter) Extension() string {
+	return "json"
+}
+
+// orderedField is a single key/value pair of a decoded JSON object
+type orderedField struct {
+	Key   string
+	Value any
+}
+
+// orderedObject is a decoded JSON object that keeps the key order of the source document
+type orderedObject []orderedField
+
+// toOrderedValue converts v into a generic tree of orderedObject, []any, string,
+// json.Number, bool and nil values, preserving the field order of the JSON encoding
+func toOrderedValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decodeOrderedValue(decoder)
+}
+
+func decodeOrderedValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		object := orderedObject{}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, orderedField{Key: keyToken.(string), Value: value})
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return object, nil
+	case '[':
+		array := []any{}
+		for decoder.More() {
+			value, err := decodeOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return array, nil
+	default:
+		return nil, fmt.Errorf("unexpected JSON delimiter %v", delim)
+	}
+}
+
+// resourceName converts a task definition family into an identifier usable as a
+// Terraform resource name or CloudFormation logical ID
+func resourceName(family string, pascalCase bool) string {
+	var b strings.Builder
+	upperNext := pascalCase
+	for _, r := range family {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			if upperNext {
+				b.WriteString(strings.ToUpper(string(r)))
+			} else {
+				b.WriteRune(r)
+			}
+			upperNext = false
+		default:
+			if pascalCase {
+				upperNext = true
+			} else {
+				b.WriteRune('_')
+			}
+		}
+	}
+
+	name := b.String()
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		if pascalCase {
+			name = "TaskDefinition" + name
+		} else {
+			name = "task_" + name
+		}
+	}
+	return name
+}
+
+// logRegion returns the awslogs region configured on the task definition's containers
+func logRegion(taskDef *ECSTaskDefinition) string {
+	for _, container := range taskDef.ContainerDefinitions {
+		if container.LogConfiguration != nil {
+			if region := container.LogConfiguration.Options["awslogs-region"]; region != "" {
+				return region
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,185 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CloudFormationEmitter renders the task definition as a CloudFormation template with an
+// AWS::ECS::TaskDefinition resource. Role ARNs and the log region are exposed as parameters.
+type CloudFormationEmitter struct {
+	// LogicalID is the logical ID of the resource (default: derived from the family)
+	LogicalID string
+}
+
+// cloudFormationPropertyNames maps task definition fields whose CloudFormation property
+// name is not the PascalCase form of the JSON field name. Keys qualified with the parent
+// field apply only within that object.
+var cloudFormationPropertyNames = map[string]string{
+	"efsVolumeConfiguration":                  "EFSVolumeConfiguration",
+	"fsxWindowsFileServerVolumeConfiguration": "FSxWindowsFileServerVolumeConfiguration",
+	"iam":                                 "IAM",
+	"properties":                          "ProxyConfigurationProperties",
+	"efsVolumeConfiguration.fileSystemId": "FilesystemId",
+}
+
+// cloudFormationFreeformKeys lists fields whose values are user-defined maps; their keys
+// are copied verbatim instead of being converted to PascalCase
+var cloudFormationFreeformKeys = map[string]bool{
+	"options":      true,
+	"dockerLabels": true,
+	"labels":       true,
+	"driverOpts":   true,
+}
+
+// Emit implements Emitter
+func (e *CloudFormationEmitter) Emit(w io.Writer, taskDef *ECSTaskDefinition) error {
+	logicalID := e.LogicalID
+	if logicalID == "" {
+		logicalID = resourceName(taskDef.Family, true)
+	}
+
+	root, err := toOrderedValue(taskDef)
+	if err != nil {
+		return fmt.Errorf("failed to encode task definition: %w", err)
+	}
+
+	region := logRegion(taskDef)
+	parameters := &yaml.Node{Kind: yaml.MappingNode}
+	addParameter := func(name, description, defaultValue string) {
+		parameters.Content = append(parameters.Content, yamlString(name), yamlMapping(
+			"Type", yamlString("String"),
+			"Description", yamlString(description),
+			"Default", yamlString(defaultValue),
+		))
+	}
+	if taskDef.ExecutionRoleArn != "" {
+		addParameter("ExecutionRoleArn", "ARN of the ECS task execution role", taskDef.ExecutionRoleArn)
+	}
+	if taskDef.TaskRoleArn != "" {
+		addParameter("TaskRoleArn", "ARN of the IAM role assumed by the task", taskDef.TaskRoleArn)
+	}
+	if region != "" {
+		addParameter("LogRegion", "AWS region of the CloudWatch log group", region)
+	}
+
+	properties := &yaml.Node{Kind: yaml.MappingNode}
+	for _, field := range root.(orderedObject) {
+		if readOnlyTaskDefinitionFields[field.Key] {
+			continue
+		}
+
+		var value *yaml.Node
+		switch field.Key {
+		case "executionRoleArn":
+			value = yamlMapping("Ref", yamlString("ExecutionRoleArn"))
+		case "taskRoleArn":
+			value = yamlMapping("Ref", yamlString("TaskRoleArn"))
+		default:
+			value = cloudFormationValue(field.Key, field.Value, region != "")
+		}
+		properties.Content = append(properties.Content,
+			yamlString(cloudFormationPropertyName("", field.Key)), value)
+	}
+
+	template := &yaml.Node{Kind: yaml.MappingNode}
+	template.Content = append(template.Content,
+		yamlString("AWSTemplateFormatVersion"), yamlString("2010-09-09"),
+		yamlString("Description"), yamlString(fmt.Sprintf("ECS task definition %s", taskDef.Family)),
+	)
+	if len(parameters.Content) > 0 {
+		template.Content = append(template.Content, yamlString("Parameters"), parameters)
+	}
+	template.Content = append(template.Content,
+		yamlString("Resources"), yamlMapping(logicalID, yamlMapping(
+			"Type", yamlString("AWS::ECS::TaskDefinition"),
+			"Properties", properties,
+		)),
+		yamlString("Outputs"), yamlMapping("TaskDefinitionArn", yamlMapping(
+			"Value", yamlMapping("Ref", yamlString(logicalID)),
+		)),
+	)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{template}}); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return encoder.Close()
+}
+
+// Extension implements Emitter
+func (e *CloudFormationEmitter) Extension() string {
+	return "yaml"
+}
+
+func cloudFormationPropertyName(parent, key string) string {
+	if name, ok := cloudFormationPropertyNames[parent+"."+key]; ok {
+		return name
+	}
+	if name, ok := cloudFormationPropertyNames[key]; ok {
+		return name
+	}
+	if key == "" {
+		return key
+	}
+	return strings.ToUpper(key[:1]) + key[1:]
+}
+
+// cloudFormationValue converts a decoded JSON value into a YAML node, renaming object keys
+// to CloudFormation property names
+func cloudFormationValue(key string, v any, refRegion bool) *yaml.Node {
+	switch value := v.(type) {
+	case orderedObject:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, field := range value {
+			if cloudFormationFreeformKeys[key] {
+				fieldNode := cloudFormationValue(field.Key, field.Value, refRegion)
+				if field.Key == "awslogs-region" && refRegion {
+					fieldNode = yamlMapping("Ref", yamlString("LogRegion"))
+				}
+				node.Content = append(node.Content, yamlString(field.Key), fieldNode)
+				continue
+			}
+			node.Content = append(node.Content,
+				yamlString(cloudFormationPropertyName(key, field.Key)),
+				cloudFormationValue(field.Key, field.Value, refRegion))
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range value {
+			node.Content = append(node.Content, cloudFormationValue(key, item, refRegion))
+		}
+		return node
+	case string:
+		return yamlString(value)
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprintf("%t", value)}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+}
+
+func yamlString(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// yamlMapping builds a mapping node from alternating key strings and value nodes
+func yamlMapping(pairs ...any) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		node.Content = append(node.Content, yamlString(pairs[i].(string)), pairs[i+1].(*yaml.Node))
+	}
+	return node
+}
@@ -0,0 +1,364 @@
+package ecs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultPauseImage holds the shared network namespace for awsvpc tasks
+	defaultPauseImage = "registry.k8s.io/pause:3.10"
+)
+
+var composeProjectInvalidChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// ComposeEmitter renders the task definition as a docker-compose file for running the
+// task locally. awsvpc tasks share a single network namespace through a pause service,
+// and secrets are resolved from a local map keyed by their valueFrom path.
+//
+// docker compose has no condition for "exited with any code", so a COMPLETE dependency
+// becomes service_completed_successfully and the dependent container does not start when
+// the dependency fails. Emit reports a warning for each such dependency; see Diagnostics.
+type ComposeEmitter struct {
+	// Secrets maps Parameter Store paths or secret ARNs to their local values.
+	// Unresolved secrets are read from the shell environment by docker compose.
+	Secrets map[string]string
+
+	// PauseImage is the image used for the network namespace holder (default: registry.k8s.io/pause)
+	PauseImage string
+
+	diagnostics []Diagnostic
+}
+
+type composeFile struct {
+	Name     string                     `yaml:"name,omitempty"`
+	Services map[string]*composeService `yaml:"services"`
+	Volumes  map[string]struct{}        `yaml:"volumes,omitempty"`
+}
+
+type composeService struct {
+	Image       string                       `yaml:"image"`
+	Entrypoint  []string                     `yaml:"entrypoint,omitempty"`
+	Command     []string                     `yaml:"command,omitempty"`
+	WorkingDir  string                       `yaml:"working_dir,omitempty"`
+	User        string                       `yaml:"user,omitempty"`
+	Environment map[string]string            `yaml:"environment,omitempty"`
+	Ports       []string                     `yaml:"ports,omitempty"`
+	NetworkMode string                       `yaml:"network_mode,omitempty"`
+	DependsOn   map[string]composeDependency `yaml:"depends_on,omitempty"`
+	Healthcheck *composeHealthcheck          `yaml:"healthcheck,omitempty"`
+	Volumes     []string                     `yaml:"volumes,omitempty"`
+	VolumesFrom []string                     `yaml:"volumes_from,omitempty"`
+	Tmpfs       []string                     `yaml:"tmpfs,omitempty"`
+	CPUs        string                       `yaml:"cpus,omitempty"`
+	MemLimit    string                       `yaml:"mem_limit,omitempty"`
+	MemReserve  string                       `yaml:"mem_reservation,omitempty"`
+	Hostname    string                       `yaml:"hostname,omitempty"`
+	Privileged  bool                         `yaml:"privileged,omitempty"`
+	ReadOnly    bool                         `yaml:"read_only,omitempty"`
+	Init        bool                         `yaml:"init,omitempty"`
+	CapAdd      []string                     `yaml:"cap_add,omitempty"`
+	CapDrop     []string                     `yaml:"cap_drop,omitempty"`
+	ShmSize     string                       `yaml:"shm_size,omitempty"`
+	DNS         []string                     `yaml:"dns,omitempty"`
+	DNSSearch   []string                     `yaml:"dns_search,omitempty"`
+	ExtraHosts  []string                     `yaml:"extra_hosts,omitempty"`
+	Labels      map[string]string            `yaml:"labels,omitempty"`
+	Sysctls     map[string]string            `yaml:"sysctls,omitempty"`
+	Ulimits     map[string]composeUlimit     `yaml:"ulimits,omitempty"`
+	StopGrace   string                       `yaml:"stop_grace_period,omitempty"`
+}
+
+type composeUlimit struct {
+	Soft int `yaml:"soft"`
+	Hard int `yaml:"hard"`
+}
+
+type composeDependency struct {
+	Condition string `yaml:"condition"`
+}
+
+type composeHealthcheck struct {
+	Test        []string `yaml:"test"`
+	Interval    string   `yaml:"interval,omitempty"`
+	Timeout     string   `yaml:"timeout,omitempty"`
+	Retries     int      `yaml:"retries,omitempty"`
+	StartPeriod string   `yaml:"start_period,omitempty"`
+}
+
+// composeDependencyConditions maps ECS dependsOn conditions to docker compose conditions.
+// COMPLETE accepts any exit code in ECS but only a zero exit code in docker compose.
+var composeDependencyConditions = map[string]string{
+	"START":    "service_started",
+	"COMPLETE": "service_completed_successfully",
+	"SUCCESS":  "service_completed_successfully",
+	"HEALTHY":  "service_healthy",
+}
+
+// Emit implements Emitter
+func (e *ComposeEmitter) Emit(w io.Writer, taskDef *ECSTaskDefinition) error {
+	e.diagnostics = nil
+	pauseImage := e.PauseImage
+	if pauseImage == "" {
+		pauseImage = defaultPauseImage
+	}
+
+	compose := composeFile{
+		Name:     composeProjectInvalidChars.ReplaceAllString(strings.ToLower(taskDef.Family), "-"),
+		Services: map[string]*composeService{},
+	}
+
+	hostPaths := map[string]string{}
+	for _, volume := range taskDef.Volumes {
+		if volume.Host != nil && volume.Host.SourcePath != "" {
+			hostPaths[volume.Name] = volume.Host.SourcePath
+			continue
+		}
+		if compose.Volumes == nil {
+			compose.Volumes = map[string]struct{}{}
+		}
+		compose.Volumes[volume.Name] = struct{}{}
+	}
+
+	// awsvpc tasks share one network namespace, emulated by a pause container owning the ports
+	var pause *composeService
+	pauseName := ""
+	if taskDef.NetworkMode == "" || taskDef.NetworkMode == "awsvpc" {
+		pauseName = "pause"
+		for _, container := range taskDef.ContainerDefinitions {
+			if container.Name == pauseName {
+				pauseName = "ecs-pause"
+			}
+		}
+		pause = &composeService{Image: pauseImage}
+		compose.Services[pauseName] = pause
+	}
+
+	for i, container := range taskDef.ContainerDefinitions {
+		service := &composeService{
+			Image:      container.Image,
+			Entrypoint: container.EntryPoint,
+			Command:    container.Command,
+			WorkingDir: container.WorkingDirectory,
+			User:       container.User,
+		}
+
+		environment := map[string]string{}
+		for _, env := range container.Environment {
+			environment[env.Name] = escapeComposeValue(env.Value)
+		}
+		for _, secret := range container.Secrets {
+			if value, ok := e.Secrets[secret.ValueFrom]; ok {
+				environment[secret.Name] = escapeComposeValue(value)
+			} else {
+				environment[secret.Name] = fmt.Sprintf("${%s}", secret.Name)
+			}
+		}
+		if len(environment) > 0 {
+			service.Environment = environment
+		}
+
+		var ports []string
+		for _, port := range container.PortMappings {
+			hostPort := port.HostPort
+			if hostPort == 0 {
+				hostPort = port.ContainerPort
+			}
+			mapping := fmt.Sprintf("%d:%d", hostPort, port.ContainerPort)
+			if port.Protocol != "" && port.Protocol != "tcp" {
+				mapping += "/" + port.Protocol
+			}
+			ports = append(ports, mapping)
+		}
+
+		if pause != nil {
+			pause.Ports = append(pause.Ports, ports...)
+			service.NetworkMode = "service:" + pauseName
+			service.DependsOn = map[string]composeDependency{
+				pauseName: {Condition: "service_started"},
+			}
+		} else {
+			// Docker rejects a hostname on containers joining another network namespace
+			service.Ports = ports
+			service.Hostname = container.Hostname
+		}
+
+		for j, dependency := range container.DependsOn {
+			condition, ok := composeDependencyConditions[dependency.Condition]
+			if !ok {
+				return fmt.Errorf("container %s has unsupported dependsOn condition %q",
+					container.Name, dependency.Condition)
+			}
+			if dependency.Condition == "COMPLETE" {
+				e.diagnostics = append(e.diagnostics, newDiagnostic(SeverityWarning, DiagLossyMapping,
+					fmt.Sprintf("containerDefinitions[%d].dependsOn[%d].condition", i, j),
+					"COMPLETE is emitted as service_completed_successfully, so %s does not start "+
+						"when %s exits with a non-zero code", container.Name, dependency.ContainerName))
+			}
+			if service.DependsOn == nil {
+				service.DependsOn = map[string]composeDependency{}
+			}
+			service.DependsOn[dependency.ContainerName] = composeDependency{Condition: condition}
+		}
+
+		if check := container.HealthCheck; check != nil {
+			service.Healthcheck = &composeHealthcheck{
+				Test:        check.Command,
+				Interval:    composeDuration(check.Interval),
+				Timeout:     composeDuration(check.Timeout),
+				Retries:     check.Retries,
+				StartPeriod: composeDuration(check.StartPeriod),
+			}
+		}
+
+		for _, mount := range container.MountPoints {
+			source := mount.SourceVolume
+			if hostPath, ok := hostPaths[mount.SourceVolume]; ok {
+				source = hostPath
+			}
+			volume := fmt.Sprintf("%s:%s", source, mount.ContainerPath)
+			if mount.ReadOnly {
+				volume += ":ro"
+			}
+			service.Volumes = append(service.Volumes, volume)
+		}
+
+		for _, from := range container.VolumesFrom {
+			volumesFrom := from.SourceContainer
+			if from.ReadOnly {
+				volumesFrom += ":ro"
+			}
+			service.VolumesFrom = append(service.VolumesFrom, volumesFrom)
+		}
+
+		if params := container.LinuxParameters; params != nil {
+			if params.Capabilities != nil {
+				service.CapAdd = params.Capabilities.Add
+				service.CapDrop = params.Capabilities.Drop
+			}
+			service.Init = params.InitProcessEnabled != nil && *params.InitProcessEnabled
+			if params.SharedMemorySize != nil {
+				service.ShmSize = fmt.Sprintf("%dm", *params.SharedMemorySize)
+			}
+			for _, tmpfs := range params.Tmpfs {
+				options := append([]string{}, tmpfs.MountOptions...)
+				if tmpfs.Size > 0 {
+					options = append(options, fmt.Sprintf("size=%dm", tmpfs.Size))
+				}
+				mount := tmpfs.ContainerPath
+				if len(options) > 0 {
+					mount += ":" + strings.Join(options, ",")
+				}
+				service.Tmpfs = append(service.Tmpfs, mount)
+			}
+		}
+
+		service.Privileged = container.Privileged != nil && *container.Privileged
+		service.ReadOnly = container.ReadonlyRootFilesystem != nil && *container.ReadonlyRootFilesystem
+		service.DNS = container.DNSServers
+		service.DNSSearch = container.DNSSearchDomains
+		service.Labels = container.DockerLabels
+		service.StopGrace = composeDuration(container.StopTimeout)
+		for _, host := range container.ExtraHosts {
+			service.ExtraHosts = append(service.ExtraHosts, host.Hostname+":"+host.IPAddress)
+		}
+		for _, control := range container.SystemControls {
+			if service.Sysctls == nil {
+				service.Sysctls = map[string]string{}
+			}
+			service.Sysctls[control.Namespace] = control.Value
+		}
+		for _, ulimit := range container.Ulimits {
+			if service.Ulimits == nil {
+				service.Ulimits = map[string]composeUlimit{}
+			}
+			service.Ulimits[ulimit.Name] = composeUlimit{Soft: ulimit.SoftLimit, Hard: ulimit.HardLimit}
+		}
+
+		if container.CPU > 0 {
+			service.CPUs = fmt.Sprintf("%g", float64(container.CPU)/1024)
+		}
+		if container.Memory > 0 {
+			service.MemLimit = fmt.Sprintf("%dm", container.Memory)
+		}
+		if container.MemoryReservation > 0 {
+			service.MemReserve = fmt.Sprintf("%dm", container.MemoryReservation)
+		}
+
+		compose.Services[container.Name] = service
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(compose); err != nil {
+		return fmt.Errorf("failed to encode compose file: %w", err)
+	}
+	return encoder.Close()
+}
+
+// Diagnostics returns the warnings reported by the last call to Emit
+func (e *ComposeEmitter) Diagnostics() []Diagnostic {
+	return e.diagnostics
+}
+
+// Extension implements Emitter
+func (e *ComposeEmitter) Extension() string {
+	return "compose.yaml"
+}
+
+// LoadSecretsFile reads a .env style file mapping Parameter Store paths (or secret ARNs)
+// to values, one KEY=VALUE pair per line. Blank lines and lines starting with # are ignored.
+func LoadSecretsFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	secrets := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Paths and ARNs never contain "=", so the first one separates key and value
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNumber)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		secrets[strings.TrimSpace(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+func composeDuration(seconds int) string {
+	if seconds <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// escapeComposeValue prevents docker compose from interpolating literal values
+func escapeComposeValue(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
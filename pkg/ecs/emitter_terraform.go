@@ -0,0 +1,369 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// TerraformEmitter renders the task definition as an aws_ecs_task_definition resource.
+// Role ARNs and the log region are exposed as input variables.
+type TerraformEmitter struct {
+	// ResourceName is the Terraform resource name (default: derived from the family)
+	ResourceName string
+}
+
+var hclIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// terraformAttributeNames maps task definition fields whose Terraform attribute name
+// does not follow the snake_case convention
+var terraformAttributeNames = map[string]string{
+	"volumes":               "volume",
+	"inferenceAccelerators": "inference_accelerator",
+	"sizeInGiB":             "size_in_gib",
+	"configuredAtLaunch":    "configure_at_launch",
+}
+
+// terraformMapAttributes lists fields that are map attributes rather than nested blocks
+var terraformMapAttributes = map[string]bool{
+	"driverOpts": true,
+	"labels":     true,
+}
+
+// readOnlyTaskDefinitionFields lists fields set by ECS that are not part of the
+// RegisterTaskDefinition input and therefore never emitted as IaC
+var readOnlyTaskDefinitionFields = map[string]bool{
+	"taskDefinitionArn":  true,
+	"revision":           true,
+	"status":             true,
+	"requiresAttributes": true,
+	"compatibilities":    true,
+	"registeredAt":       true,
+	"registeredBy":       true,
+	"deregisteredAt":     true,
+	"deleteRequestedAt":  true,
+}
+
+// Emit implements Emitter
+func (e *TerraformEmitter) Emit(w io.Writer, taskDef *ECSTaskDefinition) error {
+	name := e.ResourceName
+	if name == "" {
+		name = resourceName(taskDef.Family, false)
+	}
+
+	root, err := toOrderedValue(taskDef)
+	if err != nil {
+		return fmt.Errorf("failed to encode task definition: %w", err)
+	}
+
+	region := logRegion(taskDef)
+	variables := orderedObject{}
+	if taskDef.ExecutionRoleArn != "" {
+		variables = append(variables, orderedField{"execution_role_arn", taskDef.ExecutionRoleArn})
+	}
+	if taskDef.TaskRoleArn != "" {
+		variables = append(variables, orderedField{"task_role_arn", taskDef.TaskRoleArn})
+	}
+	if region != "" {
+		variables = append(variables, orderedField{"aws_region", region})
+	}
+
+	var b strings.Builder
+	for _, variable := range variables {
+		fmt.Fprintf(&b, "variable %q {\n", variable.Key)
+		fmt.Fprintf(&b, "  type    = string\n")
+		fmt.Fprintf(&b, "  default = %s\n", hclString(variable.Value.(string)))
+		fmt.Fprintf(&b, "}\n\n")
+	}
+
+	substitute := func(key string, _ any) (string, bool) {
+		if key == "awslogs-region" && region != "" {
+			return "var.aws_region", true
+		}
+		return "", false
+	}
+
+	body := orderedObject{}
+	for _, field := range root.(orderedObject) {
+		if readOnlyTaskDefinitionFields[field.Key] {
+			continue
+		}
+
+		switch field.Key {
+		case "executionRoleArn":
+			body = append(body, orderedField{"execution_role_arn", hclExpression("var.execution_role_arn")})
+		case "taskRoleArn":
+			body = append(body, orderedField{"task_role_arn", hclExpression("var.task_role_arn")})
+		case "containerDefinitions":
+			var inner strings.Builder
+			writeHCLValue(&inner, field.Value, 1, substitute)
+			body = append(body, orderedField{
+				"container_definitions", hclExpression("jsonencode(" + inner.String() + ")"),
+			})
+		case "tags":
+			tags := hclMap{}
+			for _, tag := range field.Value.([]any) {
+				tagObject := tag.(orderedObject)
+				tags = append(tags, orderedField{fieldValue(tagObject, "key").(string), fieldValue(tagObject, "value")})
+			}
+			body = append(body, orderedField{"tags", tags})
+		default:
+			body = append(body, terraformField(field)...)
+		}
+	}
+
+	fmt.Fprintf(&b, "resource \"aws_ecs_task_definition\" %q {\n", name)
+	writeTerraformBody(&b, body, 1)
+	b.WriteString("}\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// Extension implements Emitter
+func (e *TerraformEmitter) Extension() string {
+	return "tf"
+}
+
+// hclExpression is a raw HCL expression that is written without quoting
+type hclExpression string
+
+func terraformAttributeName(key string) string {
+	if name, ok := terraformAttributeNames[key]; ok {
+		return name
+	}
+	return toSnakeCase(key)
+}
+
+// hclMap is an object rendered as a map attribute instead of a nested block
+type hclMap orderedObject
+
+// terraformField converts a task definition field into Terraform body entries. Objects
+// become nested blocks, lists of objects become repeated blocks and freeform maps become
+// map attributes.
+func terraformField(field orderedField) []orderedField {
+	name := terraformAttributeName(field.Key)
+
+	switch value := field.Value.(type) {
+	case orderedObject:
+		switch {
+		case terraformMapAttributes[field.Key]:
+			return []orderedField{{name, hclMap(value)}}
+		case field.Key == "host":
+			// The provider flattens the host volume configuration into host_path
+			if sourcePath := fieldValue(value, "sourcePath"); sourcePath != nil {
+				return []orderedField{{"host_path", sourcePath}}
+			}
+			return nil
+		}
+		return []orderedField{{name, terraformBlock(value)}}
+	case []any:
+		if field.Key == "properties" {
+			// Proxy configuration properties are a map in the provider schema
+			properties := hclMap{}
+			for _, item := range value {
+				property := item.(orderedObject)
+				properties = append(properties,
+					orderedField{fieldValue(property, "name").(string), fieldValue(property, "value")})
+			}
+			return []orderedField{{name, properties}}
+		}
+		if len(value) > 0 {
+			if _, isObject := value[0].(orderedObject); isObject {
+				blocks := make([]orderedField, 0, len(value))
+				for _, item := range value {
+					blocks = append(blocks, orderedField{name, terraformBlock(item.(orderedObject))})
+				}
+				return blocks
+			}
+		}
+		return []orderedField{{name, value}}
+	default:
+		return []orderedField{{name, value}}
+	}
+}
+
+func terraformBlock(object orderedObject) orderedObject {
+	block := orderedObject{}
+	for _, field := range object {
+		block = append(block, terraformField(field)...)
+	}
+	return block
+}
+
+// writeTerraformBody writes attributes first and nested blocks after them
+func writeTerraformBody(b *strings.Builder, body orderedObject, indent int) {
+	attributes := orderedObject{}
+	var blocks []orderedField
+	for _, field := range body {
+		if block, ok := field.Value.(orderedObject); ok {
+			blocks = append(blocks, orderedField{field.Key, block})
+			continue
+		}
+		attributes = append(attributes, field)
+	}
+
+	writeHCLAttributes(b, attributes, indent, nil)
+
+	prefix := strings.Repeat("  ", indent)
+	for i, block := range blocks {
+		if i > 0 || len(attributes) > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(b, "%s%s {\n", prefix, block.Key)
+		writeTerraformBody(b, block.Value.(orderedObject), indent+1)
+		b.WriteString(prefix + "}\n")
+	}
+}
+
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func fieldValue(object orderedObject, key string) any {
+	for _, field := range object {
+		if field.Key == key {
+			return field.Value
+		}
+	}
+	return nil
+}
+
+type hclSubstitution func(key string, value any) (string, bool)
+
+// writeHCLAttributes writes the fields of an object as aligned HCL attributes
+func writeHCLAttributes(b *strings.Builder, object orderedObject, indent int, substitute hclSubstitution) {
+	prefix := strings.Repeat("  ", indent)
+
+	for i := 0; i < len(object); {
+		// Align consecutive single-line attributes like terraform fmt does
+		end := i
+		width := 0
+		for end < len(object) && hclInline(object[end].Value) {
+			width = max(width, len(hclKey(object[end].Key)))
+			end++
+		}
+		if end == i {
+			end = i + 1
+			width = len(hclKey(object[i].Key))
+		}
+
+		for _, field := range object[i:end] {
+			key := hclKey(field.Key)
+			fmt.Fprintf(b, "%s%s%s = ", prefix, key, strings.Repeat(" ", width-len(key)))
+			if substitute != nil {
+				if expr, ok := substitute(field.Key, field.Value); ok {
+					b.WriteString(expr + "\n")
+					continue
+				}
+			}
+			writeHCLValue(b, field.Value, indent, substitute)
+			b.WriteByte('\n')
+		}
+		i = end
+	}
+}
+
+// writeHCLValue writes v as an HCL expression
+func writeHCLValue(b *strings.Builder, v any, indent int, substitute hclSubstitution) {
+	prefix := strings.Repeat("  ", indent)
+
+	switch value := v.(type) {
+	case orderedObject:
+		if len(value) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		writeHCLAttributes(b, value, indent+1, substitute)
+		b.WriteString(prefix + "}")
+	case []any:
+		if hclInline(value) {
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				var inner strings.Builder
+				writeHCLValue(&inner, item, indent, substitute)
+				items = append(items, inner.String())
+			}
+			b.WriteString("[" + strings.Join(items, ", ") + "]")
+			return
+		}
+		b.WriteString("[\n")
+		for _, item := range value {
+			b.WriteString(prefix + "  ")
+			writeHCLValue(b, item, indent+1, substitute)
+			b.WriteString(",\n")
+		}
+		b.WriteString(prefix + "]")
+	case hclMap:
+		writeHCLValue(b, orderedObject(value), indent, substitute)
+	case hclExpression:
+		b.WriteString(string(value))
+	case string:
+		b.WriteString(hclString(value))
+	case json.Number:
+		b.WriteString(value.String())
+	case bool:
+		fmt.Fprintf(b, "%t", value)
+	case nil:
+		b.WriteString("null")
+	default:
+		fmt.Fprintf(b, "%v", value)
+	}
+}
+
+// hclInline reports whether v is rendered on a single line
+func hclInline(v any) bool {
+	switch value := v.(type) {
+	case orderedObject:
+		return len(value) == 0
+	case []any:
+		for _, item := range value {
+			if !hclInline(item) {
+				return false
+			}
+		}
+		return true
+	case hclMap:
+		return len(value) == 0
+	case hclExpression:
+		return !strings.Contains(string(value), "\n")
+	default:
+		return true
+	}
+}
+
+func hclKey(key string) string {
+	if hclIdentifier.MatchString(key) {
+		return key
+	}
+	return hclString(key)
+}
+
+func hclString(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+		"${", "$${",
+		"%{", "%%{",
+	)
+	return `"` + replacer.Replace(s) + `"`
+}
@@ -0,0 +1,325 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func newEmitterTestTaskDefinition() *ECSTaskDefinition {
+	return &ECSTaskDefinition{
+		Family:                  "default-web-app",
+		TaskRoleArn:             "arn:aws:iam::123456789012:role/ecsTaskRole",
+		ExecutionRoleArn:        "arn:aws:iam::123456789012:role/ecsTaskExecutionRole",
+		NetworkMode:             "awsvpc",
+		RequiresCompatibilities: []string{"FARGATE"},
+		CPU:                     "256",
+		Memory:                  "512",
+		ContainerDefinitions: []ECSContainerDefinition{
+			{
+				Name:      "nginx",
+				Image:     "nginx:alpine",
+				Essential: true,
+				PortMappings: []ECSPortMapping{
+					{ContainerPort: 80, Protocol: "tcp"},
+				},
+				Environment: []ECSKeyValuePair{
+					{Name: "GREETING", Value: "hello ${USER}"},
+				},
+				LogConfiguration: &ECSLogConfiguration{
+					LogDriver: "awslogs",
+					Options: map[string]string{
+						"awslogs-group":  "/ecs/pods",
+						"awslogs-region": "ap-northeast-1",
+					},
+				},
+			},
+		},
+		Volumes: []ECSVolume{
+			{Name: "data", Host: &ECSHostVolume{SourcePath: "/var/data"}},
+		},
+		Tags: []ECSTag{
+			{Key: "team", Value: "platform"},
+		},
+	}
+}
+
+func TestNewEmitter(t *testing.T) {
+	for _, format := range []string{"json", "terraform", "cloudformation"} {
+		if _, err := NewEmitter(format); err != nil {
+			t.Errorf("NewEmitter(%q) error = %v", format, err)
+		}
+	}
+
+	if _, err := NewEmitter("pulumi"); err == nil {
+		t.Error("NewEmitter(\"pulumi\") should fail for unknown format")
+	}
+}
+
+func TestJSONEmitter_Emit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&JSONEmitter{}).Emit(&buf, newEmitterTestTaskDefinition()); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+
+	var got ECSTaskDefinition
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if got.Family != "default-web-app" {
+		t.Errorf("Family = %v, want default-web-app", got.Family)
+	}
+}
+
+func TestTerraformEmitter_Emit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&TerraformEmitter{}).Emit(&buf, newEmitterTestTaskDefinition()); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+	got := buf.String()
+
+	for _, want := range []string{
+		`variable "execution_role_arn" {`,
+		`default = "arn:aws:iam::123456789012:role/ecsTaskRole"`,
+		`variable "aws_region" {`,
+		`resource "aws_ecs_task_definition" "default_web_app" {`,
+		`family                   = "default-web-app"`,
+		`requires_compatibilities = ["FARGATE"]`,
+		`execution_role_arn       = var.execution_role_arn`,
+		`task_role_arn            = var.task_role_arn`,
+		`container_definitions = jsonencode([`,
+		`awslogs-region = var.aws_region`,
+		`value = "hello $${USER}"`,
+		"  volume {\n    name      = \"data\"\n    host_path = \"/var/data\"\n  }",
+		`team = "platform"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output does not contain %q\n%s", want, got)
+		}
+	}
+}
+
+func TestCloudFormationEmitter_Emit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&CloudFormationEmitter{}).Emit(&buf, newEmitterTestTaskDefinition()); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+
+	var template struct {
+		Parameters map[string]struct {
+			Type    string `yaml:"Type"`
+			Default string `yaml:"Default"`
+		} `yaml:"Parameters"`
+		Resources map[string]struct {
+			Type       string         `yaml:"Type"`
+			Properties map[string]any `yaml:"Properties"`
+		} `yaml:"Resources"`
+	}
+	if err := yaml.Unmarshal(buf.Bytes(), &template); err != nil {
+		t.Fatalf("output is not valid YAML: %v\n%s", err, buf.String())
+	}
+
+	if got := template.Parameters["TaskRoleArn"].Default; got != "arn:aws:iam::123456789012:role/ecsTaskRole" {
+		t.Errorf("TaskRoleArn parameter default = %v", got)
+	}
+	if got := template.Parameters["LogRegion"].Default; got != "ap-northeast-1" {
+		t.Errorf("LogRegion parameter default = %v", got)
+	}
+
+	resource, ok := template.Resources["DefaultWebApp"]
+	if !ok {
+		t.Fatalf("resource DefaultWebApp not found in %v", template.Resources)
+	}
+	if resource.Type != "AWS::ECS::TaskDefinition" {
+		t.Errorf("Type = %v, want AWS::ECS::TaskDefinition", resource.Type)
+	}
+	if got := resource.Properties["Cpu"]; got != "256" {
+		t.Errorf("Cpu = %#v, want string 256", got)
+	}
+	if got := resource.Properties["TaskRoleArn"]; !equalRef(got, "TaskRoleArn") {
+		t.Errorf("TaskRoleArn = %v, want Ref TaskRoleArn", got)
+	}
+
+	containers := resource.Properties["ContainerDefinitions"].([]any)
+	container := containers[0].(map[string]any)
+	options := container["LogConfiguration"].(map[string]any)["Options"].(map[string]any)
+	if options["awslogs-group"] != "/ecs/pods" {
+		t.Errorf("awslogs-group = %v, want /ecs/pods", options["awslogs-group"])
+	}
+	if !equalRef(options["awslogs-region"], "LogRegion") {
+		t.Errorf("awslogs-region = %v, want Ref LogRegion", options["awslogs-region"])
+	}
+	if port := container["PortMappings"].([]any)[0].(map[string]any)["ContainerPort"]; port != 80 {
+		t.Errorf("ContainerPort = %#v, want 80", port)
+	}
+}
+
+func equalRef(v any, name string) bool {
+	ref, ok := v.(map[string]any)
+	return ok && ref["Ref"] == name
+}
+
+func TestComposeEmitter_Emit(t *testing.T) {
+	taskDef := newEmitterTestTaskDefinition()
+	taskDef.ContainerDefinitions[0].Secrets = []ECSSecret{
+		{Name: "DB_PASSWORD", ValueFrom: "/pods/default/secrets/db/password"},
+		{Name: "API_KEY", ValueFrom: "/pods/default/secrets/api/key"},
+	}
+	taskDef.ContainerDefinitions[0].DependsOn = []ECSContainerDependency{
+		{ContainerName: "migrate", Condition: "SUCCESS"},
+	}
+	taskDef.ContainerDefinitions[0].HealthCheck = &ECSHealthCheck{
+		Command:  []string{"CMD-SHELL", "curl -f http://localhost/ || exit 1"},
+		Interval: 30,
+		Retries:  3,
+	}
+	taskDef.ContainerDefinitions[0].LinuxParameters = &ECSLinuxParameters{
+		Tmpfs: []ECSTmpfs{{ContainerPath: "/tmp", Size: 64}},
+	}
+	taskDef.ContainerDefinitions = append(taskDef.ContainerDefinitions, ECSContainerDefinition{
+		Name:  "migrate",
+		Image: "migrate:latest",
+	})
+	taskDef.ContainerDefinitions[0].MountPoints = []ECSMountPoint{
+		{SourceVolume: "data", ContainerPath: "/data", ReadOnly: true},
+	}
+
+	emitter := &ComposeEmitter{
+		Secrets: map[string]string{"/pods/default/secrets/db/password": "s3cr$t"},
+	}
+
+	var buf bytes.Buffer
+	if err := emitter.Emit(&buf, taskDef); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+
+	var compose composeFile
+	if err := yaml.Unmarshal(buf.Bytes(), &compose); err != nil {
+		t.Fatalf("output is not valid YAML: %v\n%s", err, buf.String())
+	}
+
+	pause, ok := compose.Services["pause"]
+	if !ok {
+		t.Fatalf("pause service missing:\n%s", buf.String())
+	}
+	if len(pause.Ports) != 1 || pause.Ports[0] != "80:80" {
+		t.Errorf("pause ports = %v, want [80:80]", pause.Ports)
+	}
+
+	nginx := compose.Services["nginx"]
+	if nginx.NetworkMode != "service:pause" {
+		t.Errorf("network_mode = %v, want service:pause", nginx.NetworkMode)
+	}
+	if len(nginx.Ports) != 0 {
+		t.Errorf("nginx should not publish ports, got %v", nginx.Ports)
+	}
+	if got := nginx.DependsOn["migrate"].Condition; got != "service_completed_successfully" {
+		t.Errorf("depends_on migrate = %v, want service_completed_successfully", got)
+	}
+	if got := nginx.Environment["DB_PASSWORD"]; got != "s3cr$$t" {
+		t.Errorf("DB_PASSWORD = %v, want s3cr$$t", got)
+	}
+	if got := nginx.Environment["API_KEY"]; got != "${API_KEY}" {
+		t.Errorf("API_KEY = %v, want ${API_KEY}", got)
+	}
+	if got := nginx.Environment["GREETING"]; got != "hello $${USER}" {
+		t.Errorf("GREETING = %v, want escaped value", got)
+	}
+	if nginx.Healthcheck == nil || nginx.Healthcheck.Interval != "30s" {
+		t.Errorf("healthcheck = %+v, want interval 30s", nginx.Healthcheck)
+	}
+	if len(nginx.Tmpfs) != 1 || nginx.Tmpfs[0] != "/tmp:size=64m" {
+		t.Errorf("tmpfs = %v, want [/tmp:size=64m]", nginx.Tmpfs)
+	}
+	if len(nginx.Volumes) != 1 || nginx.Volumes[0] != "/var/data:/data:ro" {
+		t.Errorf("volumes = %v, want [/var/data:/data:ro]", nginx.Volumes)
+	}
+}
+
+func TestComposeEmitter_DependsOnConditions(t *testing.T) {
+	tests := []struct {
+		condition     string
+		wantCondition string
+		wantWarning   bool
+	}{
+		{condition: "SUCCESS", wantCondition: "service_completed_successfully"},
+		{condition: "COMPLETE", wantCondition: "service_completed_successfully", wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			taskDef := newEmitterTestTaskDefinition()
+			taskDef.ContainerDefinitions[0].DependsOn = []ECSContainerDependency{
+				{ContainerName: "migrate", Condition: tt.condition},
+			}
+			taskDef.ContainerDefinitions = append(taskDef.ContainerDefinitions, ECSContainerDefinition{
+				Name:  "migrate",
+				Image: "migrate:latest",
+			})
+
+			emitter := &ComposeEmitter{}
+			var buf bytes.Buffer
+			if err := emitter.Emit(&buf, taskDef); err != nil {
+				t.Fatalf("Emit() error = %v", err)
+			}
+
+			var compose composeFile
+			if err := yaml.Unmarshal(buf.Bytes(), &compose); err != nil {
+				t.Fatalf("output is not valid YAML: %v\n%s", err, buf.String())
+			}
+			if got := compose.Services["nginx"].DependsOn["migrate"].Condition; got != tt.wantCondition {
+				t.Errorf("depends_on migrate = %v, want %v", got, tt.wantCondition)
+			}
+
+			diagnostics := emitter.Diagnostics()
+			if !tt.wantWarning {
+				if len(diagnostics) != 0 {
+					t.Errorf("Diagnostics() = %v, want none", diagnostics)
+				}
+				return
+			}
+			if len(diagnostics) != 1 {
+				t.Fatalf("Diagnostics() = %v, want one warning", diagnostics)
+			}
+			want := Diagnostic{
+				Severity: SeverityWarning,
+				Code:     DiagLossyMapping,
+				Field:    "containerDefinitions[0].dependsOn[0].condition",
+			}
+			if got := diagnostics[0]; got.Severity != want.Severity || got.Code != want.Code || got.Field != want.Field {
+				t.Errorf("Diagnostics()[0] = %v, want %s [%s] %s", got, want.Severity, want.Code, want.Field)
+			}
+		})
+	}
+}
+
+func TestLoadSecretsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.env")
+	content := "# local secrets\n/pods/default/secrets/db/password=\"p=ss\"\n\narn:aws:secretsmanager:us-east-1:123456789012:secret:api=abc\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	secrets, err := LoadSecretsFile(path)
+	if err != nil {
+		t.Fatalf("LoadSecretsFile() error = %v", err)
+	}
+	if got := secrets["/pods/default/secrets/db/password"]; got != "p=ss" {
+		t.Errorf("password = %q, want p=ss", got)
+	}
+	if got := secrets["arn:aws:secretsmanager:us-east-1:123456789012:secret:api"]; got != "abc" {
+		t.Errorf("api = %q, want abc", got)
+	}
+
+	if err := os.WriteFile(path, []byte("invalid line\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSecretsFile(path); err == nil {
+		t.Error("LoadSecretsFile() should fail for lines without '='")
+	}
+}
@@ -0,0 +1,203 @@
+package ecs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultFamilyTemplate names families after the namespace and the owning workload
+	DefaultFamilyTemplate = "{{.Namespace}}-{{.OwnerName}}"
+
+	// podTemplateHashLabel is set by the Deployment controller on ReplicaSets and their pods
+	podTemplateHashLabel = "pod-template-hash"
+
+	familyHashLength = 8
+)
+
+var (
+	familyInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+	familyRepeatedDash = regexp.MustCompile(`-{2,}`)
+)
+
+// FamilyTemplateData is the data available to ConversionOptions.FamilyTemplate
+type FamilyTemplateData struct {
+	// Namespace is the pod namespace
+	Namespace string
+	// Name is the pod name
+	Name string
+	// OwnerKind is the kind of the owning workload, or "Pod" for bare pods.
+	// Pods of a Deployment report the Deployment rather than its ReplicaSet.
+	OwnerKind string
+	// OwnerName is the name of the owning workload, or the pod name for bare pods
+	OwnerName string
+	// Container0 is the name of the first container
+	Container0 string
+	// Labels are the pod labels
+	Labels map[string]string
+}
+
+// ownerFields are the template fields that together identify the owning workload
+var ownerFields = []string{"Namespace", "OwnerKind", "OwnerName"}
+
+// ownerDelimiter separates the owner fields in templates that identify the owner. It is valid
+// in family names but not in the names of namespaces, kinds and workloads.
+const ownerDelimiter = "_"
+
+// NewFamilyTemplateData collects the template data for a pod. ReplicaSets created by a
+// Deployment are collapsed to the Deployment so that all replicas share one family.
+func NewFamilyTemplateData(pod *corev1.Pod, namespace string) FamilyTemplateData {
+	data := FamilyTemplateData{
+		Namespace: namespace,
+		Name:      pod.Name,
+		OwnerKind: "Pod",
+		OwnerName: pod.Name,
+		Labels:    pod.Labels,
+	}
+	if len(pod.Spec.Containers) > 0 {
+		data.Container0 = pod.Spec.Containers[0].Name
+	}
+	if data.OwnerName == "" {
+		data.OwnerName = pod.GenerateName
+	}
+
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		data.OwnerKind = owner.Kind
+		data.OwnerName = owner.Name
+		if owner.Kind == "ReplicaSet" {
+			if hash := pod.Labels[podTemplateHashLabel]; hash != "" {
+				if deployment, ok := strings.CutSuffix(owner.Name, "-"+hash); ok && deployment != "" {
+					data.OwnerKind = "Deployment"
+					data.OwnerName = deployment
+				}
+			}
+		}
+	}
+
+	return data
+}
+
+// FamilyName renders the family template for a pod and sanitizes the result. Unless the
+// template identifies the owner (see identifiesOwner) and the rendered name needed no
+// sanitizing, distinct workloads may render to the same family, so a short hash of the owner
+// is appended. The result depends only on the pod, never on previously converted pods.
+func (c *Converter) FamilyName(pod *corev1.Pod, namespace string) (string, error) {
+	if c.familyTemplateErr != nil {
+		return "", c.familyTemplateErr
+	}
+	data := NewFamilyTemplateData(pod, namespace)
+
+	var rendered bytes.Buffer
+	if err := c.familyTemplate.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf("failed to render family template: %w", err)
+	}
+
+	family := SanitizeFamily(rendered.String())
+	if c.familyIdentifiesOwner && family == rendered.String() && !containsOwnerDelimiter(data) {
+		return family, nil
+	}
+
+	suffix := "-" + shortHash(fmt.Sprintf("%s/%s/%s", data.Namespace, data.OwnerKind, data.OwnerName))
+	return truncateRunes(family, MaxNameLength-len(suffix)) + suffix, nil
+}
+
+// SanitizeFamily rewrites name to satisfy the ECS family rules: runs of characters other
+// than letters, digits, hyphens and underscores become a single hyphen, and names longer
+// than 255 characters are shortened with a hash suffix
+func SanitizeFamily(name string) string {
+	family := familyInvalidChars.ReplaceAllString(name, "-")
+	family = familyRepeatedDash.ReplaceAllString(family, "-")
+	family = strings.Trim(family, "-")
+	if family == "" {
+		return "task"
+	}
+
+	if len(family) > MaxNameLength {
+		suffix := "-" + shortHash(name)
+		family = strings.TrimRight(family[:MaxNameLength-len(suffix)], "-") + suffix
+	}
+	return family
+}
+
+// containsOwnerDelimiter reports whether an owner field contains ownerDelimiter, which
+// only names of custom resources outside the Kubernetes naming rules do
+func containsOwnerDelimiter(data FamilyTemplateData) bool {
+	return strings.Contains(data.Namespace, ownerDelimiter) ||
+		strings.Contains(data.OwnerKind, ownerDelimiter) ||
+		strings.Contains(data.OwnerName, ownerDelimiter)
+}
+
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:familyHashLength]
+}
+
+// identifiesOwner reports whether every rendering of the template names a single owner: the
+// template consists only of text and the fields in ownerFields, renders each of them
+// unconditionally, and separates them with text containing ownerDelimiter. Kubernetes names
+// never contain the delimiter, so the rendered name splits back into one namespace, kind and
+// name. Fields inside if, range or with, or other fields and functions, may render the same
+// name for distinct owners.
+func identifiesOwner(tmpl *template.Template) bool {
+	used := map[string]bool{}
+	delimited := true
+	for _, node := range tmpl.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			if bytes.Contains(node.Text, []byte(ownerDelimiter)) {
+				delimited = true
+			}
+		case *parse.ActionNode:
+			field := ownerField(node.Pipe)
+			if field == "" || !delimited {
+				return false
+			}
+			used[field] = true
+			delimited = false
+		case *parse.CommentNode:
+		default:
+			return false
+		}
+	}
+
+	for _, field := range ownerFields {
+		if !used[field] {
+			return false
+		}
+	}
+	return true
+}
+
+// ownerField returns the name of the owner field a pipeline prints as is, such as
+// {{.OwnerName}}, or "" for any other pipeline
+func ownerField(pipe *parse.PipeNode) string {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return ""
+	}
+	field, ok := pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok || len(field.Ident) != 1 || !slices.Contains(ownerFields, field.Ident[0]) {
+		return ""
+	}
+	return field.Ident[0]
+}
+
+func parseFamilyTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultFamilyTemplate
+	}
+	tmpl, err := template.New("family").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid family template %q: %w", text, err)
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,206 @@
+package ecs
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newFamilyTestPod(name string, owner *metav1.OwnerReference, labels map[string]string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "shop",
+			Labels:    labels,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "web", Image: "web:1.0"}},
+		},
+	}
+	if owner != nil {
+		controller := true
+		owner.Controller = &controller
+		pod.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return pod
+}
+
+// ownerSuffix is the hash FamilyName appends for an owner in the shop namespace
+func ownerSuffix(kind, name string) string {
+	return "-" + shortHash("shop/"+kind+"/"+name)
+}
+
+func TestConverter_FamilyName(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		pod      *corev1.Pod
+		want     string
+	}{
+		{
+			name: "bare pod",
+			pod:  newFamilyTestPod("debug", nil, nil),
+			want: "shop-debug" + ownerSuffix("Pod", "debug"),
+		},
+		{
+			name: "deployment replica collapses to the deployment",
+			pod: newFamilyTestPod("frontend-7d9f8b6c5-x2k4p",
+				&metav1.OwnerReference{Kind: "ReplicaSet", Name: "frontend-7d9f8b6c5"},
+				map[string]string{"pod-template-hash": "7d9f8b6c5"}),
+			want: "shop-frontend" + ownerSuffix("Deployment", "frontend"),
+		},
+		{
+			name: "standalone replicaset keeps its name",
+			pod: newFamilyTestPod("cache-abcde",
+				&metav1.OwnerReference{Kind: "ReplicaSet", Name: "cache"}, nil),
+			want: "shop-cache" + ownerSuffix("ReplicaSet", "cache"),
+		},
+		{
+			name: "statefulset",
+			pod:  newFamilyTestPod("db-0", &metav1.OwnerReference{Kind: "StatefulSet", Name: "db"}, nil),
+			want: "shop-db" + ownerSuffix("StatefulSet", "db"),
+		},
+		{
+			name:     "template identifying the owner is used as is",
+			template: "{{.Namespace}}_{{.OwnerKind}}_{{.OwnerName}}",
+			pod:      newFamilyTestPod("db-0", &metav1.OwnerReference{Kind: "StatefulSet", Name: "db"}, nil),
+			want:     "shop_StatefulSet_db",
+		},
+		{
+			name:     "owner fields separated by hyphens are hashed",
+			template: "{{.Namespace}}-{{.OwnerKind}}-{{.OwnerName}}",
+			pod:      newFamilyTestPod("db-0", &metav1.OwnerReference{Kind: "StatefulSet", Name: "db"}, nil),
+			want:     "shop-StatefulSet-db" + ownerSuffix("StatefulSet", "db"),
+		},
+		{
+			name:     "owner fields without a delimiter are hashed",
+			template: "{{.Namespace}}{{.OwnerKind}}_{{.OwnerName}}",
+			pod:      newFamilyTestPod("db-0", &metav1.OwnerReference{Kind: "StatefulSet", Name: "db"}, nil),
+			want:     "shopStatefulSet_db" + ownerSuffix("StatefulSet", "db"),
+		},
+		{
+			name: "owner fields inside a conditional are hashed",
+			template: "{{.Namespace}}_{{if eq .OwnerKind \"Deployment\"}}{{.OwnerName}}" +
+				"{{else}}{{.OwnerKind}}_{{.OwnerName}}{{end}}",
+			pod:  newFamilyTestPod("db-0", &metav1.OwnerReference{Kind: "StatefulSet", Name: "db"}, nil),
+			want: "shop_StatefulSet_db" + ownerSuffix("StatefulSet", "db"),
+		},
+		{
+			name:     "sanitized owner is hashed",
+			template: "{{.Namespace}}_{{.OwnerKind}}_{{.OwnerName}}",
+			pod:      newFamilyTestPod("db.v2-0", &metav1.OwnerReference{Kind: "StatefulSet", Name: "db.v2"}, nil),
+			want:     "shop_StatefulSet_db-v2" + ownerSuffix("StatefulSet", "db.v2"),
+		},
+		{
+			name:     "owner name containing the delimiter is hashed",
+			template: "{{.Namespace}}_{{.OwnerKind}}_{{.OwnerName}}",
+			pod:      newFamilyTestPod("nightly_run-0", &metav1.OwnerReference{Kind: "Workflow", Name: "nightly_run"}, nil),
+			want:     "shop_Workflow_nightly_run" + ownerSuffix("Workflow", "nightly_run"),
+		},
+		{
+			name:     "custom template is sanitized",
+			template: "{{.Namespace}}/{{.OwnerKind}}.{{.Container0}}--{{index .Labels \"tier\"}}",
+			pod:      newFamilyTestPod("debug", nil, map[string]string{"tier": "front end"}),
+			want:     "shop-Pod-web-front-end" + ownerSuffix("Pod", "debug"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := NewConverter(ConversionOptions{FamilyTemplate: tt.template})
+			got, err := converter.FamilyName(tt.pod, tt.pod.Namespace)
+			if err != nil {
+				t.Fatalf("FamilyName() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("FamilyName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConverter_FamilyName_Collisions(t *testing.T) {
+	replicaSet := &metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-5f6d7c8b9"}
+	labels := map[string]string{"pod-template-hash": "5f6d7c8b9"}
+
+	converter := NewConverter(ConversionOptions{})
+	first, err := converter.FamilyName(newFamilyTestPod("web-5f6d7c8b9-aaaaa", replicaSet, labels), "shop")
+	if err != nil {
+		t.Fatalf("FamilyName() error = %v", err)
+	}
+	second, err := converter.FamilyName(newFamilyTestPod("web-5f6d7c8b9-bbbbb", replicaSet, labels), "shop")
+	if err != nil {
+		t.Fatalf("FamilyName() error = %v", err)
+	}
+	if second != first {
+		t.Errorf("replicas got families %q and %q, want one family", first, second)
+	}
+
+	// A bare pod named like the deployment renders to the same template output
+	bare, err := converter.FamilyName(newFamilyTestPod("web", nil, nil), "shop")
+	if err != nil {
+		t.Fatalf("FamilyName() error = %v", err)
+	}
+	if bare == first || !strings.HasPrefix(bare, "shop-web-") || len(bare) != len("shop-web-")+familyHashLength {
+		t.Errorf("colliding family = %q, want shop-web-<hash> distinct from %q", bare, first)
+	}
+
+	// Families do not depend on the order pods are converted in, e.g. after a restart
+	restarted := NewConverter(ConversionOptions{})
+	again, err := restarted.FamilyName(newFamilyTestPod("web", nil, nil), "shop")
+	if err != nil {
+		t.Fatalf("FamilyName() error = %v", err)
+	}
+	if again != bare {
+		t.Errorf("family depends on conversion order: %q != %q", again, bare)
+	}
+}
+
+func TestConverter_FamilyFromTemplate(t *testing.T) {
+	pod := newFamilyTestPod("debug", nil, nil)
+
+	converter := NewConverter(ConversionOptions{})
+	taskDef, err := converter.ConvertPod(pod, &pod.Spec, &ECSConfig{}, pod.Namespace)
+	if err != nil {
+		t.Fatalf("ConvertPod() error = %v", err)
+	}
+	if want := "shop-debug" + ownerSuffix("Pod", "debug"); taskDef.Family != want {
+		t.Errorf("Family = %q, want %q", taskDef.Family, want)
+	}
+
+	taskDef, err = converter.ConvertPod(pod, &pod.Spec, &ECSConfig{Family: "explicit"}, pod.Namespace)
+	if err != nil {
+		t.Fatalf("ConvertPod() error = %v", err)
+	}
+	if taskDef.Family != "explicit" {
+		t.Errorf("Family = %q, want explicit family to win", taskDef.Family)
+	}
+
+	invalid := NewConverter(ConversionOptions{FamilyTemplate: "{{.Namespace"})
+	if _, err := invalid.ConvertPod(pod, &pod.Spec, &ECSConfig{}, pod.Namespace); err == nil {
+		t.Error("ConvertPod() should fail for an invalid family template")
+	}
+}
+
+func TestSanitizeFamily(t *testing.T) {
+	long := strings.Repeat("a", 300)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"web-app_1", "web-app_1"},
+		{"shop/web.app", "shop-web-app"},
+		{"--a  b--", "a-b"},
+		{"...", "task"},
+		{long, strings.Repeat("a", MaxNameLength-familyHashLength-1) + "-" + shortHash(long)},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeFamily(tt.name); got != tt.want {
+			t.Errorf("SanitizeFamily(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package ecs
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// MetadataSource selects the pod metadata a MetadataRule reads
+type MetadataSource string
+
+const (
+	// MetadataSourceLabel reads pod labels
+	MetadataSourceLabel MetadataSource = "label"
+	// MetadataSourceAnnotation reads pod annotations
+	MetadataSourceAnnotation MetadataSource = "annotation"
+)
+
+// MetadataTarget selects where a MetadataRule writes matching metadata
+type MetadataTarget string
+
+const (
+	// MetadataTargetTag writes task definition tags
+	MetadataTargetTag MetadataTarget = "tag"
+	// MetadataTargetDockerLabel writes dockerLabels on every container
+	MetadataTargetDockerLabel MetadataTarget = "dockerLabel"
+)
+
+// Tag limits enforced by ECS
+const (
+	MaxTagsPerResource = 50
+	MaxTagKeyLength    = 128
+	MaxTagValueLength  = 256
+)
+
+// tagInvalidChars matches characters that are not allowed in tag keys and values
+var tagInvalidChars = regexp.MustCompile(`[^\p{L}\p{Z}\p{N}_.:/=+\-@]`)
+
+// MetadataRule propagates selected pod labels or annotations to the task definition
+type MetadataRule struct {
+	// Source is the metadata the rule reads (default: label)
+	Source MetadataSource `json:"source,omitempty"`
+
+	// Keys selects metadata keys. An entry ending in "*" matches every key with that prefix.
+	Keys []string `json:"keys"`
+
+	// TrimPrefix is removed from matching keys before they are written
+	TrimPrefix string `json:"trimPrefix,omitempty"`
+
+	// KeyPrefix is prepended to the resulting keys
+	KeyPrefix string `json:"keyPrefix,omitempty"`
+
+	// Targets lists where matching metadata is written (default: tag)
+	Targets []MetadataTarget `json:"targets,omitempty"`
+}
+
+// matches reports whether key is selected by the rule
+func (r *MetadataRule) matches(key string) bool {
+	for _, pattern := range r.Keys {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// rewrite returns the key written to the targets
+func (r *MetadataRule) rewrite(key string) string {
+	return r.KeyPrefix + strings.TrimPrefix(key, r.TrimPrefix)
+}
+
+func (r *MetadataRule) source(pod *corev1.Pod) map[string]string {
+	if r.Source == MetadataSourceAnnotation {
+		return pod.Annotations
+	}
+	return pod.Labels
+}
+
+// field returns the path of a metadata key of the rule's source in diagnostics
+func (r *MetadataRule) field(key string) string {
+	if r.Source == MetadataSourceAnnotation {
+		return fmt.Sprintf("metadata.annotations[%s]", key)
+	}
+	return fmt.Sprintf("metadata.labels[%s]", key)
+}
+
+func (r *MetadataRule) targets() []MetadataTarget {
+	if len(r.Targets) == 0 {
+		return []MetadataTarget{MetadataTargetTag}
+	}
+	return r.Targets
+}
+
+// applyMetadata builds the task definition tags from the metadata rules and ECSConfig.Tags,
+// and adds docker labels to the containers. Explicit ECSConfig tags take precedence over
+// propagated ones, and later rules take precedence over earlier ones. Propagated and explicit
+// tags are sanitized alike; keys that end up the same are reported as lossy mappings.
+func (c *Converter) applyMetadata(
+	pod *corev1.Pod,
+	ecsConfig *ECSConfig,
+	taskDef *ECSTaskDefinition,
+) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+	tags := map[string]string{}
+	dockerLabels := map[string]string{}
+	// origins remembers the metadata key each propagated tag was written from
+	origins := map[string]string{}
+
+	if pod != nil {
+		for i := range c.options.MetadataRules {
+			rule := &c.options.MetadataRules[i]
+			source := rule.source(pod)
+			for _, key := range slices.Sorted(maps.Keys(source)) {
+				if !rule.matches(key) {
+					continue
+				}
+				name := rule.rewrite(key)
+				for _, target := range rule.targets() {
+					switch target {
+					case MetadataTargetTag:
+						tagKey, field := SanitizeTagKey(name), rule.field(key)
+						if origin, ok := origins[tagKey]; ok && origin != field {
+							diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, DiagLossyMapping, field,
+								"overwrites tag %q propagated from %s", tagKey, origin))
+						}
+						origins[tagKey] = field
+						tags[tagKey] = SanitizeTagValue(source[key])
+					case MetadataTargetDockerLabel:
+						dockerLabels[name] = source[key]
+					default:
+						return nil, fmt.Errorf("unknown metadata target %q", target)
+					}
+				}
+			}
+		}
+	}
+
+	explicit := map[string]string{}
+	for _, key := range slices.Sorted(maps.Keys(ecsConfig.Tags)) {
+		value := ecsConfig.Tags[key]
+		tagKey, tagValue := SanitizeTagKey(key), SanitizeTagValue(value)
+		field := fmt.Sprintf("tags[%s]", key)
+		if tagKey != key || tagValue != value {
+			diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, DiagLossyMapping, field,
+				"written as tag %q=%q to satisfy the ECS tag rules", tagKey, tagValue))
+		}
+		if other, ok := explicit[tagKey]; ok {
+			diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, DiagLossyMapping, field,
+				"overwrites tag %q set by tags[%s]", tagKey, other))
+		}
+		explicit[tagKey] = key
+		tags[tagKey] = tagValue
+	}
+
+	if len(tags) > MaxTagsPerResource {
+		return nil, fmt.Errorf("task definition would have %d tags, exceeding the limit of %d",
+			len(tags), MaxTagsPerResource)
+	}
+	if len(tags) > 0 {
+		taskDef.Tags = make([]ECSTag, 0, len(tags))
+		for _, key := range slices.Sorted(maps.Keys(tags)) {
+			taskDef.Tags = append(taskDef.Tags, ECSTag{Key: key, Value: tags[key]})
+		}
+	}
+
+	if len(dockerLabels) > 0 {
+		for i := range taskDef.ContainerDefinitions {
+			container := &taskDef.ContainerDefinitions[i]
+			if container.DockerLabels == nil {
+				container.DockerLabels = map[string]string{}
+			}
+			for key, value := range dockerLabels {
+				if _, exists := container.DockerLabels[key]; !exists {
+					container.DockerLabels[key] = value
+				}
+			}
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// SanitizeTagKey rewrites a key to satisfy the ECS tag key rules: disallowed characters
+// become "_", the reserved "aws:" prefix is escaped and the key is truncated to 128 characters
+func SanitizeTagKey(key string) string {
+	key = tagInvalidChars.ReplaceAllString(key, "_")
+	if strings.HasPrefix(strings.ToLower(key), "aws:") {
+		key = key[:3] + "_" + key[4:]
+	}
+	return truncateRunes(key, MaxTagKeyLength)
+}
+
+// SanitizeTagValue rewrites a value to satisfy the ECS tag value rules
+func SanitizeTagValue(value string) string {
+	return truncateRunes(tagInvalidChars.ReplaceAllString(value, "_"), MaxTagValueLength)
+}
+
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
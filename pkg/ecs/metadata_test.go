@@ -0,0 +1,205 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConverter_MetadataRules(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+			Labels: map[string]string{
+				"app":                    "web",
+				"team":                   "platform",
+				"tier":                   "frontend",
+				"cost.example.com/owner": "alice (billing)",
+			},
+			Annotations: map[string]string{
+				"billing.example.com/cost-center": "cc-1234",
+				"kubectl.kubernetes.io/restart":   "2024-05-01",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "app:1.0"}},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		rules            []MetadataRule
+		configTags       map[string]string
+		wantTags         []ECSTag
+		wantDockerLabels map[string]string
+		wantErr          string
+	}{
+		{
+			name:       "no rules keeps only config tags, sorted",
+			configTags: map[string]string{"env": "prod", "app": "override", "cost": "shared"},
+			wantTags: []ECSTag{
+				{Key: "app", Value: "override"},
+				{Key: "cost", Value: "shared"},
+				{Key: "env", Value: "prod"},
+			},
+		},
+		{
+			name: "labels to tags with config precedence",
+			rules: []MetadataRule{
+				{Keys: []string{"team", "app"}},
+			},
+			configTags: map[string]string{"app": "override"},
+			wantTags: []ECSTag{
+				{Key: "app", Value: "override"},
+				{Key: "team", Value: "platform"},
+			},
+		},
+		{
+			name: "prefix match with key rewriting and sanitization",
+			rules: []MetadataRule{
+				{Keys: []string{"cost.example.com/*"}, TrimPrefix: "cost.example.com/", KeyPrefix: "cost:"},
+				{
+					Source:     MetadataSourceAnnotation,
+					Keys:       []string{"billing.example.com/*"},
+					TrimPrefix: "billing.example.com/",
+				},
+			},
+			wantTags: []ECSTag{
+				{Key: "cost-center", Value: "cc-1234"},
+				{Key: "cost:owner", Value: "alice _billing_"},
+			},
+		},
+		{
+			name: "docker labels only",
+			rules: []MetadataRule{
+				{Keys: []string{"app", "tier"}, Targets: []MetadataTarget{MetadataTargetDockerLabel}},
+			},
+			wantDockerLabels: map[string]string{"app": "web", "tier": "frontend"},
+		},
+		{
+			name: "tags and docker labels",
+			rules: []MetadataRule{
+				{Keys: []string{"team"}, Targets: []MetadataTarget{MetadataTargetTag, MetadataTargetDockerLabel}},
+			},
+			wantTags:         []ECSTag{{Key: "team", Value: "platform"}},
+			wantDockerLabels: map[string]string{"team": "platform"},
+		},
+		{
+			name:       "tag limit",
+			rules:      []MetadataRule{{Keys: []string{"*"}}},
+			configTags: manyTags(48),
+			wantErr:    "exceeding the limit of 50",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := NewConverter(ConversionOptions{MetadataRules: tt.rules})
+			config := &ECSConfig{Family: "web", Tags: tt.configTags}
+
+			// Convert several times to make sure the output does not depend on map order
+			var first *ECSTaskDefinition
+			for i := 0; i < 5; i++ {
+				taskDef, err := converter.ConvertPod(pod, &pod.Spec, config, pod.Namespace)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("ConvertPod() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("ConvertPod() error = %v", err)
+				}
+				if first == nil {
+					first = taskDef
+				} else if !reflect.DeepEqual(first.Tags, taskDef.Tags) {
+					t.Fatalf("tags are not deterministic: %v != %v", first.Tags, taskDef.Tags)
+				}
+			}
+
+			if !reflect.DeepEqual(first.Tags, tt.wantTags) {
+				t.Errorf("Tags = %v, want %v", first.Tags, tt.wantTags)
+			}
+			if got := first.ContainerDefinitions[0].DockerLabels; !reflect.DeepEqual(got, tt.wantDockerLabels) {
+				t.Errorf("DockerLabels = %v, want %v", got, tt.wantDockerLabels)
+			}
+		})
+	}
+}
+
+func TestConverter_MetadataDiagnostics(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+			Labels:    map[string]string{"team#": "platform", "team$": "billing"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "app:1.0"}},
+		},
+	}
+	converter := NewConverter(ConversionOptions{MetadataRules: []MetadataRule{{Keys: []string{"team*"}}}})
+	config := &ECSConfig{
+		Family: "web",
+		Tags:   map[string]string{"aws:owner": "alice", "aws_owner": "bob", "env": strings.Repeat("x", 300)},
+	}
+
+	taskDef, diagnostics, err := converter.ConvertPodWithDiagnostics(context.Background(), pod, &pod.Spec, config,
+		pod.Namespace)
+	if err != nil {
+		t.Fatalf("ConvertPodWithDiagnostics() error = %v", err)
+	}
+	wantTags := []ECSTag{
+		{Key: "aws_owner", Value: "bob"},
+		{Key: "env", Value: strings.Repeat("x", MaxTagValueLength)},
+		{Key: "team_", Value: "billing"},
+	}
+	if !reflect.DeepEqual(taskDef.Tags, wantTags) {
+		t.Errorf("Tags = %v, want %v", taskDef.Tags, wantTags)
+	}
+
+	var got []string
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Code == DiagLossyMapping {
+			got = append(got, diagnostic.Field)
+		}
+	}
+	want := []string{"metadata.labels[team$]", "tags[aws:owner]", "tags[aws_owner]", "tags[env]"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lossy mappings = %v, want %v (diagnostics %v)", got, want, diagnostics)
+	}
+}
+
+func manyTags(n int) map[string]string {
+	tags := map[string]string{}
+	for i := 0; i < n; i++ {
+		tags[fmt.Sprintf("tag-%02d", i)] = "value"
+	}
+	return tags
+}
+
+func TestSanitizeTagKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"app.kubernetes.io/name", "app.kubernetes.io/name"},
+		{"team name", "team name"},
+		{"owner#1", "owner_1"},
+		{"aws:cloudformation:stack-name", "aws_cloudformation:stack-name"},
+		{"AWS:reserved", "AWS_reserved"},
+		{strings.Repeat("k", 200), strings.Repeat("k", MaxTagKeyLength)},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeTagKey(tt.key); got != tt.want {
+			t.Errorf("SanitizeTagKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// ProfileConfigAPIVersion is the apiVersion of the converter configuration file
+	ProfileConfigAPIVersion = "ecs.takutakahashi.dev/v1alpha1"
+	// ProfileConfigKind is the kind of the converter configuration file
+	ProfileConfigKind = "ConverterConfig"
+)
+
+// SecretBackend selects where Kubernetes Secret values are stored for ECS
+type SecretBackend string
+
+const (
+	// SecretBackendParameterStore references Secret values as SSM Parameter Store parameters
+	SecretBackendParameterStore SecretBackend = "ssm"
+	// SecretBackendSecretsManager references Secret values as keys of Secrets Manager secrets
+	SecretBackendSecretsManager SecretBackend = "secretsmanager"
+)
+
+var (
+	regionPattern    = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+	accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+)
+
+// ProfileConfig is the versioned converter configuration file holding named profiles
+type ProfileConfig struct {
+	APIVersion     string             `json:"apiVersion"`
+	Kind           string             `json:"kind"`
+	DefaultProfile string             `json:"defaultProfile,omitempty"`
+	Profiles       map[string]Profile `json:"profiles"`
+}
+
+// Profile holds the conversion settings of one environment
+type Profile struct {
+	Region                  string              `json:"region,omitempty"`
+	AccountID               string              `json:"accountId,omitempty"`
+	Logging                 ProfileLogging      `json:"logging,omitempty"`
+	Secrets                 ProfileSecrets      `json:"secrets,omitempty"`
+	ExecutionRoleArn        string              `json:"executionRoleArn,omitempty"`
+	TaskRoleArn             string              `json:"taskRoleArn,omitempty"`
+	CPU                     string              `json:"cpu,omitempty"`
+	Memory                  string              `json:"memory,omitempty"`
+	FamilyTemplate          string              `json:"familyTemplate,omitempty"`
+	SkipUnsupportedFeatures *bool               `json:"skipUnsupportedFeatures,omitempty"`
+	ServiceAccountRoles     ServiceAccountRoles `json:"serviceAccountRoles,omitempty"`
+	MetadataRules           []MetadataRule      `json:"metadataRules,omitempty"`
+	Run                     ProfileRun          `json:"run,omitempty"`
+	// Namespaces holds the settings of the pods of a namespace, keyed by its name; the entry
+	// "*" applies to namespaces without their own
+	Namespaces map[string]NamespaceProfile `json:"namespaces,omitempty"`
+}
+
+// NamespaceProfile holds the settings of the pods of a namespace
+type NamespaceProfile struct {
+	// Requests fill the CPU and memory requests missing from the containers
+	Requests corev1.ResourceList `json:"requests,omitempty"`
+}
+
+// ProfileLogging configures the default log configuration of the containers
+type ProfileLogging struct {
+	Driver  string            `json:"driver,omitempty"`
+	Group   string            `json:"group,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// ProfileSecrets configures how Secret and ConfigMap references are mapped
+type ProfileSecrets struct {
+	Backend SecretBackend `json:"backend,omitempty"`
+	Prefix  string        `json:"prefix,omitempty"`
+}
+
+// ProfileRun selects where the tasks of converted task definitions are started, by pod-to-ecs
+// -run and by the controller
+type ProfileRun struct {
+	Cluster    string `json:"cluster,omitempty"`
+	LaunchType string `json:"launchType,omitempty"`
+	// Subnets, SecurityGroups and AssignPublicIP form the network configuration of awsvpc tasks
+	Subnets        []string `json:"subnets,omitempty"`
+	SecurityGroups []string `json:"securityGroups,omitempty"`
+	AssignPublicIP bool     `json:"assignPublicIp,omitempty"`
+}
+
+// LoadProfileConfig reads a converter configuration file
+func LoadProfileConfig(path string) (*ProfileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseProfileConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseProfileConfig parses a YAML or JSON converter configuration. Unknown fields are
+// rejected so that typos do not silently fall back to defaults.
+func ParseProfileConfig(data []byte) (*ProfileConfig, error) {
+	jsonData, err := yaml.ToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse converter config: %w", err)
+	}
+
+	var config ProfileConfig
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse converter config: %w", err)
+	}
+
+	if errs := config.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid converter config: %w", errs)
+	}
+	return &config, nil
+}
+
+// Validate checks the version and the values of every profile
+func (c *ProfileConfig) Validate() ValidationErrors {
+	var errs ValidationErrors
+
+	if c.APIVersion != ProfileConfigAPIVersion {
+		errs.add("apiVersion", ValidationInvalidValue, "must be %s, got %q", ProfileConfigAPIVersion, c.APIVersion)
+	}
+	if c.Kind != ProfileConfigKind {
+		errs.add("kind", ValidationInvalidValue, "must be %s, got %q", ProfileConfigKind, c.Kind)
+	}
+	if len(c.Profiles) == 0 {
+		errs.add("profiles", ValidationRequired, "at least one profile is required")
+	}
+	if _, ok := c.Profiles[c.DefaultProfile]; c.DefaultProfile != "" && !ok {
+		errs.add("defaultProfile", ValidationNotFound, "profile %q is not defined", c.DefaultProfile)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Profiles)) {
+		c.Profiles[name].validate(fmt.Sprintf("profiles.%s", name), &errs)
+	}
+	return errs
+}
+
+func (p Profile) validate(field string, errs *ValidationErrors) {
+	if p.Region != "" && !regionPattern.MatchString(p.Region) {
+		errs.add(field+".region", ValidationInvalidFormat, "%q is not an AWS region", p.Region)
+	}
+	if p.AccountID != "" && !accountIDPattern.MatchString(p.AccountID) {
+		errs.add(field+".accountId", ValidationInvalidFormat, "%q is not a 12-digit account ID", p.AccountID)
+	}
+	if p.ExecutionRoleArn != "" && !iamRoleArnPattern.MatchString(p.ExecutionRoleArn) {
+		errs.add(field+".executionRoleArn", ValidationInvalidFormat, "%q is not an IAM role ARN", p.ExecutionRoleArn)
+	}
+	if p.TaskRoleArn != "" && !iamRoleArnPattern.MatchString(p.TaskRoleArn) {
+		errs.add(field+".taskRoleArn", ValidationInvalidFormat, "%q is not an IAM role ARN", p.TaskRoleArn)
+	}
+	for _, serviceAccount := range slices.Sorted(maps.Keys(p.ServiceAccountRoles)) {
+		role := p.ServiceAccountRoles[serviceAccount]
+		entry := fmt.Sprintf("%s.serviceAccountRoles[%s]", field, serviceAccount)
+		if !strings.Contains(serviceAccount, "/") {
+			errs.add(entry, ValidationInvalidFormat, "key must be namespace/name or namespace/*")
+		}
+		if !iamRoleArnPattern.MatchString(role) {
+			errs.add(entry, ValidationInvalidFormat, "%q is not an IAM role ARN", role)
+		}
+	}
+	errs.checkEnum(field+".logging.driver", p.Logging.Driver, LogDrivers)
+	errs.checkEnum(field+".secrets.backend", string(p.Secrets.Backend), SecretBackends)
+	if p.Secrets.Backend == SecretBackendSecretsManager && (p.Region == "" || p.AccountID == "") {
+		errs.add(field+".secrets.backend", ValidationRequired,
+			"the secretsmanager backend requires region and accountId to build secret ARNs")
+	}
+	if _, ok := parseTaskCPU(p.CPU); p.CPU != "" && !ok {
+		errs.add(field+".cpu", ValidationInvalidFormat, "%q is not a valid CPU value", p.CPU)
+	}
+	if _, ok := parseTaskMemory(p.Memory); p.Memory != "" && !ok {
+		errs.add(field+".memory", ValidationInvalidFormat, "%q is not a valid memory value", p.Memory)
+	}
+	if _, err := parseFamilyTemplate(p.FamilyTemplate); err != nil {
+		errs.add(field+".familyTemplate", ValidationInvalidFormat, "%v", err)
+	}
+	errs.checkEnum(field+".run.launchType", p.Run.LaunchType, LaunchTypes)
+	for i, subnet := range p.Run.Subnets {
+		if !strings.HasPrefix(subnet, "subnet-") {
+			errs.add(fmt.Sprintf("%s.run.subnets[%d]", field, i), ValidationInvalidFormat, "%q is not a subnet ID", subnet)
+		}
+	}
+	for i, group := range p.Run.SecurityGroups {
+		if !strings.HasPrefix(group, "sg-") {
+			errs.add(fmt.Sprintf("%s.run.securityGroups[%d]", field, i), ValidationInvalidFormat,
+				"%q is not a security group ID", group)
+		}
+	}
+	if len(p.Run.SecurityGroups) > 0 && len(p.Run.Subnets) == 0 {
+		errs.add(field+".run.subnets", ValidationRequired, "securityGroups require subnets")
+	}
+	for _, namespace := range slices.Sorted(maps.Keys(p.Namespaces)) {
+		requests := p.Namespaces[namespace].Requests
+		for _, name := range slices.Sorted(maps.Keys(requests)) {
+			entry := fmt.Sprintf("%s.namespaces[%s].requests[%s]", field, namespace, name)
+			quantity := requests[name]
+			if name != corev1.ResourceCPU && name != corev1.ResourceMemory {
+				errs.add(entry, ValidationInvalidValue, "only cpu and memory requests can be defaulted")
+			} else if quantity.Sign() <= 0 {
+				errs.add(entry, ValidationInvalidValue, "%s must be positive", quantity.String())
+			}
+		}
+	}
+}
+
+// Profile returns the named profile, or the default profile when name is empty
+func (c *ProfileConfig) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		if len(c.Profiles) != 1 {
+			return nil, fmt.Errorf("no profile selected and no defaultProfile set (available: %s)",
+				strings.Join(slices.Sorted(maps.Keys(c.Profiles)), ", "))
+		}
+		for _, profile := range c.Profiles {
+			return &profile, nil
+		}
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q is not defined (available: %s)",
+			name, strings.Join(slices.Sorted(maps.Keys(c.Profiles)), ", "))
+	}
+	return &profile, nil
+}
+
+// ConversionOptions builds the converter options described by the profile
+func (p *Profile) ConversionOptions() ConversionOptions {
+	options := ConversionOptions{
+		ParameterStorePrefix:    p.Secrets.Prefix,
+		SecretBackend:           p.Secrets.Backend,
+		Region:                  p.Region,
+		AccountID:               p.AccountID,
+		DefaultLogDriver:        p.Logging.Driver,
+		DefaultExecutionRoleArn: p.ExecutionRoleArn,
+		DefaultTaskRoleArn:      p.TaskRoleArn,
+		DefaultCPU:              p.CPU,
+		DefaultMemory:           p.Memory,
+		FamilyTemplate:          p.FamilyTemplate,
+		ServiceAccountRoles:     maps.Clone(p.ServiceAccountRoles),
+		MetadataRules:           slices.Clone(p.MetadataRules),
+	}
+	for namespace, settings := range p.Namespaces {
+		if len(settings.Requests) == 0 {
+			continue
+		}
+		if options.DefaultRequests == nil {
+			options.DefaultRequests = map[string]corev1.ResourceList{}
+		}
+		options.DefaultRequests[namespace] = settings.Requests.DeepCopy()
+	}
+	if p.SkipUnsupportedFeatures != nil {
+		options.SkipUnsupportedFeatures = *p.SkipUnsupportedFeatures
+	}
+
+	// awslogs needs a group and a region, which fall back to the profile region and the
+	// converter defaults, and a stream prefix for the streams to be found by task. Options of
+	// other drivers are passed through unchanged.
+	options.DefaultLogOptions = maps.Clone(p.Logging.Options)
+	awslogs := p.Logging.Driver == "" || p.Logging.Driver == "awslogs"
+	if awslogs && (options.DefaultLogOptions != nil || p.Logging.Group != "" || p.Region != "") {
+		if options.DefaultLogOptions == nil {
+			options.DefaultLogOptions = map[string]string{}
+		}
+		if p.Logging.Group != "" {
+			options.DefaultLogOptions["awslogs-group"] = p.Logging.Group
+		}
+		setDefault(options.DefaultLogOptions, "awslogs-group", DefaultLogGroup)
+		setDefault(options.DefaultLogOptions, "awslogs-region", valueOrDefault(p.Region, DefaultRegion))
+		setDefault(options.DefaultLogOptions, "awslogs-stream-prefix", DefaultLogStreamPrefix)
+	}
+
+	return options
+}
+
+// partitionForRegion returns the ARN partition of the region
+func partitionForRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	default:
+		return "aws"
+	}
+}
+
+func setDefault(values map[string]string, key, value string) {
+	if _, ok := values[key]; !ok {
+		values[key] = value
+	}
+}
@@ -0,0 +1,181 @@
+package ecs
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestLoadProfileConfig_Example(t *testing.T) {
+	config, err := LoadProfileConfig("../../examples/converter-config.yaml")
+	if err != nil {
+		t.Fatalf("LoadProfileConfig() error = %v", err)
+	}
+
+	profile, err := config.Profile("")
+	if err != nil {
+		t.Fatalf("Profile() error = %v", err)
+	}
+	options := profile.ConversionOptions()
+	if options.ParameterStorePrefix != "/dev/pods" || options.DefaultCPU != "256" {
+		t.Errorf("default profile options = %+v, want the dev profile", options)
+	}
+	wantLogOptions := map[string]string{
+		"awslogs-group": "/ecs/dev", "awslogs-region": "us-east-1", "awslogs-stream-prefix": "ecs",
+	}
+	if !reflect.DeepEqual(options.DefaultLogOptions, wantLogOptions) {
+		t.Errorf("DefaultLogOptions = %v, want %v", options.DefaultLogOptions, wantLogOptions)
+	}
+
+	staging, err := config.Profile("staging")
+	if err != nil {
+		t.Fatalf("Profile(staging) error = %v", err)
+	}
+	// Containers of the batch namespace get its memory request, which becomes the reservation
+	converter := NewConverter(staging.ConversionOptions())
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "job", Image: "job:1.0"}}}}
+	taskDef, diagnostics, err := converter.ConvertPodWithDiagnostics(context.Background(), pod, &pod.Spec,
+		&ECSConfig{}, "batch")
+	if err != nil {
+		t.Fatalf("ConvertPodWithDiagnostics() error = %v", err)
+	}
+	if taskDef.ContainerDefinitions[0].MemoryReservation != 1024 || pod.Spec.Containers[0].Resources.Requests != nil {
+		t.Errorf("memoryReservation = %d, pod requests = %v", taskDef.ContainerDefinitions[0].MemoryReservation,
+			pod.Spec.Containers[0].Resources.Requests)
+	}
+	if diagnostics[0].Field != "spec.containers[0].resources.requests.memory" {
+		t.Errorf("diagnostics = %v, want the defaulted request", diagnostics)
+	}
+	// Other namespaces get the requests of "*"
+	if converter.DefaultRequests(&pod.Spec, "web"); pod.Spec.Containers[0].Resources.Requests.Cpu().String() != "250m" {
+		t.Errorf("requests in other namespaces = %v", pod.Spec.Containers[0].Resources.Requests)
+	}
+
+	prod, err := config.Profile("prod")
+	if err != nil {
+		t.Fatalf("Profile(prod) error = %v", err)
+	}
+	options = prod.ConversionOptions()
+	if options.SecretBackend != SecretBackendSecretsManager || options.Region != "ap-northeast-1" {
+		t.Errorf("prod options = %+v", options)
+	}
+	if options.DefaultLogOptions["awslogs-stream-prefix"] != "app" ||
+		options.DefaultLogOptions["awslogs-region"] != "ap-northeast-1" {
+		t.Errorf("prod DefaultLogOptions = %v", options.DefaultLogOptions)
+	}
+	if role, _ := options.ServiceAccountRoles.lookup("shop", "web"); role != "arn:aws:iam::345678901234:role/web" {
+		t.Errorf("ServiceAccountRoles = %v", options.ServiceAccountRoles)
+	}
+	if prod.Run.Cluster != "prod" || prod.Run.LaunchType != "FARGATE" || len(prod.Run.Subnets) != 2 {
+		t.Errorf("prod run = %+v", prod.Run)
+	}
+
+	if _, err := config.Profile("qa"); err == nil || !strings.Contains(err.Error(), "dev, prod, staging") {
+		t.Errorf("Profile(qa) error = %v, want the available profiles listed", err)
+	}
+}
+
+func TestParseProfileConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name:   "wrong version",
+			config: "apiVersion: v1\nkind: ConverterConfig\nprofiles: {dev: {}}",
+			want:   "apiVersion",
+		},
+		{
+			name:   "unknown field",
+			config: "apiVersion: ecs.takutakahashi.dev/v1alpha1\nkind: ConverterConfig\nprofiles: {dev: {regoin: x}}",
+			want:   "unknown field",
+		},
+		{
+			name: "missing default profile",
+			config: "apiVersion: ecs.takutakahashi.dev/v1alpha1\nkind: ConverterConfig\n" +
+				"defaultProfile: prod\nprofiles: {dev: {}}",
+			want: "defaultProfile",
+		},
+		{
+			name: "invalid values",
+			config: "apiVersion: ecs.takutakahashi.dev/v1alpha1\nkind: ConverterConfig\n" +
+				"profiles: {dev: {region: Tokyo, accountId: '123', secrets: {backend: vault}}}",
+			want: "profiles.dev.region",
+		},
+		{
+			name: "secrets manager without account",
+			config: "apiVersion: ecs.takutakahashi.dev/v1alpha1\nkind: ConverterConfig\n" +
+				"profiles: {dev: {region: us-east-1, secrets: {backend: secretsmanager}}}",
+			want: "requires region and accountId",
+		},
+		{
+			name: "invalid run settings",
+			config: "apiVersion: ecs.takutakahashi.dev/v1alpha1\nkind: ConverterConfig\n" +
+				"profiles: {dev: {run: {launchType: LAMBDA, securityGroups: [sg-1]}}}",
+			want: "profiles.dev.run.launchType",
+		},
+		{
+			name: "invalid namespace requests",
+			config: "apiVersion: ecs.takutakahashi.dev/v1alpha1\nkind: ConverterConfig\n" +
+				"profiles: {dev: {namespaces: {batch: {requests: {nvidia.com/gpu: 1}}}}}",
+			want: "profiles.dev.namespaces[batch].requests[nvidia.com/gpu]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseProfileConfig([]byte(tt.config))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseProfileConfig() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConverter_SecretsManagerBackend(t *testing.T) {
+	converter := NewConverter(ConversionOptions{
+		ParameterStorePrefix: "prod/pods",
+		SecretBackend:        SecretBackendSecretsManager,
+		Region:               "ap-northeast-1",
+		AccountID:            "345678901234",
+	})
+	podSpec := &corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:  "app",
+			Image: "app:1.0",
+			Env: []corev1.EnvVar{
+				{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password",
+				}}},
+				{Name: "MODE", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}, Key: "mode",
+				}}},
+			},
+		}},
+	}
+
+	taskDef, err := converter.Convert(podSpec, &ECSConfig{Family: "app"}, "shop")
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	want := []ECSSecret{
+		{
+			Name:      "PASSWORD",
+			ValueFrom: "arn:aws:secretsmanager:ap-northeast-1:345678901234:secret:prod/pods/shop/db:password::",
+		},
+		{Name: "MODE", ValueFrom: "prod/pods/shop/configmaps/settings/mode"},
+	}
+	if !reflect.DeepEqual(taskDef.ContainerDefinitions[0].Secrets, want) {
+		t.Errorf("Secrets = %+v, want %+v", taskDef.ContainerDefinitions[0].Secrets, want)
+	}
+	var errs ValidationErrors
+	errs.checkSecretReference("secrets[0]", want[0].ValueFrom)
+	if len(errs) > 0 {
+		t.Errorf("Secrets Manager reference does not validate: %v", errs)
+	}
+}
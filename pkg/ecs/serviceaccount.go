@@ -0,0 +1,96 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// IRSARoleArnAnnotation is the ServiceAccount annotation used by IAM Roles for Service Accounts
+	IRSARoleArnAnnotation = "eks.amazonaws.com/role-arn"
+
+	defaultServiceAccountName = "default"
+)
+
+// ServiceAccountResolver looks up the ServiceAccount a pod runs as. Implementations return
+// a NotFound API error when the ServiceAccount does not exist.
+type ServiceAccountResolver interface {
+	GetServiceAccount(ctx context.Context, namespace, name string) (*corev1.ServiceAccount, error)
+}
+
+// ServiceAccountRoles maps ServiceAccounts to task role ARNs. Keys are "namespace/name",
+// or "namespace/*" to match every ServiceAccount of a namespace. Pod Identity
+// associations can be expressed as entries of this table.
+type ServiceAccountRoles map[string]string
+
+// lookup returns the role mapped to the ServiceAccount, preferring exact matches
+func (r ServiceAccountRoles) lookup(namespace, name string) (string, bool) {
+	if role, ok := r[namespace+"/"+name]; ok {
+		return role, true
+	}
+	role, ok := r[namespace+"/*"]
+	return role, ok
+}
+
+// podServiceAccountName returns the ServiceAccount the pod runs as
+func podServiceAccountName(podSpec *corev1.PodSpec) string {
+	if podSpec.ServiceAccountName != "" {
+		return podSpec.ServiceAccountName
+	}
+	if podSpec.DeprecatedServiceAccount != "" {
+		return podSpec.DeprecatedServiceAccount
+	}
+	return defaultServiceAccountName
+}
+
+// resolveTaskRoleArn determines the task role in order of precedence: the explicit
+// ECSConfig role, the IRSA annotation of the pod's ServiceAccount, the ServiceAccountRoles
+// table and finally DefaultTaskRoleArn
+func (c *Converter) resolveTaskRoleArn(
+	ctx context.Context,
+	podSpec *corev1.PodSpec,
+	ecsConfig *ECSConfig,
+	namespace string,
+) (string, []Diagnostic, error) {
+	if ecsConfig.TaskRoleArn != "" {
+		return ecsConfig.TaskRoleArn, nil, nil
+	}
+
+	var diagnostics []Diagnostic
+	name := podServiceAccountName(podSpec)
+
+	if c.options.ServiceAccounts != nil {
+		serviceAccount, err := c.options.ServiceAccounts.GetServiceAccount(ctx, namespace, name)
+		switch {
+		case apierrors.IsNotFound(err):
+			diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, DiagServiceAccountNotFound,
+				"spec.serviceAccountName", "ServiceAccount %s/%s was not found", namespace, name))
+		case err != nil:
+			return "", nil, fmt.Errorf("failed to get ServiceAccount %s/%s: %w", namespace, name, err)
+		default:
+			if role := serviceAccount.Annotations[IRSARoleArnAnnotation]; role != "" {
+				return role, diagnostics, nil
+			}
+		}
+	}
+
+	if role, ok := c.options.ServiceAccountRoles.lookup(namespace, name); ok {
+		return role, diagnostics, nil
+	}
+
+	if c.options.DefaultTaskRoleArn != "" {
+		if name != defaultServiceAccountName {
+			diagnostics = append(diagnostics, newDiagnostic(SeverityInfo, DiagDefaultedValue, "taskRoleArn",
+				"no role is associated with ServiceAccount %s/%s, using the default task role", namespace, name))
+		}
+		return c.options.DefaultTaskRoleArn, diagnostics, nil
+	}
+
+	diagnostics = append(diagnostics, newDiagnostic(SeverityWarning, DiagMissingTaskRole, "taskRoleArn",
+		"ServiceAccount %s/%s has no %s annotation or role mapping; the task will run without a task role",
+		namespace, name, IRSARoleArnAnnotation))
+	return "", diagnostics, nil
+}
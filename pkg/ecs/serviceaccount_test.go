@@ -0,0 +1,147 @@
+package ecs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// staticServiceAccounts is a ServiceAccountResolver backed by a map keyed by namespace/name
+type staticServiceAccounts map[string]*corev1.ServiceAccount
+
+func (s staticServiceAccounts) GetServiceAccount(
+	_ context.Context,
+	namespace, name string,
+) (*corev1.ServiceAccount, error) {
+	if serviceAccount, ok := s[namespace+"/"+name]; ok {
+		return serviceAccount, nil
+	}
+	if namespace == "forbidden" {
+		return nil, errors.New("forbidden")
+	}
+	return nil, apierrors.NewNotFound(corev1.Resource("serviceaccounts"), name)
+}
+
+func TestConverter_ResolveTaskRole(t *testing.T) {
+	serviceAccounts := staticServiceAccounts{
+		"shop/web": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "web",
+				Namespace:   "shop",
+				Annotations: map[string]string{IRSARoleArnAnnotation: "arn:aws:iam::123456789012:role/irsa-web"},
+			},
+		},
+		"shop/worker": {ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "shop"}},
+		"shop/batch":  {ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "shop"}},
+	}
+	roles := ServiceAccountRoles{
+		"shop/worker": "arn:aws:iam::123456789012:role/mapped-worker",
+		"shop/*":      "arn:aws:iam::123456789012:role/mapped-shop",
+	}
+
+	tests := []struct {
+		name           string
+		options        ConversionOptions
+		serviceAccount string
+		namespace      string
+		configRole     string
+		wantRole       string
+		wantCodes      []DiagnosticCode
+		wantErr        bool
+	}{
+		{
+			name:           "explicit role wins",
+			options:        ConversionOptions{ServiceAccounts: serviceAccounts},
+			serviceAccount: "web",
+			namespace:      "shop",
+			configRole:     "arn:aws:iam::123456789012:role/explicit",
+			wantRole:       "arn:aws:iam::123456789012:role/explicit",
+		},
+		{
+			name:           "IRSA annotation",
+			options:        ConversionOptions{ServiceAccounts: serviceAccounts, ServiceAccountRoles: roles},
+			serviceAccount: "web",
+			namespace:      "shop",
+			wantRole:       "arn:aws:iam::123456789012:role/irsa-web",
+		},
+		{
+			name:           "exact mapping",
+			options:        ConversionOptions{ServiceAccounts: serviceAccounts, ServiceAccountRoles: roles},
+			serviceAccount: "worker",
+			namespace:      "shop",
+			wantRole:       "arn:aws:iam::123456789012:role/mapped-worker",
+		},
+		{
+			name:           "namespace wildcard mapping without a resolver",
+			options:        ConversionOptions{ServiceAccountRoles: roles},
+			serviceAccount: "batch",
+			namespace:      "shop",
+			wantRole:       "arn:aws:iam::123456789012:role/mapped-shop",
+		},
+		{
+			name: "default role for an unmapped service account",
+			options: ConversionOptions{
+				ServiceAccounts:    serviceAccounts,
+				DefaultTaskRoleArn: "arn:aws:iam::123456789012:role/default",
+			},
+			serviceAccount: "batch",
+			namespace:      "shop",
+			wantRole:       "arn:aws:iam::123456789012:role/default",
+			wantCodes:      []DiagnosticCode{DiagDefaultedValue},
+		},
+		{
+			name:      "missing service account and no role",
+			options:   ConversionOptions{ServiceAccounts: serviceAccounts},
+			namespace: "shop",
+			wantCodes: []DiagnosticCode{DiagServiceAccountNotFound, DiagMissingTaskRole},
+		},
+		{
+			name:      "lookup failure",
+			options:   ConversionOptions{ServiceAccounts: serviceAccounts},
+			namespace: "forbidden",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: tt.namespace},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: tt.serviceAccount,
+					Containers:         []corev1.Container{{Name: "app", Image: "app:1.0"}},
+				},
+			}
+			config := &ECSConfig{Family: "test", TaskRoleArn: tt.configRole}
+
+			converter := NewConverter(tt.options)
+			taskDef, diagnostics, err := converter.ConvertPodWithDiagnostics(
+				context.Background(), pod, &pod.Spec, config, tt.namespace)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ConvertPodWithDiagnostics() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConvertPodWithDiagnostics() error = %v", err)
+			}
+
+			if taskDef.TaskRoleArn != tt.wantRole {
+				t.Errorf("TaskRoleArn = %q, want %q", taskDef.TaskRoleArn, tt.wantRole)
+			}
+			if len(diagnostics) != len(tt.wantCodes) {
+				t.Fatalf("diagnostics = %v, want codes %v", diagnostics, tt.wantCodes)
+			}
+			for i, code := range tt.wantCodes {
+				if diagnostics[i].Code != code {
+					t.Errorf("diagnostics[%d] = %v, want code %v", i, diagnostics[i], code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ECSTimestamp is a timestamp returned by the ECS API. The API encodes timestamps as
+// epoch seconds, while the AWS CLI prints them as RFC 3339 strings; both are accepted.
+type ECSTimestamp struct {
+	time.Time
+}
+
+// MarshalJSON encodes the timestamp as epoch seconds like the ECS API does
+func (t ECSTimestamp) MarshalJSON() ([]byte, error) {
+	seconds := float64(t.UnixNano()) / float64(time.Second)
+	return []byte(strconv.FormatFloat(seconds, 'f', -1, 64)), nil
+}
+
+// UnmarshalJSON decodes epoch seconds or an RFC 3339 string
+func (t *ECSTimestamp) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q: %w", s, err)
+		}
+		t.Time = parsed
+		return nil
+	}
+
+	seconds, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %s: %w", data, err)
+	}
+	whole, frac := math.Modf(seconds)
+	t.Time = time.Unix(int64(whole), int64(math.Round(frac*1e9))).UTC()
+	return nil
+}
+
+// describeTaskDefinitionOutput is the shape printed by `aws ecs describe-task-definition`
+type describeTaskDefinitionOutput struct {
+	TaskDefinition *ECSTaskDefinition `json:"taskDefinition"`
+	Tags           []ECSTag           `json:"tags,omitempty"`
+}
+
+// ParseTaskDefinition strictly decodes a task definition from JSON. It accepts both a bare
+// task definition (RegisterTaskDefinition input or DescribeTaskDefinition's taskDefinition)
+// and the full DescribeTaskDefinition output. Unknown fields and invalid enum values are
+// rejected so that no settings are silently dropped.
+func ParseTaskDefinition(data []byte) (*ECSTaskDefinition, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse task definition: %w", err)
+	}
+
+	var taskDef *ECSTaskDefinition
+	if _, wrapped := probe["taskDefinition"]; wrapped {
+		var output describeTaskDefinitionOutput
+		if err := decodeStrict(data, &output); err != nil {
+			return nil, err
+		}
+		if output.TaskDefinition == nil {
+			return nil, fmt.Errorf("failed to parse task definition: taskDefinition is null")
+		}
+		taskDef = output.TaskDefinition
+		if len(taskDef.Tags) == 0 {
+			taskDef.Tags = output.Tags
+		}
+	} else {
+		taskDef = &ECSTaskDefinition{}
+		if err := decodeStrict(data, taskDef); err != nil {
+			return nil, err
+		}
+	}
+
+	if errs := taskDef.ValidateEnums(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	return taskDef, nil
+}
+
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to parse task definition: %w", err)
+	}
+	return nil
+}
+
+// RegisterInput returns a copy of the task definition without the read-only fields that
+// ECS sets on registration, suitable as RegisterTaskDefinition input
+func (t *ECSTaskDefinition) RegisterInput() *ECSTaskDefinition {
+	input := *t
+	input.TaskDefinitionArn = ""
+	input.Revision = 0
+	input.Status = ""
+	input.RequiresAttributes = nil
+	input.Compatibilities = nil
+	input.RegisteredAt = nil
+	input.RegisteredBy = ""
+	input.DeregisteredAt = nil
+	input.DeleteRequestedAt = nil
+	return &input
+}
@@ -0,0 +1,270 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const describedTaskDefinition = `{
+  "taskDefinition": {
+    "taskDefinitionArn": "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/web:3",
+    "family": "web",
+    "revision": 3,
+    "status": "ACTIVE",
+    "networkMode": "awsvpc",
+    "pidMode": "task",
+    "cpu": "512",
+    "memory": "1024",
+    "requiresCompatibilities": ["FARGATE"],
+    "compatibilities": ["EC2", "FARGATE"],
+    "requiresAttributes": [{"name": "com.amazonaws.ecs.capability.docker-remote-api.1.18"}],
+    "runtimePlatform": {"cpuArchitecture": "ARM64", "operatingSystemFamily": "LINUX"},
+    "ephemeralStorage": {"sizeInGiB": 30},
+    "containerDefinitions": [
+      {
+        "name": "app",
+        "image": "app:1.0",
+        "essential": true,
+        "repositoryCredentials": {"credentialsParameter": "arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:registry"},
+        "portMappings": [{"containerPort": 8080, "protocol": "tcp", "name": "http", "appProtocol": "http"}],
+        "dependsOn": [{"containerName": "init", "condition": "SUCCESS"}],
+        "startTimeout": 30,
+        "stopTimeout": 60,
+        "ulimits": [{"name": "nofile", "softLimit": 1024, "hardLimit": 4096}],
+        "systemControls": [{"namespace": "net.core.somaxconn", "value": "1024"}],
+        "dockerLabels": {"team": "platform"},
+        "linuxParameters": {"initProcessEnabled": true, "capabilities": {"add": ["NET_ADMIN"]}}
+      },
+      {"name": "init", "image": "init:1.0", "essential": false}
+    ],
+    "volumes": [
+      {
+        "name": "shared",
+        "efsVolumeConfiguration": {
+          "fileSystemId": "fs-12345678",
+          "transitEncryption": "ENABLED",
+          "authorizationConfig": {"iam": "ENABLED"}
+        }
+      }
+    ],
+    "registeredAt": "2024-05-01T12:30:00.123000+09:00",
+    "registeredBy": "arn:aws:iam::123456789012:user/deployer"
+  },
+  "tags": [{"key": "team", "value": "platform"}]
+}`
+
+func TestParseTaskDefinition(t *testing.T) {
+	taskDef, err := ParseTaskDefinition([]byte(describedTaskDefinition))
+	if err != nil {
+		t.Fatalf("ParseTaskDefinition() error = %v", err)
+	}
+
+	if taskDef.Revision != 3 || taskDef.Status != "ACTIVE" {
+		t.Errorf("revision/status = %d/%s, want 3/ACTIVE", taskDef.Revision, taskDef.Status)
+	}
+	if taskDef.RuntimePlatform == nil || taskDef.RuntimePlatform.CPUArchitecture != "ARM64" {
+		t.Errorf("RuntimePlatform = %+v, want ARM64", taskDef.RuntimePlatform)
+	}
+	if len(taskDef.Tags) != 1 || taskDef.Tags[0].Key != "team" {
+		t.Errorf("Tags = %+v, want tags from the describe output", taskDef.Tags)
+	}
+	want := time.Date(2024, 5, 1, 3, 30, 0, 123000000, time.UTC)
+	if taskDef.RegisteredAt == nil || !taskDef.RegisteredAt.Equal(want) {
+		t.Errorf("RegisteredAt = %v, want %v", taskDef.RegisteredAt, want)
+	}
+
+	app := taskDef.ContainerDefinitions[0]
+	if app.RepositoryCredentials == nil || app.StopTimeout != 60 || len(app.Ulimits) != 1 {
+		t.Errorf("container fields were not decoded: %+v", app)
+	}
+	if app.LinuxParameters == nil || app.LinuxParameters.Capabilities == nil {
+		t.Errorf("LinuxParameters = %+v, want capabilities", app.LinuxParameters)
+	}
+	if fs := taskDef.Volumes[0].EFSVolumeConfiguration; fs == nil || fs.FileSystemID != "fs-12345678" {
+		t.Errorf("EFSVolumeConfiguration = %+v, want fs-12345678", fs)
+	}
+}
+
+func TestParseTaskDefinition_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "unknown field",
+			input: `{"family": "web", "containerDefinitions": [{"name": "app", "image": "app", "imagePullPolicy": "Always"}]}`,
+			want:  `unknown field "imagePullPolicy"`,
+		},
+		{
+			name:  "invalid network mode",
+			input: `{"family": "web", "networkMode": "overlay", "containerDefinitions": []}`,
+			want:  "networkMode",
+		},
+		{
+			name: "invalid dependency condition",
+			input: `{"family": "web", "containerDefinitions": [
+				{"name": "app", "image": "app", "dependsOn": [{"containerName": "db", "condition": "READY"}]}]}`,
+			want: "containerDefinitions[0].dependsOn[0].condition",
+		},
+		{
+			name:  "invalid timestamp",
+			input: `{"family": "web", "registeredAt": "yesterday"}`,
+			want:  "invalid timestamp",
+		},
+		{
+			name:  "trailing data",
+			input: `{"family": "web"} {"family": "api"}`,
+			want:  "after top-level value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTaskDefinition([]byte(tt.input))
+			if err == nil {
+				t.Fatal("ParseTaskDefinition() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTaskDefinition_ValidationErrors(t *testing.T) {
+	_, err := ParseTaskDefinition([]byte(`{"family": "web", "pidMode": "container", "ipcMode": "shared"}`))
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error = %v, want ValidationErrors", err)
+	}
+	if len(errs) != 2 || errs[0].Field != "pidMode" || errs[1].Field != "ipcMode" {
+		t.Errorf("errors = %v, want pidMode and ipcMode", errs)
+	}
+	if errs[0].Code != ValidationInvalidValue {
+		t.Errorf("code = %v, want %v", errs[0].Code, ValidationInvalidValue)
+	}
+}
+
+func TestECSTimestamp(t *testing.T) {
+	var ts ECSTimestamp
+	if err := json.Unmarshal([]byte("1714534200.5"), &ts); err != nil {
+		t.Fatalf("UnmarshalJSON() error = %v", err)
+	}
+	if want := time.Unix(1714534200, 500000000); !ts.Equal(want) {
+		t.Errorf("timestamp = %v, want %v", ts.Time, want)
+	}
+
+	data, err := json.Marshal(ts)
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+	if string(data) != "1714534200.5" {
+		t.Errorf("MarshalJSON() = %s, want 1714534200.5", data)
+	}
+}
+
+func TestECSTaskDefinition_RoundTrip(t *testing.T) {
+	taskDef, err := ParseTaskDefinition([]byte(describedTaskDefinition))
+	if err != nil {
+		t.Fatalf("ParseTaskDefinition() error = %v", err)
+	}
+
+	data, err := json.Marshal(taskDef)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	again, err := ParseTaskDefinition(data)
+	if err != nil {
+		t.Fatalf("ParseTaskDefinition() of marshalled output error = %v\n%s", err, data)
+	}
+
+	againData, err := json.Marshal(again)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !bytes.Equal(data, againData) {
+		t.Errorf("round trip changed the task definition:\n%s\n%s", data, againData)
+	}
+}
+
+func TestECSTaskDefinition_RegisterInput(t *testing.T) {
+	taskDef, err := ParseTaskDefinition([]byte(describedTaskDefinition))
+	if err != nil {
+		t.Fatalf("ParseTaskDefinition() error = %v", err)
+	}
+
+	data, err := json.Marshal(taskDef.RegisterInput())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, field := range []string{
+		"taskDefinitionArn", "revision", "status", "compatibilities", "requiresAttributes",
+		"registeredAt", "registeredBy",
+	} {
+		if bytes.Contains(data, []byte(`"`+field+`"`)) {
+			t.Errorf("RegisterInput() still contains %s", field)
+		}
+	}
+	if taskDef.TaskDefinitionArn == "" {
+		t.Error("RegisterInput() modified the original task definition")
+	}
+}
+
+func TestEmitters_FullTaskDefinition(t *testing.T) {
+	taskDef, err := ParseTaskDefinition([]byte(describedTaskDefinition))
+	if err != nil {
+		t.Fatalf("ParseTaskDefinition() error = %v", err)
+	}
+
+	var tf bytes.Buffer
+	if err := (&TerraformEmitter{}).Emit(&tf, taskDef); err != nil {
+		t.Fatalf("TerraformEmitter.Emit() error = %v", err)
+	}
+	for _, want := range []string{
+		`pid_mode = "task"`,
+		"  runtime_platform {\n    cpu_architecture        = \"ARM64\"",
+		"  ephemeral_storage {\n    size_in_gib = 30\n  }",
+		"    efs_volume_configuration {\n      file_system_id     = \"fs-12345678\"",
+		"      authorization_config {\n        iam = \"ENABLED\"\n      }",
+	} {
+		if !strings.Contains(tf.String(), want) {
+			t.Errorf("terraform output does not contain %q\n%s", want, tf.String())
+		}
+	}
+	if strings.Contains(tf.String(), "revision") || strings.Contains(tf.String(), "registered_at") {
+		t.Errorf("terraform output contains read-only fields\n%s", tf.String())
+	}
+
+	var cfn bytes.Buffer
+	if err := (&CloudFormationEmitter{}).Emit(&cfn, taskDef); err != nil {
+		t.Fatalf("CloudFormationEmitter.Emit() error = %v", err)
+	}
+	var template struct {
+		Resources map[string]struct {
+			Properties map[string]any `yaml:"Properties"`
+		} `yaml:"Resources"`
+	}
+	if err := yaml.Unmarshal(cfn.Bytes(), &template); err != nil {
+		t.Fatalf("output is not valid YAML: %v", err)
+	}
+	properties := template.Resources["Web"].Properties
+	if _, ok := properties["Revision"]; ok {
+		t.Error("CloudFormation output contains read-only Revision")
+	}
+	volume := properties["Volumes"].([]any)[0].(map[string]any)
+	efs := volume["EFSVolumeConfiguration"].(map[string]any)
+	if efs["FilesystemId"] != "fs-12345678" {
+		t.Errorf("EFSVolumeConfiguration = %v, want FilesystemId", efs)
+	}
+	if got := efs["AuthorizationConfig"].(map[string]any)["IAM"]; got != "ENABLED" {
+		t.Errorf("AuthorizationConfig.IAM = %v, want ENABLED", got)
+	}
+}
@@ -1,5 +1,7 @@
 package ecs
 
+import corev1 "k8s.io/api/core/v1"
+
 // ECSConfig represents ECS-specific configuration for task definition
 type ECSConfig struct {
 	Family                  string            `json:"family"`
@@ -10,47 +12,112 @@ type ECSConfig struct {
 	CPU                     string            `json:"cpu,omitempty"`
 	Memory                  string            `json:"memory,omitempty"`
 	Tags                    map[string]string `json:"tags,omitempty"`
+
+	// Containers overrides settings of individual containers, keyed by container name
+	Containers map[string]ECSContainerOverride `json:"containers,omitempty"`
 }
 
-// ECSTaskDefinition represents the ECS task definition output
+// ECSTaskDefinition represents the ECS task definition output. It covers the
+// RegisterTaskDefinition input as well as the read-only fields returned by
+// DescribeTaskDefinition, so existing definitions can be read without losing fields.
 type ECSTaskDefinition struct {
-	Family                  string                   `json:"family"`
-	TaskRoleArn             string                   `json:"taskRoleArn,omitempty"`
-	ExecutionRoleArn        string                   `json:"executionRoleArn,omitempty"`
-	NetworkMode             string                   `json:"networkMode,omitempty"`
-	RequiresCompatibilities []string                 `json:"requiresCompatibilities,omitempty"`
-	CPU                     string                   `json:"cpu,omitempty"`
-	Memory                  string                   `json:"memory,omitempty"`
-	ContainerDefinitions    []ECSContainerDefinition `json:"containerDefinitions"`
-	Volumes                 []ECSVolume              `json:"volumes,omitempty"`
-	Tags                    []ECSTag                 `json:"tags,omitempty"`
+	Family                  string                    `json:"family"`
+	TaskRoleArn             string                    `json:"taskRoleArn,omitempty"`
+	ExecutionRoleArn        string                    `json:"executionRoleArn,omitempty"`
+	NetworkMode             string                    `json:"networkMode,omitempty"`
+	RequiresCompatibilities []string                  `json:"requiresCompatibilities,omitempty"`
+	CPU                     string                    `json:"cpu,omitempty"`
+	Memory                  string                    `json:"memory,omitempty"`
+	ContainerDefinitions    []ECSContainerDefinition  `json:"containerDefinitions"`
+	Volumes                 []ECSVolume               `json:"volumes,omitempty"`
+	PlacementConstraints    []ECSPlacementConstraint  `json:"placementConstraints,omitempty"`
+	PidMode                 string                    `json:"pidMode,omitempty"`
+	IpcMode                 string                    `json:"ipcMode,omitempty"`
+	ProxyConfiguration      *ECSProxyConfiguration    `json:"proxyConfiguration,omitempty"`
+	InferenceAccelerators   []ECSInferenceAccelerator `json:"inferenceAccelerators,omitempty"`
+	EphemeralStorage        *ECSEphemeralStorage      `json:"ephemeralStorage,omitempty"`
+	RuntimePlatform         *ECSRuntimePlatform       `json:"runtimePlatform,omitempty"`
+	EnableFaultInjection    *bool                     `json:"enableFaultInjection,omitempty"`
+	Tags                    []ECSTag                  `json:"tags,omitempty"`
+
+	// Read-only fields set by ECS when the task definition is registered
+	TaskDefinitionArn  string         `json:"taskDefinitionArn,omitempty"`
+	Revision           int            `json:"revision,omitempty"`
+	Status             string         `json:"status,omitempty"`
+	RequiresAttributes []ECSAttribute `json:"requiresAttributes,omitempty"`
+	Compatibilities    []string       `json:"compatibilities,omitempty"`
+	RegisteredAt       *ECSTimestamp  `json:"registeredAt,omitempty"`
+	RegisteredBy       string         `json:"registeredBy,omitempty"`
+	DeregisteredAt     *ECSTimestamp  `json:"deregisteredAt,omitempty"`
+	DeleteRequestedAt  *ECSTimestamp  `json:"deleteRequestedAt,omitempty"`
 }
 
 // ECSContainerDefinition represents an ECS container definition
 type ECSContainerDefinition struct {
-	Name              string               `json:"name"`
-	Image             string               `json:"image"`
-	CPU               int                  `json:"cpu,omitempty"`
-	Memory            int                  `json:"memory,omitempty"`
-	MemoryReservation int                  `json:"memoryReservation,omitempty"`
-	Essential         bool                 `json:"essential"`
-	PortMappings      []ECSPortMapping     `json:"portMappings,omitempty"`
-	Environment       []ECSKeyValuePair    `json:"environment,omitempty"`
-	Secrets           []ECSSecret          `json:"secrets,omitempty"`
-	MountPoints       []ECSMountPoint      `json:"mountPoints,omitempty"`
-	VolumesFrom       []ECSVolumeFrom      `json:"volumesFrom,omitempty"`
-	LogConfiguration  *ECSLogConfiguration `json:"logConfiguration,omitempty"`
-	Command           []string             `json:"command,omitempty"`
-	EntryPoint        []string             `json:"entryPoint,omitempty"`
-	WorkingDirectory  string               `json:"workingDirectory,omitempty"`
-	User              string               `json:"user,omitempty"`
+	Name                   string                    `json:"name"`
+	Image                  string                    `json:"image"`
+	RepositoryCredentials  *ECSRepositoryCredentials `json:"repositoryCredentials,omitempty"`
+	CPU                    int                       `json:"cpu,omitempty"`
+	Memory                 int                       `json:"memory,omitempty"`
+	MemoryReservation      int                       `json:"memoryReservation,omitempty"`
+	Links                  []string                  `json:"links,omitempty"`
+	Essential              bool                      `json:"essential"`
+	PortMappings           []ECSPortMapping          `json:"portMappings,omitempty"`
+	RestartPolicy          *ECSRestartPolicy         `json:"restartPolicy,omitempty"`
+	Environment            []ECSKeyValuePair         `json:"environment,omitempty"`
+	EnvironmentFiles       []ECSEnvironmentFile      `json:"environmentFiles,omitempty"`
+	Secrets                []ECSSecret               `json:"secrets,omitempty"`
+	MountPoints            []ECSMountPoint           `json:"mountPoints,omitempty"`
+	VolumesFrom            []ECSVolumeFrom           `json:"volumesFrom,omitempty"`
+	LogConfiguration       *ECSLogConfiguration      `json:"logConfiguration,omitempty"`
+	FirelensConfiguration  *ECSFirelensConfiguration `json:"firelensConfiguration,omitempty"`
+	Command                []string                  `json:"command,omitempty"`
+	EntryPoint             []string                  `json:"entryPoint,omitempty"`
+	WorkingDirectory       string                    `json:"workingDirectory,omitempty"`
+	User                   string                    `json:"user,omitempty"`
+	Hostname               string                    `json:"hostname,omitempty"`
+	DependsOn              []ECSContainerDependency  `json:"dependsOn,omitempty"`
+	StartTimeout           int                       `json:"startTimeout,omitempty"`
+	StopTimeout            int                       `json:"stopTimeout,omitempty"`
+	VersionConsistency     string                    `json:"versionConsistency,omitempty"`
+	HealthCheck            *ECSHealthCheck           `json:"healthCheck,omitempty"`
+	LinuxParameters        *ECSLinuxParameters       `json:"linuxParameters,omitempty"`
+	DisableNetworking      *bool                     `json:"disableNetworking,omitempty"`
+	Privileged             *bool                     `json:"privileged,omitempty"`
+	ReadonlyRootFilesystem *bool                     `json:"readonlyRootFilesystem,omitempty"`
+	Interactive            *bool                     `json:"interactive,omitempty"`
+	PseudoTerminal         *bool                     `json:"pseudoTerminal,omitempty"`
+	DNSServers             []string                  `json:"dnsServers,omitempty"`
+	DNSSearchDomains       []string                  `json:"dnsSearchDomains,omitempty"`
+	ExtraHosts             []ECSHostEntry            `json:"extraHosts,omitempty"`
+	DockerSecurityOptions  []string                  `json:"dockerSecurityOptions,omitempty"`
+	DockerLabels           map[string]string         `json:"dockerLabels,omitempty"`
+	Ulimits                []ECSUlimit               `json:"ulimits,omitempty"`
+	SystemControls         []ECSSystemControl        `json:"systemControls,omitempty"`
+	ResourceRequirements   []ECSResourceRequirement  `json:"resourceRequirements,omitempty"`
+	CredentialSpecs        []string                  `json:"credentialSpecs,omitempty"`
+}
+
+// ECSRepositoryCredentials represents private registry credentials stored in Secrets Manager
+type ECSRepositoryCredentials struct {
+	CredentialsParameter string `json:"credentialsParameter"`
 }
 
 // ECSPortMapping represents port mapping in ECS
 type ECSPortMapping struct {
-	ContainerPort int    `json:"containerPort"`
-	HostPort      int    `json:"hostPort,omitempty"`
-	Protocol      string `json:"protocol,omitempty"`
+	ContainerPort      int    `json:"containerPort,omitempty"`
+	HostPort           int    `json:"hostPort,omitempty"`
+	Protocol           string `json:"protocol,omitempty"`
+	Name               string `json:"name,omitempty"`
+	AppProtocol        string `json:"appProtocol,omitempty"`
+	ContainerPortRange string `json:"containerPortRange,omitempty"`
+}
+
+// ECSRestartPolicy represents the container restart policy
+type ECSRestartPolicy struct {
+	Enabled              bool  `json:"enabled"`
+	IgnoredExitCodes     []int `json:"ignoredExitCodes,omitempty"`
+	RestartAttemptPeriod int   `json:"restartAttemptPeriod,omitempty"`
 }
 
 // ECSKeyValuePair represents environment variables
@@ -59,6 +126,12 @@ type ECSKeyValuePair struct {
 	Value string `json:"value"`
 }
 
+// ECSEnvironmentFile represents an environment file stored in S3
+type ECSEnvironmentFile struct {
+	Value string `json:"value"`
+	Type  string `json:"type"`
+}
+
 // ECSSecret represents secrets from Parameter Store
 type ECSSecret struct {
 	Name      string `json:"name"`
@@ -80,14 +153,96 @@ type ECSVolumeFrom struct {
 
 // ECSLogConfiguration represents logging configuration
 type ECSLogConfiguration struct {
-	LogDriver string            `json:"logDriver"`
-	Options   map[string]string `json:"options,omitempty"`
+	LogDriver     string            `json:"logDriver"`
+	Options       map[string]string `json:"options,omitempty"`
+	SecretOptions []ECSSecret       `json:"secretOptions,omitempty"`
+}
+
+// ECSFirelensConfiguration represents FireLens log router configuration
+type ECSFirelensConfiguration struct {
+	Type    string            `json:"type"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// ECSContainerDependency represents a startup dependency on another container
+type ECSContainerDependency struct {
+	ContainerName string `json:"containerName"`
+	Condition     string `json:"condition"`
+}
+
+// ECSHealthCheck represents a container health check
+type ECSHealthCheck struct {
+	Command     []string `json:"command"`
+	Interval    int      `json:"interval,omitempty"`
+	Timeout     int      `json:"timeout,omitempty"`
+	Retries     int      `json:"retries,omitempty"`
+	StartPeriod int      `json:"startPeriod,omitempty"`
+}
+
+// ECSLinuxParameters represents Linux-specific container settings
+type ECSLinuxParameters struct {
+	Capabilities       *ECSKernelCapabilities `json:"capabilities,omitempty"`
+	Devices            []ECSDevice            `json:"devices,omitempty"`
+	InitProcessEnabled *bool                  `json:"initProcessEnabled,omitempty"`
+	SharedMemorySize   *int                   `json:"sharedMemorySize,omitempty"`
+	Tmpfs              []ECSTmpfs             `json:"tmpfs,omitempty"`
+	MaxSwap            *int                   `json:"maxSwap,omitempty"`
+	Swappiness         *int                   `json:"swappiness,omitempty"`
+}
+
+// ECSKernelCapabilities represents Linux capabilities added to or dropped from a container
+type ECSKernelCapabilities struct {
+	Add  []string `json:"add,omitempty"`
+	Drop []string `json:"drop,omitempty"`
+}
+
+// ECSDevice represents a host device exposed to a container
+type ECSDevice struct {
+	HostPath      string   `json:"hostPath"`
+	ContainerPath string   `json:"containerPath,omitempty"`
+	Permissions   []string `json:"permissions,omitempty"`
+}
+
+// ECSTmpfs represents a tmpfs mount
+type ECSTmpfs struct {
+	ContainerPath string   `json:"containerPath"`
+	Size          int      `json:"size"`
+	MountOptions  []string `json:"mountOptions,omitempty"`
+}
+
+// ECSHostEntry represents an /etc/hosts entry
+type ECSHostEntry struct {
+	Hostname  string `json:"hostname"`
+	IPAddress string `json:"ipAddress"`
+}
+
+// ECSUlimit represents a ulimit setting
+type ECSUlimit struct {
+	Name      string `json:"name"`
+	SoftLimit int    `json:"softLimit"`
+	HardLimit int    `json:"hardLimit"`
+}
+
+// ECSSystemControl represents a namespaced kernel parameter
+type ECSSystemControl struct {
+	Namespace string `json:"namespace,omitempty"`
+	Value     string `json:"value,omitempty"`
+}
+
+// ECSResourceRequirement represents a GPU or inference accelerator requirement
+type ECSResourceRequirement struct {
+	Value string `json:"value"`
+	Type  string `json:"type"`
 }
 
 // ECSVolume represents ECS volume definitions
 type ECSVolume struct {
-	Name string         `json:"name"`
-	Host *ECSHostVolume `json:"host,omitempty"`
+	Name                                    string                                      `json:"name"`
+	Host                                    *ECSHostVolume                              `json:"host,omitempty"`
+	ConfiguredAtLaunch                      *bool                                       `json:"configuredAtLaunch,omitempty"`
+	DockerVolumeConfiguration               *ECSDockerVolumeConfiguration               `json:"dockerVolumeConfiguration,omitempty"`
+	EFSVolumeConfiguration                  *ECSEFSVolumeConfiguration                  `json:"efsVolumeConfiguration,omitempty"`
+	FSxWindowsFileServerVolumeConfiguration *ECSFSxWindowsFileServerVolumeConfiguration `json:"fsxWindowsFileServerVolumeConfiguration,omitempty"` //nolint:lll
 }
 
 // ECSHostVolume represents host volume configuration
@@ -95,6 +250,81 @@ type ECSHostVolume struct {
 	SourcePath string `json:"sourcePath,omitempty"`
 }
 
+// ECSDockerVolumeConfiguration represents a Docker volume managed by a volume driver
+type ECSDockerVolumeConfiguration struct {
+	Scope         string            `json:"scope,omitempty"`
+	Autoprovision *bool             `json:"autoprovision,omitempty"`
+	Driver        string            `json:"driver,omitempty"`
+	DriverOpts    map[string]string `json:"driverOpts,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+}
+
+// ECSEFSVolumeConfiguration represents an Amazon EFS file system volume
+type ECSEFSVolumeConfiguration struct {
+	FileSystemID          string                     `json:"fileSystemId"`
+	RootDirectory         string                     `json:"rootDirectory,omitempty"`
+	TransitEncryption     string                     `json:"transitEncryption,omitempty"`
+	TransitEncryptionPort int                        `json:"transitEncryptionPort,omitempty"`
+	AuthorizationConfig   *ECSEFSAuthorizationConfig `json:"authorizationConfig,omitempty"`
+}
+
+// ECSEFSAuthorizationConfig represents EFS access point and IAM authorization
+type ECSEFSAuthorizationConfig struct {
+	AccessPointID string `json:"accessPointId,omitempty"`
+	IAM           string `json:"iam,omitempty"`
+}
+
+// ECSFSxWindowsFileServerVolumeConfiguration represents an FSx for Windows File Server volume
+type ECSFSxWindowsFileServerVolumeConfiguration struct {
+	FileSystemID        string                     `json:"fileSystemId"`
+	RootDirectory       string                     `json:"rootDirectory"`
+	AuthorizationConfig *ECSFSxAuthorizationConfig `json:"authorizationConfig"`
+}
+
+// ECSFSxAuthorizationConfig represents the credentials used to access an FSx volume
+type ECSFSxAuthorizationConfig struct {
+	CredentialsParameter string `json:"credentialsParameter"`
+	Domain               string `json:"domain"`
+}
+
+// ECSPlacementConstraint represents a task placement constraint
+type ECSPlacementConstraint struct {
+	Type       string `json:"type,omitempty"`
+	Expression string `json:"expression,omitempty"`
+}
+
+// ECSProxyConfiguration represents the App Mesh proxy configuration
+type ECSProxyConfiguration struct {
+	Type          string            `json:"type,omitempty"`
+	ContainerName string            `json:"containerName"`
+	Properties    []ECSKeyValuePair `json:"properties,omitempty"`
+}
+
+// ECSInferenceAccelerator represents an Elastic Inference accelerator
+type ECSInferenceAccelerator struct {
+	DeviceName string `json:"deviceName"`
+	DeviceType string `json:"deviceType"`
+}
+
+// ECSEphemeralStorage represents the amount of ephemeral storage for Fargate tasks
+type ECSEphemeralStorage struct {
+	SizeInGiB int `json:"sizeInGiB"`
+}
+
+// ECSRuntimePlatform represents the CPU architecture and operating system of the task
+type ECSRuntimePlatform struct {
+	CPUArchitecture       string `json:"cpuArchitecture,omitempty"`
+	OperatingSystemFamily string `json:"operatingSystemFamily,omitempty"`
+}
+
+// ECSAttribute represents a container instance attribute required by the task definition
+type ECSAttribute struct {
+	Name       string `json:"name"`
+	Value      string `json:"value,omitempty"`
+	TargetType string `json:"targetType,omitempty"`
+	TargetID   string `json:"targetId,omitempty"`
+}
+
 // ECSTag represents ECS resource tags
 type ECSTag struct {
 	Key   string `json:"key"`
@@ -106,6 +336,14 @@ type ConversionOptions struct {
 	// ParameterStorePrefix is the prefix for Parameter Store parameters
 	ParameterStorePrefix string
 
+	// SecretBackend selects how Secret references are mapped (default: SecretBackendParameterStore).
+	// ConfigMap references always use Parameter Store.
+	SecretBackend SecretBackend
+
+	// Region and AccountID are used to build ARNs, such as Secrets Manager secret ARNs
+	Region    string
+	AccountID string
+
 	// DefaultLogDriver is the default log driver to use
 	DefaultLogDriver string
 
@@ -120,4 +358,27 @@ type ConversionOptions struct {
 
 	// DefaultTaskRoleArn is used if not specified in the spec
 	DefaultTaskRoleArn string
+
+	// DefaultCPU is the task CPU used when neither the spec nor the pod annotations set one
+	DefaultCPU string
+
+	// DefaultMemory is the task memory used when neither the spec nor the pod annotations set one
+	DefaultMemory string
+
+	// ServiceAccounts looks up pod ServiceAccounts to read their IRSA role annotation
+	ServiceAccounts ServiceAccountResolver
+
+	// ServiceAccountRoles maps ServiceAccounts without an IRSA annotation to task roles
+	ServiceAccountRoles ServiceAccountRoles
+
+	// FamilyTemplate is a text/template rendered with FamilyTemplateData to name the
+	// family when ECSConfig.Family is empty (default: DefaultFamilyTemplate)
+	FamilyTemplate string
+
+	// MetadataRules propagate pod labels and annotations to tags and dockerLabels
+	MetadataRules []MetadataRule
+
+	// DefaultRequests fill the resource requests missing from containers, keyed by namespace;
+	// the entry AllNamespaces applies to namespaces without their own
+	DefaultRequests map[string]corev1.ResourceList
 }
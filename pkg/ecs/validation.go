@@ -0,0 +1,562 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValidationErrorCode identifies the kind of task definition validation failure
+type ValidationErrorCode string
+
+const (
+	// ValidationInvalidValue is reported for values outside the set accepted by ECS
+	ValidationInvalidValue ValidationErrorCode = "InvalidValue"
+	// ValidationRequired is reported for required fields that are empty
+	ValidationRequired ValidationErrorCode = "Required"
+	// ValidationInvalidFormat is reported for names, ARNs and paths that do not match the expected format
+	ValidationInvalidFormat ValidationErrorCode = "InvalidFormat"
+	// ValidationLimitExceeded is reported when a count or size exceeds an ECS limit
+	ValidationLimitExceeded ValidationErrorCode = "LimitExceeded"
+	// ValidationDuplicate is reported for names that must be unique within the task definition
+	ValidationDuplicate ValidationErrorCode = "Duplicate"
+	// ValidationConflict is reported for settings that are incompatible with each other
+	ValidationConflict ValidationErrorCode = "Conflict"
+	// ValidationNotFound is reported for references to containers that do not exist
+	ValidationNotFound ValidationErrorCode = "NotFound"
+	// ValidationCycle is reported for dependsOn chains that loop back to a container
+	ValidationCycle ValidationErrorCode = "Cycle"
+	// ValidationUnsupported is reported for pod fields ECS has no equivalent for
+	ValidationUnsupported ValidationErrorCode = "Unsupported"
+	// ValidationEnvironmentTooLarge is reported for containers whose environment variables exceed
+	// MaxContainerEnvironmentSize
+	ValidationEnvironmentTooLarge ValidationErrorCode = "EnvironmentTooLarge"
+)
+
+// Limits enforced by ECS on task definitions
+const (
+	MaxContainersPerTaskDefinition = 10
+	MaxNameLength                  = 255
+	MaxTaskDefinitionSize          = 64 * 1024
+	MaxParameterNameLength         = 2048
+	MaxContainerEnvironmentSize    = 32 * 1024
+)
+
+// ValidationError describes a single field of a task definition that ECS would reject
+type ValidationError struct {
+	Field   string              `json:"field"`
+	Code    ValidationErrorCode `json:"code"`
+	Message string              `json:"message"`
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors is a list of validation errors that is itself an error
+type ValidationErrors []*ValidationError
+
+// Error implements error
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationErrors) add(field string, code ValidationErrorCode, format string, args ...any) {
+	*e = append(*e, &ValidationError{
+		Field:   field,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// checkEnum records an error when value is set but not one of the allowed values
+func (e *ValidationErrors) checkEnum(field, value string, allowed []string) {
+	if value != "" && !slices.Contains(allowed, value) {
+		e.add(field, ValidationInvalidValue, "%q is not one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+// Values accepted by the RegisterTaskDefinition API for enumerated fields
+var (
+	NetworkModes         = []string{"none", "bridge", "awsvpc", "host"}
+	PidModes             = []string{"host", "task"}
+	IpcModes             = []string{"host", "task", "none"}
+	LaunchTypes          = []string{"EC2", "FARGATE", "EXTERNAL"}
+	PortProtocols        = []string{"tcp", "udp"}
+	AppProtocols         = []string{"http", "http2", "grpc"}
+	DependencyConditions = []string{"START", "COMPLETE", "SUCCESS", "HEALTHY"}
+	LogDrivers           = []string{
+		"json-file", "syslog", "journald", "gelf", "fluentd", "awslogs", "splunk", "awsfirelens",
+	}
+	FirelensTypes            = []string{"fluentd", "fluentbit"}
+	EnvironmentFileTypes     = []string{"s3"}
+	ResourceRequirementTypes = []string{"GPU", "InferenceAccelerator"}
+	DevicePermissions        = []string{"read", "write", "mknod"}
+	VersionConsistencies     = []string{"enabled", "disabled"}
+	DockerVolumeScopes       = []string{"task", "shared"}
+	EFSTransitEncryptions    = []string{"ENABLED", "DISABLED"}
+	EFSAuthorizationIAMs     = []string{"ENABLED", "DISABLED"}
+	PlacementConstraintTypes = []string{"memberOf"}
+	ProxyConfigurationTypes  = []string{"APPMESH"}
+	CPUArchitectures         = []string{"X86_64", "ARM64"}
+	OperatingSystemFamilies  = []string{
+		"LINUX",
+		"WINDOWS_SERVER_2019_FULL", "WINDOWS_SERVER_2019_CORE",
+		"WINDOWS_SERVER_2022_FULL", "WINDOWS_SERVER_2022_CORE",
+		"WINDOWS_SERVER_2025_FULL", "WINDOWS_SERVER_2025_CORE",
+		"WINDOWS_SERVER_2016_FULL", "WINDOWS_SERVER_2004_CORE", "WINDOWS_SERVER_20H2_CORE",
+	}
+	UlimitNames = []string{
+		"core", "cpu", "data", "fsize", "locks", "memlock", "msgqueue", "nice",
+		"nofile", "nproc", "rss", "rtprio", "rttime", "sigpending", "stack",
+	}
+	TaskDefinitionStatuses = []string{"ACTIVE", "INACTIVE", "DELETE_IN_PROGRESS"}
+
+	// SecretBackends lists the supported values of ConversionOptions.SecretBackend
+	SecretBackends = []string{string(SecretBackendParameterStore), string(SecretBackendSecretsManager)}
+)
+
+// ValidateEnums checks that every enumerated field holds a value accepted by ECS.
+// It returns nil when all values are valid.
+func (t *ECSTaskDefinition) ValidateEnums() ValidationErrors {
+	var errs ValidationErrors
+
+	errs.checkEnum("networkMode", t.NetworkMode, NetworkModes)
+	errs.checkEnum("pidMode", t.PidMode, PidModes)
+	errs.checkEnum("ipcMode", t.IpcMode, IpcModes)
+	errs.checkEnum("status", t.Status, TaskDefinitionStatuses)
+	for i, compat := range t.RequiresCompatibilities {
+		errs.checkEnum(fmt.Sprintf("requiresCompatibilities[%d]", i), compat, LaunchTypes)
+	}
+	for i, constraint := range t.PlacementConstraints {
+		errs.checkEnum(fmt.Sprintf("placementConstraints[%d].type", i), constraint.Type, PlacementConstraintTypes)
+	}
+	if t.ProxyConfiguration != nil {
+		errs.checkEnum("proxyConfiguration.type", t.ProxyConfiguration.Type, ProxyConfigurationTypes)
+	}
+	if t.RuntimePlatform != nil {
+		errs.checkEnum("runtimePlatform.cpuArchitecture", t.RuntimePlatform.CPUArchitecture, CPUArchitectures)
+		errs.checkEnum("runtimePlatform.operatingSystemFamily",
+			t.RuntimePlatform.OperatingSystemFamily, OperatingSystemFamilies)
+	}
+
+	for i := range t.ContainerDefinitions {
+		t.ContainerDefinitions[i].validateEnums(fmt.Sprintf("containerDefinitions[%d]", i), &errs)
+	}
+
+	for i, volume := range t.Volumes {
+		field := fmt.Sprintf("volumes[%d]", i)
+		if config := volume.DockerVolumeConfiguration; config != nil {
+			errs.checkEnum(field+".dockerVolumeConfiguration.scope", config.Scope, DockerVolumeScopes)
+		}
+		if config := volume.EFSVolumeConfiguration; config != nil {
+			errs.checkEnum(field+".efsVolumeConfiguration.transitEncryption",
+				config.TransitEncryption, EFSTransitEncryptions)
+			if config.AuthorizationConfig != nil {
+				errs.checkEnum(field+".efsVolumeConfiguration.authorizationConfig.iam",
+					config.AuthorizationConfig.IAM, EFSAuthorizationIAMs)
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (c *ECSContainerDefinition) validateEnums(field string, errs *ValidationErrors) {
+	for i, port := range c.PortMappings {
+		errs.checkEnum(fmt.Sprintf("%s.portMappings[%d].protocol", field, i), port.Protocol, PortProtocols)
+		errs.checkEnum(fmt.Sprintf("%s.portMappings[%d].appProtocol", field, i), port.AppProtocol, AppProtocols)
+	}
+	for i, dependency := range c.DependsOn {
+		errs.checkEnum(fmt.Sprintf("%s.dependsOn[%d].condition", field, i), dependency.Condition, DependencyConditions)
+	}
+	for i, file := range c.EnvironmentFiles {
+		errs.checkEnum(fmt.Sprintf("%s.environmentFiles[%d].type", field, i), file.Type, EnvironmentFileTypes)
+	}
+	for i, ulimit := range c.Ulimits {
+		errs.checkEnum(fmt.Sprintf("%s.ulimits[%d].name", field, i), ulimit.Name, UlimitNames)
+	}
+	for i, requirement := range c.ResourceRequirements {
+		errs.checkEnum(fmt.Sprintf("%s.resourceRequirements[%d].type", field, i),
+			requirement.Type, ResourceRequirementTypes)
+	}
+	if c.LogConfiguration != nil {
+		errs.checkEnum(field+".logConfiguration.logDriver", c.LogConfiguration.LogDriver, LogDrivers)
+	}
+	if c.FirelensConfiguration != nil {
+		errs.checkEnum(field+".firelensConfiguration.type", c.FirelensConfiguration.Type, FirelensTypes)
+	}
+	errs.checkEnum(field+".versionConsistency", c.VersionConsistency, VersionConsistencies)
+	if c.LinuxParameters != nil {
+		for i, device := range c.LinuxParameters.Devices {
+			for j, permission := range device.Permissions {
+				errs.checkEnum(fmt.Sprintf("%s.linuxParameters.devices[%d].permissions[%d]", field, i, j),
+					permission, DevicePermissions)
+			}
+		}
+	}
+}
+
+var (
+	// ecsNamePattern matches family and container names
+	ecsNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	// parameterNamePattern matches Parameter Store parameter names
+	parameterNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_./-]+$`)
+	// ssmParameterArnPattern matches Parameter Store parameter ARNs
+	ssmParameterArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:ssm:[a-z0-9-]+:\d{12}:parameter/[a-zA-Z0-9_./-]+$`)
+	// secretsManagerArnPattern matches Secrets Manager secret ARNs, optionally followed by
+	// the json-key:version-stage:version-id suffix
+	secretsManagerArnPattern = regexp.MustCompile(
+		`^arn:aws[a-z-]*:secretsmanager:[a-z0-9-]+:\d{12}:secret:[a-zA-Z0-9/_+=.@-]+(:[^:]*:[^:]*:[^:]*)?$`)
+)
+
+// fargateMemoryByCPU lists the memory values (MiB) accepted by Fargate for each task CPU value
+var fargateMemoryByCPU = map[int][]int{
+	256:   {512, 1024, 2048},
+	512:   memoryRange(1024, 4096, 1024),
+	1024:  memoryRange(2048, 8192, 1024),
+	2048:  memoryRange(4096, 16384, 1024),
+	4096:  memoryRange(8192, 30720, 1024),
+	8192:  memoryRange(16384, 61440, 4096),
+	16384: memoryRange(32768, 122880, 8192),
+}
+
+func memoryRange(from, to, step int) []int {
+	var values []int
+	for value := from; value <= to; value += step {
+		values = append(values, value)
+	}
+	return values
+}
+
+// Validate checks the task definition against the limits ECS enforces on
+// RegisterTaskDefinition, in addition to the enum checks of ValidateEnums.
+// It returns nil when no problems are found.
+func (t *ECSTaskDefinition) Validate() ValidationErrors {
+	errs := t.ValidateEnums()
+
+	errs.checkName("family", t.Family)
+	t.validateContainers(&errs)
+	t.validatePorts(&errs)
+	t.validateDependencies(&errs)
+	t.validateSize(&errs)
+	t.validateTags(&errs)
+	if slices.Contains(t.RequiresCompatibilities, "FARGATE") {
+		t.validateFargate(&errs)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (e *ValidationErrors) checkName(field, name string) {
+	switch {
+	case name == "":
+		e.add(field, ValidationRequired, "must not be empty")
+	case len(name) > MaxNameLength:
+		e.add(field, ValidationLimitExceeded, "must be at most %d characters, got %d", MaxNameLength, len(name))
+	case !ecsNamePattern.MatchString(name):
+		e.add(field, ValidationInvalidFormat,
+			"%q may only contain letters, numbers, hyphens and underscores", name)
+	}
+}
+
+// checkSecretReference validates the valueFrom of a secret, which is either a Parameter
+// Store parameter name or the ARN of a parameter or Secrets Manager secret
+func (e *ValidationErrors) checkSecretReference(field, valueFrom string) {
+	switch {
+	case valueFrom == "":
+		e.add(field, ValidationRequired, "must not be empty")
+	case strings.HasPrefix(valueFrom, "arn:"):
+		if !ssmParameterArnPattern.MatchString(valueFrom) && !secretsManagerArnPattern.MatchString(valueFrom) {
+			e.add(field, ValidationInvalidFormat,
+				"%q is not a Parameter Store parameter or Secrets Manager secret ARN", valueFrom)
+		}
+	case len(valueFrom) > MaxParameterNameLength:
+		e.add(field, ValidationLimitExceeded,
+			"parameter name must be at most %d characters, got %d", MaxParameterNameLength, len(valueFrom))
+	case !parameterNamePattern.MatchString(valueFrom):
+		e.add(field, ValidationInvalidFormat, "%q is not a valid Parameter Store parameter name", valueFrom)
+	}
+}
+
+func (t *ECSTaskDefinition) validateContainers(errs *ValidationErrors) {
+	switch {
+	case len(t.ContainerDefinitions) == 0:
+		errs.add("containerDefinitions", ValidationRequired, "at least one container is required")
+		return
+	case len(t.ContainerDefinitions) > MaxContainersPerTaskDefinition:
+		errs.add("containerDefinitions", ValidationLimitExceeded, "at most %d containers are allowed, got %d",
+			MaxContainersPerTaskDefinition, len(t.ContainerDefinitions))
+	}
+
+	names := map[string]int{}
+	essential := false
+	for i := range t.ContainerDefinitions {
+		container := &t.ContainerDefinitions[i]
+		field := fmt.Sprintf("containerDefinitions[%d]", i)
+
+		errs.checkName(field+".name", container.Name)
+		if first, ok := names[container.Name]; ok && container.Name != "" {
+			errs.add(field+".name", ValidationDuplicate,
+				"container name %q is already used by containerDefinitions[%d]", container.Name, first)
+		} else {
+			names[container.Name] = i
+		}
+		if container.Image == "" {
+			errs.add(field+".image", ValidationRequired, "must not be empty")
+		}
+		essential = essential || container.Essential
+
+		environmentSize := 0
+		for _, env := range container.Environment {
+			environmentSize += len(env.Name) + len("=") + len(env.Value)
+		}
+		if environmentSize > MaxContainerEnvironmentSize {
+			errs.add(field+".environment", ValidationEnvironmentTooLarge,
+				"environment variables are %d bytes, exceeding the %d byte limit per container; "+
+					"move large values to Parameter Store secrets or environmentFiles",
+				environmentSize, MaxContainerEnvironmentSize)
+		}
+
+		for j, secret := range container.Secrets {
+			errs.checkSecretReference(fmt.Sprintf("%s.secrets[%d].valueFrom", field, j), secret.ValueFrom)
+		}
+		if container.LogConfiguration != nil {
+			for j, secret := range container.LogConfiguration.SecretOptions {
+				errs.checkSecretReference(
+					fmt.Sprintf("%s.logConfiguration.secretOptions[%d].valueFrom", field, j), secret.ValueFrom)
+			}
+		}
+	}
+
+	if !essential {
+		errs.add("containerDefinitions", ValidationRequired, "at least one container must be essential")
+	}
+}
+
+// validatePorts reports ports bound twice in the task's network namespace and, for awsvpc
+// and host networking, host ports that differ from the container port
+func (t *ECSTaskDefinition) validatePorts(errs *ValidationErrors) {
+	networkMode := t.networkModeOrDefault()
+	sharedNamespace := networkMode == "awsvpc" || networkMode == "host"
+
+	used := map[string]string{}
+	for i, container := range t.ContainerDefinitions {
+		for j, port := range container.PortMappings {
+			field := fmt.Sprintf("containerDefinitions[%d].portMappings[%d]", i, j)
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = "tcp"
+			}
+
+			if sharedNamespace && port.HostPort != 0 && port.HostPort != port.ContainerPort {
+				errs.add(field+".hostPort", ValidationConflict,
+					"must equal containerPort %d in %s network mode, got %d",
+					port.ContainerPort, networkMode, port.HostPort)
+			}
+
+			// Only ports bound on the host (or the shared task namespace) can conflict
+			bound := port.HostPort
+			if sharedNamespace {
+				bound = port.ContainerPort
+			}
+			if bound == 0 {
+				continue
+			}
+			key := fmt.Sprintf("%d/%s", bound, protocol)
+			if other, ok := used[key]; ok {
+				errs.add(field, ValidationConflict, "port %s is already bound by %s", key, other)
+				continue
+			}
+			used[key] = field
+		}
+	}
+}
+
+// validateDependencies reports dependsOn references to unknown containers and cycles
+func (t *ECSTaskDefinition) validateDependencies(errs *ValidationErrors) {
+	index := map[string]int{}
+	for i, container := range t.ContainerDefinitions {
+		index[container.Name] = i
+	}
+
+	for i, container := range t.ContainerDefinitions {
+		for j, dependency := range container.DependsOn {
+			if _, ok := index[dependency.ContainerName]; !ok {
+				errs.add(fmt.Sprintf("containerDefinitions[%d].dependsOn[%d].containerName", i, j),
+					ValidationNotFound, "container %q is not defined in the task", dependency.ContainerName)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(t.ContainerDefinitions))
+	var path []string
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = visiting
+		path = append(path, t.ContainerDefinitions[i].Name)
+		for _, dependency := range t.ContainerDefinitions[i].DependsOn {
+			next, ok := index[dependency.ContainerName]
+			if !ok {
+				continue
+			}
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case visiting:
+				start := slices.Index(path, dependency.ContainerName)
+				cycle := append(slices.Clone(path[start:]), dependency.ContainerName)
+				errs.add(fmt.Sprintf("containerDefinitions[%d].dependsOn", i), ValidationCycle,
+					"dependency cycle %s", strings.Join(cycle, " -> "))
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+	}
+	for i := range t.ContainerDefinitions {
+		if state[i] == unvisited {
+			visit(i)
+		}
+	}
+}
+
+// validateSize reports task definitions larger than ECS accepts. Environment variables
+// usually dominate the size, so their share is included in the message.
+func (t *ECSTaskDefinition) validateSize(errs *ValidationErrors) {
+	data, err := json.Marshal(t.RegisterInput())
+	if err != nil || len(data) <= MaxTaskDefinitionSize {
+		return
+	}
+
+	environmentSize := 0
+	for _, container := range t.ContainerDefinitions {
+		if env, err := json.Marshal(container.Environment); err == nil && len(container.Environment) > 0 {
+			environmentSize += len(env)
+		}
+	}
+	errs.add("containerDefinitions", ValidationLimitExceeded,
+		"task definition is %d bytes (environment variables: %d bytes), exceeding the %d byte limit; "+
+			"move large values to Parameter Store secrets or environmentFiles",
+		len(data), environmentSize, MaxTaskDefinitionSize)
+}
+
+// validateTags checks the tag count and the key and value rules of ECS tags
+func (t *ECSTaskDefinition) validateTags(errs *ValidationErrors) {
+	if len(t.Tags) > MaxTagsPerResource {
+		errs.add("tags", ValidationLimitExceeded, "at most %d tags are allowed, got %d",
+			MaxTagsPerResource, len(t.Tags))
+	}
+
+	keys := map[string]int{}
+	for i, tag := range t.Tags {
+		field := fmt.Sprintf("tags[%d]", i)
+		switch {
+		case tag.Key == "":
+			errs.add(field+".key", ValidationRequired, "must not be empty")
+		case tag.Key != SanitizeTagKey(tag.Key):
+			errs.add(field+".key", ValidationInvalidFormat,
+				"%q must be at most %d characters of letters, numbers, spaces and _.:/=+-@, "+
+					"and must not start with aws:", tag.Key, MaxTagKeyLength)
+		}
+		if tag.Value != SanitizeTagValue(tag.Value) {
+			errs.add(field+".value", ValidationInvalidFormat,
+				"must be at most %d characters of letters, numbers, spaces and _.:/=+-@", MaxTagValueLength)
+		}
+		if first, ok := keys[tag.Key]; ok {
+			errs.add(field+".key", ValidationDuplicate, "tag %q is already defined by tags[%d]", tag.Key, first)
+		} else {
+			keys[tag.Key] = i
+		}
+	}
+}
+
+// validateFargate checks the task size requirements of the FARGATE launch type
+func (t *ECSTaskDefinition) validateFargate(errs *ValidationErrors) {
+	cpu, cpuOK := parseTaskCPU(t.CPU)
+	memory, memoryOK := parseTaskMemory(t.Memory)
+	if t.CPU == "" {
+		errs.add("cpu", ValidationRequired, "task-level cpu is required for FARGATE")
+	} else if !cpuOK {
+		errs.add("cpu", ValidationInvalidFormat, "%q is not a valid CPU value", t.CPU)
+	}
+	if t.Memory == "" {
+		errs.add("memory", ValidationRequired, "task-level memory is required for FARGATE")
+	} else if !memoryOK {
+		errs.add("memory", ValidationInvalidFormat, "%q is not a valid memory value", t.Memory)
+	}
+	if !cpuOK || !memoryOK {
+		return
+	}
+
+	allowed, ok := fargateMemoryByCPU[cpu]
+	if !ok {
+		values := slices.Sorted(maps.Keys(fargateMemoryByCPU))
+		cpus := make([]string, 0, len(values))
+		for _, value := range values {
+			cpus = append(cpus, strconv.Itoa(value))
+		}
+		errs.add("cpu", ValidationInvalidValue, "%d is not a FARGATE CPU value (%s)", cpu, strings.Join(cpus, ", "))
+		return
+	}
+	if !slices.Contains(allowed, memory) {
+		errs.add("memory", ValidationInvalidValue, "%d MiB is not supported with %d CPU units on FARGATE (%d-%d MiB)",
+			memory, cpu, allowed[0], allowed[len(allowed)-1])
+	}
+}
+
+// networkModeOrDefault returns the network mode, or the one ECS uses when none is set:
+// awsvpc for FARGATE tasks and bridge otherwise
+func (t *ECSTaskDefinition) networkModeOrDefault() string {
+	switch {
+	case t.NetworkMode != "":
+		return t.NetworkMode
+	case slices.Contains(t.RequiresCompatibilities, "FARGATE"):
+		return "awsvpc"
+	default:
+		return "bridge"
+	}
+}
+
+// parseTaskCPU parses a task CPU value given in CPU units ("1024") or vCPUs ("1 vCPU")
+func parseTaskCPU(value string) (int, bool) {
+	return parseTaskResource(value, "vcpu")
+}
+
+// parseTaskMemory parses a task memory value given in MiB ("2048") or GB ("2 GB")
+func parseTaskMemory(value string) (int, bool) {
+	return parseTaskResource(value, "gb")
+}
+
+func parseTaskResource(value, unit string) (int, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	multiplier := 1.0
+	if trimmed, found := strings.CutSuffix(value, unit); found {
+		value = strings.TrimSpace(trimmed)
+		multiplier = 1024
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number <= 0 {
+		return 0, false
+	}
+	return int(number * multiplier), true
+}
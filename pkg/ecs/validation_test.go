@@ -0,0 +1,247 @@
+package ecs
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func newValidTaskDefinition() *ECSTaskDefinition {
+	return &ECSTaskDefinition{
+		Family:                  "web-app",
+		NetworkMode:             "awsvpc",
+		RequiresCompatibilities: []string{"FARGATE"},
+		CPU:                     "512",
+		Memory:                  "1024",
+		ContainerDefinitions: []ECSContainerDefinition{
+			{
+				Name:         "app",
+				Image:        "app:1.0",
+				Essential:    true,
+				PortMappings: []ECSPortMapping{{ContainerPort: 8080, Protocol: "tcp"}},
+				DependsOn:    []ECSContainerDependency{{ContainerName: "init", Condition: "SUCCESS"}},
+				Secrets: []ECSSecret{
+					{Name: "DB_PASSWORD", ValueFrom: "/pods/default/secrets/db/password"},
+					{Name: "API_KEY", ValueFrom: "arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:api-AbCdEf"},
+					{Name: "TOKEN", ValueFrom: "arn:aws:ssm:ap-northeast-1:123456789012:parameter/pods/token"},
+				},
+			},
+			{
+				Name:  "init",
+				Image: "init:1.0",
+			},
+		},
+	}
+}
+
+func TestECSTaskDefinition_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*ECSTaskDefinition)
+		want   []string // "field:code" of the expected errors
+	}{
+		{
+			name:   "valid",
+			modify: func(*ECSTaskDefinition) {},
+		},
+		{
+			name: "vCPU and GB notation",
+			modify: func(td *ECSTaskDefinition) {
+				td.CPU = "1 vCPU"
+				td.Memory = "2 GB"
+			},
+		},
+		{
+			name:   "invalid family",
+			modify: func(td *ECSTaskDefinition) { td.Family = "web.app" },
+			want:   []string{"family:InvalidFormat"},
+		},
+		{
+			name:   "family too long",
+			modify: func(td *ECSTaskDefinition) { td.Family = strings.Repeat("a", 256) },
+			want:   []string{"family:LimitExceeded"},
+		},
+		{
+			name: "too many containers",
+			modify: func(td *ECSTaskDefinition) {
+				for i := 0; i < 9; i++ {
+					td.ContainerDefinitions = append(td.ContainerDefinitions, ECSContainerDefinition{
+						Name: fmt.Sprintf("sidecar-%d", i), Image: "sidecar",
+					})
+				}
+			},
+			want: []string{"containerDefinitions:LimitExceeded"},
+		},
+		{
+			name: "duplicate container name and missing image",
+			modify: func(td *ECSTaskDefinition) {
+				td.ContainerDefinitions[0].DependsOn = nil
+				td.ContainerDefinitions[1].Name = "app"
+				td.ContainerDefinitions[1].Image = ""
+			},
+			want: []string{"containerDefinitions[1].name:Duplicate", "containerDefinitions[1].image:Required"},
+		},
+		{
+			name:   "no essential container",
+			modify: func(td *ECSTaskDefinition) { td.ContainerDefinitions[0].Essential = false },
+			want:   []string{"containerDefinitions:Required"},
+		},
+		{
+			name: "port conflict and host port mismatch",
+			modify: func(td *ECSTaskDefinition) {
+				td.ContainerDefinitions[1].PortMappings = []ECSPortMapping{{ContainerPort: 8080, HostPort: 80}}
+			},
+			want: []string{
+				"containerDefinitions[1].portMappings[0].hostPort:Conflict",
+				"containerDefinitions[1].portMappings[0]:Conflict",
+			},
+		},
+		{
+			name: "bridge mode allows dynamic host ports",
+			modify: func(td *ECSTaskDefinition) {
+				td.RequiresCompatibilities = []string{"EC2"}
+				td.NetworkMode = "bridge"
+				td.ContainerDefinitions[0].PortMappings[0].HostPort = 80
+				td.ContainerDefinitions[1].PortMappings = []ECSPortMapping{{ContainerPort: 8080}}
+			},
+		},
+		{
+			name: "network mode defaults to bridge on EC2",
+			modify: func(td *ECSTaskDefinition) {
+				td.RequiresCompatibilities = []string{"EC2"}
+				td.NetworkMode = ""
+				td.CPU = ""
+				td.Memory = ""
+				td.ContainerDefinitions[0].PortMappings[0].HostPort = 80
+				td.ContainerDefinitions[1].PortMappings = []ECSPortMapping{{ContainerPort: 8080}}
+			},
+		},
+		{
+			name: "network mode defaults to awsvpc on FARGATE",
+			modify: func(td *ECSTaskDefinition) {
+				td.NetworkMode = ""
+				td.ContainerDefinitions[0].PortMappings[0].HostPort = 80
+			},
+			want: []string{"containerDefinitions[0].portMappings[0].hostPort:Conflict"},
+		},
+		{
+			name: "invalid fargate memory",
+			modify: func(td *ECSTaskDefinition) {
+				td.CPU = "256"
+				td.Memory = "4096"
+			},
+			want: []string{"memory:InvalidValue"},
+		},
+		{
+			name: "fargate requires task size",
+			modify: func(td *ECSTaskDefinition) {
+				td.CPU = ""
+				td.Memory = ""
+			},
+			want: []string{"cpu:Required", "memory:Required"},
+		},
+		{
+			name: "invalid secret references",
+			modify: func(td *ECSTaskDefinition) {
+				td.ContainerDefinitions[0].Secrets[0].ValueFrom = "/pods/default/secrets/db password"
+				td.ContainerDefinitions[0].Secrets[1].ValueFrom = "arn:aws:s3:::bucket/key"
+			},
+			want: []string{
+				"containerDefinitions[0].secrets[0].valueFrom:InvalidFormat",
+				"containerDefinitions[0].secrets[1].valueFrom:InvalidFormat",
+			},
+		},
+		{
+			name: "dependency cycle",
+			modify: func(td *ECSTaskDefinition) {
+				td.ContainerDefinitions[1].DependsOn = []ECSContainerDependency{
+					{ContainerName: "app", Condition: "START"},
+				}
+			},
+			want: []string{"containerDefinitions[1].dependsOn:Cycle"},
+		},
+		{
+			name: "unknown dependency",
+			modify: func(td *ECSTaskDefinition) {
+				td.ContainerDefinitions[0].DependsOn[0].ContainerName = "db"
+			},
+			want: []string{"containerDefinitions[0].dependsOn[0].containerName:NotFound"},
+		},
+		{
+			name: "environment too large",
+			modify: func(td *ECSTaskDefinition) {
+				td.ContainerDefinitions[0].Environment = []ECSKeyValuePair{
+					{Name: "LARGE", Value: strings.Repeat("x", MaxTaskDefinitionSize)},
+				}
+			},
+			want: []string{"containerDefinitions[0].environment:EnvironmentTooLarge", "containerDefinitions:LimitExceeded"},
+		},
+		{
+			name: "container environment too large",
+			modify: func(td *ECSTaskDefinition) {
+				td.ContainerDefinitions[1].Environment = []ECSKeyValuePair{
+					{Name: "LARGE", Value: strings.Repeat("x", MaxContainerEnvironmentSize)},
+				}
+			},
+			want: []string{"containerDefinitions[1].environment:EnvironmentTooLarge"},
+		},
+		{
+			name: "container environment at the limit",
+			modify: func(td *ECSTaskDefinition) {
+				td.ContainerDefinitions[1].Environment = []ECSKeyValuePair{
+					{Name: "LARGE", Value: strings.Repeat("x", MaxContainerEnvironmentSize-len("LARGE="))},
+				}
+			},
+		},
+		{
+			name: "invalid tags",
+			modify: func(td *ECSTaskDefinition) {
+				td.Tags = []ECSTag{
+					{Key: "team", Value: "platform"},
+					{Key: "aws:owner", Value: "alice"},
+					{Key: "team", Value: "<none>"},
+				}
+			},
+			want: []string{"tags[1].key:InvalidFormat", "tags[2].value:InvalidFormat", "tags[2].key:Duplicate"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskDef := newValidTaskDefinition()
+			tt.modify(taskDef)
+
+			errs := taskDef.Validate()
+			got := make([]string, 0, len(errs))
+			for _, err := range errs {
+				got = append(got, fmt.Sprintf("%s:%s", err.Field, err.Code))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Validate() = %v, want %v", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTaskCPUAndMemory(t *testing.T) {
+	tests := []struct {
+		value string
+		parse func(string) (int, bool)
+		want  int
+		ok    bool
+	}{
+		{"1024", parseTaskCPU, 1024, true},
+		{".25 vCPU", parseTaskCPU, 256, true},
+		{"2 vcpu", parseTaskCPU, 2048, true},
+		{"0.5GB", parseTaskMemory, 512, true},
+		{"3072", parseTaskMemory, 3072, true},
+		{"lots", parseTaskMemory, 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := tt.parse(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parse(%q) = %d, %v, want %d, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
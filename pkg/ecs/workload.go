@@ -0,0 +1,100 @@
+package ecs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ErrUnsupportedKind is returned by DecodeWorkload for objects that do not run pods
+var ErrUnsupportedKind = errors.New("kind does not run pods")
+
+// WorkloadKinds lists the kinds DecodeWorkload converts
+var WorkloadKinds = []string{
+	"Pod", XPodKind, "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job", "CronJob",
+}
+
+// DecodeWorkload decodes a JSON encoded object of one of the WorkloadKinds into the pod it
+// runs and the ECS configuration it carries. Pods of workloads are built from the pod
+// template, owned by the workload so that the family template names them after it.
+func DecodeWorkload(typeMeta metav1.TypeMeta, data []byte) (*corev1.Pod, *ECSConfig, error) {
+	switch typeMeta.Kind {
+	case XPodKind:
+		xpod, err := ParseXPod(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return xpod.Pod(), &xpod.ECSConfig, nil
+	case "Pod":
+		var pod corev1.Pod
+		if err := json.Unmarshal(data, &pod); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse Pod: %w", err)
+		}
+		return &pod, &ECSConfig{}, nil
+	case "Deployment":
+		var deployment appsv1.Deployment
+		return decodeTemplate(data, &deployment, &deployment.ObjectMeta, &deployment.Spec.Template)
+	case "StatefulSet":
+		var statefulSet appsv1.StatefulSet
+		return decodeTemplate(data, &statefulSet, &statefulSet.ObjectMeta, &statefulSet.Spec.Template)
+	case "DaemonSet":
+		var daemonSet appsv1.DaemonSet
+		return decodeTemplate(data, &daemonSet, &daemonSet.ObjectMeta, &daemonSet.Spec.Template)
+	case "ReplicaSet":
+		var replicaSet appsv1.ReplicaSet
+		return decodeTemplate(data, &replicaSet, &replicaSet.ObjectMeta, &replicaSet.Spec.Template)
+	case "Job":
+		var job batchv1.Job
+		return decodeTemplate(data, &job, &job.ObjectMeta, &job.Spec.Template)
+	case "CronJob":
+		var cronJob batchv1.CronJob
+		return decodeTemplate(data, &cronJob, &cronJob.ObjectMeta, &cronJob.Spec.JobTemplate.Spec.Template)
+	default:
+		return nil, nil, fmt.Errorf("%s: %w", typeMeta.Kind, ErrUnsupportedKind)
+	}
+}
+
+// decodeTemplate unmarshals a workload into object and builds the pod of its template
+func decodeTemplate(
+	data []byte,
+	object any,
+	meta *metav1.ObjectMeta,
+	template *corev1.PodTemplateSpec,
+) (*corev1.Pod, *ECSConfig, error) {
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(data, &typeMeta); err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(data, object); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", typeMeta.Kind, err)
+	}
+
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: *template.ObjectMeta.DeepCopy(),
+		Spec:       *template.Spec.DeepCopy(),
+	}
+	pod.Name = meta.Name
+	pod.Namespace = meta.Namespace
+
+	controller := true
+	gv, err := schema.ParseGroupVersion(typeMeta.APIVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	pod.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: gv.String(),
+		Kind:       typeMeta.Kind,
+		Name:       meta.Name,
+		UID:        meta.UID,
+		Controller: &controller,
+	}}
+
+	return pod, &ECSConfig{}, nil
+}
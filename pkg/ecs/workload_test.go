@@ -0,0 +1,90 @@
+package ecs
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+func TestDecodeWorkload(t *testing.T) {
+	tests := []struct {
+		name       string
+		manifest   string
+		wantFamily string
+		wantImage  string
+	}{
+		{
+			name: "deployment",
+			manifest: `apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, namespace: shop}
+spec:
+  selector: {matchLabels: {app: web}}
+  template:
+    metadata: {labels: {app: web}}
+    spec: {containers: [{name: web, image: web:1.0}]}`,
+			wantFamily: "shop-web" + ownerSuffix("Deployment", "web"),
+			wantImage:  "web:1.0",
+		},
+		{
+			name: "cronjob",
+			manifest: `apiVersion: batch/v1
+kind: CronJob
+metadata: {name: report, namespace: shop}
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec: {restartPolicy: OnFailure, containers: [{name: report, image: report:1.0}]}`,
+			wantFamily: "shop-report" + ownerSuffix("CronJob", "report"),
+			wantImage:  "report:1.0",
+		},
+		{
+			name: "pod",
+			manifest: "apiVersion: v1\nkind: Pod\nmetadata: {name: debug, namespace: shop}\n" +
+				"spec: {containers: [{name: a, image: a:1}]}",
+			wantFamily: "shop-debug" + ownerSuffix("Pod", "debug"),
+			wantImage:  "a:1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := yaml.ToJSON([]byte(tt.manifest))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var typeMeta metav1.TypeMeta
+			if err := yaml.Unmarshal(data, &typeMeta); err != nil {
+				t.Fatal(err)
+			}
+
+			pod, ecsConfig, err := DecodeWorkload(typeMeta, data)
+			if err != nil {
+				t.Fatalf("DecodeWorkload() error = %v", err)
+			}
+			if pod.Spec.Containers[0].Image != tt.wantImage {
+				t.Errorf("image = %q, want %q", pod.Spec.Containers[0].Image, tt.wantImage)
+			}
+
+			converter := NewConverter(ConversionOptions{})
+			taskDef, err := converter.ConvertPod(pod, &pod.Spec, ecsConfig, pod.Namespace)
+			if err != nil {
+				t.Fatalf("ConvertPod() error = %v", err)
+			}
+			if taskDef.Family != tt.wantFamily {
+				t.Errorf("Family = %q, want %q", taskDef.Family, tt.wantFamily)
+			}
+		})
+	}
+}
+
+func TestDecodeWorkload_UnsupportedKind(t *testing.T) {
+	_, _, err := DecodeWorkload(metav1.TypeMeta{APIVersion: "v1", Kind: "Service"}, []byte(`{}`))
+	if !errors.Is(err, ErrUnsupportedKind) {
+		t.Errorf("DecodeWorkload() error = %v, want ErrUnsupportedKind", err)
+	}
+}
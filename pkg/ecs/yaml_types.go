@@ -1,11 +1,38 @@
 package ecs
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// XPodAPIVersion is the apiVersion of XPod documents
+	XPodAPIVersion = "ecs.takutakahashi.dev/v1alpha1"
+	// XPodKind is the kind of XPod documents
+	XPodKind = "XPod"
 )
 
+// XPod is a Kubernetes Pod with additional ECS configuration. Like Kubernetes objects it is
+// decoded from YAML through JSON, so the embedded corev1 types keep their JSON field names.
+type XPod struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              corev1.PodSpec `json:"spec"`
+
+	// ECS-specific configuration, including per-container overrides
+	ECSConfig ECSConfig `json:"ecsConfig,omitempty"`
+}
+
 // PodWithECSConfig represents a Kubernetes Pod with additional ECS configuration
+//
+// Deprecated: use XPod, which is decoded and validated by ParseXPod, and ConvertFromXPod.
 type PodWithECSConfig struct {
 	// Standard Kubernetes Pod fields
 	metav1.TypeMeta   `yaml:",inline"`
@@ -17,12 +44,106 @@ type PodWithECSConfig struct {
 	ECSConfig ECSConfig `yaml:"ecsConfig,omitempty"`
 }
 
+// ParseXPod decodes and validates a YAML or JSON XPod document. Unknown fields are rejected.
+func ParseXPod(data []byte) (*XPod, error) {
+	jsonData, err := yaml.ToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XPod: %w", err)
+	}
+
+	var xpod XPod
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&xpod); err != nil {
+		return nil, fmt.Errorf("failed to parse XPod: %w", err)
+	}
+
+	if errs := xpod.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid XPod %s: %w", xpod.Name, errs)
+	}
+	return &xpod, nil
+}
+
+// Validate checks the document version, the containers and the ECS configuration
+func (x *XPod) Validate() ValidationErrors {
+	var errs ValidationErrors
+
+	if x.APIVersion != XPodAPIVersion {
+		errs.add("apiVersion", ValidationInvalidValue, "must be %s, got %q", XPodAPIVersion, x.APIVersion)
+	}
+	if x.Kind != XPodKind {
+		errs.add("kind", ValidationInvalidValue, "must be %s, got %q", XPodKind, x.Kind)
+	}
+	if x.Name == "" {
+		errs.add("metadata.name", ValidationRequired, "name is required")
+	}
+
+	if len(x.Spec.Containers) == 0 {
+		errs.add("spec.containers", ValidationRequired, "at least one container is required")
+	}
+	names := map[string]bool{}
+	for i, container := range x.Spec.Containers {
+		field := fmt.Sprintf("spec.containers[%d]", i)
+		if container.Name == "" {
+			errs.add(field+".name", ValidationRequired, "name is required")
+		} else if names[container.Name] {
+			errs.add(field+".name", ValidationDuplicate, "container %q is defined more than once", container.Name)
+		}
+		names[container.Name] = true
+		if container.Image == "" {
+			errs.add(field+".image", ValidationRequired, "image is required")
+		}
+	}
+
+	x.ECSConfig.validate("ecsConfig.", &errs)
+	for _, name := range slices.Sorted(maps.Keys(x.ECSConfig.Containers)) {
+		if !names[name] {
+			errs.add(fmt.Sprintf("ecsConfig.containers[%s]", name), ValidationNotFound,
+				"container %q is not defined in spec.containers", name)
+		}
+	}
+
+	return errs
+}
+
+// Pod returns the Kubernetes Pod described by the document
+func (x *XPod) Pod() *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: *x.ObjectMeta.DeepCopy(),
+		Spec:       *x.Spec.DeepCopy(),
+	}
+}
+
 // ConvertFromPod converts a standard Kubernetes Pod and ECSConfig to ECS Task Definition
 func ConvertFromPod(converter *Converter, pod *corev1.Pod, ecsConfig *ECSConfig) (*ECSTaskDefinition, error) {
 	return converter.Convert(&pod.Spec, ecsConfig, pod.Namespace)
 }
 
-// ConvertFromPodWithConfig converts a PodWithECSConfig to ECS Task Definition
+// ConvertFromXPod converts an XPod to ECS Task Definition. The pod metadata is used for the
+// family template, annotations and metadata propagation.
+func ConvertFromXPod(converter *Converter, xpod *XPod) (*ECSTaskDefinition, error) {
+	return convertPodWithConfig(converter, xpod.Pod(), &xpod.ECSConfig)
+}
+
+// ConvertFromPodWithConfig converts a PodWithECSConfig to ECS Task Definition. Like
+// ConvertFromXPod it uses the pod metadata; the status is ignored.
+//
+// Deprecated: use ConvertFromXPod.
 func ConvertFromPodWithConfig(converter *Converter, podWithConfig *PodWithECSConfig) (*ECSTaskDefinition, error) {
-	return converter.Convert(&podWithConfig.Spec, &podWithConfig.ECSConfig, podWithConfig.Namespace)
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: *podWithConfig.ObjectMeta.DeepCopy(),
+		Spec:       *podWithConfig.Spec.DeepCopy(),
+		Status:     *podWithConfig.Status.DeepCopy(),
+	}
+	return convertPodWithConfig(converter, pod, &podWithConfig.ECSConfig)
+}
+
+func convertPodWithConfig(converter *Converter, pod *corev1.Pod, ecsConfig *ECSConfig) (*ECSTaskDefinition, error) {
+	namespace := pod.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	return converter.ConvertPod(pod, &pod.Spec, ecsConfig, namespace)
 }
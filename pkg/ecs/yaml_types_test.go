@@ -0,0 +1,133 @@
+package ecs
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseXPod_Example(t *testing.T) {
+	data, err := os.ReadFile("../../examples/sample-xpod.yaml")
+	if err != nil {
+		t.Fatalf("failed to read example: %v", err)
+	}
+
+	xpod, err := ParseXPod(data)
+	if err != nil {
+		t.Fatalf("ParseXPod() error = %v", err)
+	}
+	if xpod.Name != "web-application" || xpod.Namespace != "production" {
+		t.Errorf("metadata = %s/%s", xpod.Namespace, xpod.Name)
+	}
+	if len(xpod.Spec.Containers) != 2 || xpod.Spec.Containers[0].Resources.Limits.Cpu().MilliValue() != 256 {
+		t.Errorf("spec.containers were not decoded: %+v", xpod.Spec.Containers)
+	}
+
+	converter := NewConverter(ConversionOptions{})
+	taskDef, err := ConvertFromXPod(converter, xpod)
+	if err != nil {
+		t.Fatalf("ConvertFromXPod() error = %v", err)
+	}
+	if taskDef.Family != "web-application" || taskDef.CPU != "512" || len(taskDef.Tags) != 4 {
+		t.Errorf("family/cpu/tags = %q/%q/%v", taskDef.Family, taskDef.CPU, taskDef.Tags)
+	}
+	if nginx := taskDef.ContainerDefinitions[0]; nginx.ReadonlyRootFilesystem == nil || !*nginx.ReadonlyRootFilesystem {
+		t.Error("nginx readonlyRootFilesystem override was not applied")
+	}
+	if app := taskDef.ContainerDefinitions[1]; app.StopTimeout != 30 {
+		t.Errorf("app stopTimeout = %d, want 30", app.StopTimeout)
+	}
+}
+
+func TestParseXPod_Invalid(t *testing.T) {
+	const header = "apiVersion: ecs.takutakahashi.dev/v1alpha1\nkind: XPod\nmetadata: {name: web}\n"
+
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{
+			name: "flat legacy layout",
+			doc:  "family: web\ncontainers: [{name: app, image: app}]\n",
+			want: "unknown field",
+		},
+		{
+			name: "unknown container field",
+			doc:  header + "spec: {containers: [{name: app, imag: app}]}\n",
+			want: `unknown field "imag"`,
+		},
+		{
+			name: "wrong kind",
+			doc: "apiVersion: ecs.takutakahashi.dev/v1alpha1\nkind: Pod\nmetadata: {name: web}\n" +
+				"spec: {containers: [{name: app, image: app}]}\n",
+			want: "kind:InvalidValue",
+		},
+		{
+			name: "no containers",
+			doc:  header + "spec: {}\n",
+			want: "spec.containers:Required",
+		},
+		{
+			name: "duplicate container",
+			doc:  header + "spec: {containers: [{name: app, image: a}, {name: app, image: b}]}\n",
+			want: "spec.containers[1].name:Duplicate",
+		},
+		{
+			name: "invalid ecsConfig",
+			doc:  header + "spec: {containers: [{name: app, image: a}]}\necsConfig: {networkMode: overlay}\n",
+			want: "ecsConfig.networkMode:InvalidValue",
+		},
+		{
+			name: "override for unknown container",
+			doc: header + "spec: {containers: [{name: app, image: a}]}\n" +
+				"ecsConfig: {containers: {web: {essential: false}}}\n",
+			want: "ecsConfig.containers[web]:NotFound",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseXPod([]byte(tt.doc))
+			if err == nil {
+				t.Fatal("ParseXPod() should fail")
+			}
+			var errs ValidationErrors
+			if errors.As(err, &errs) {
+				var got []string
+				for _, e := range errs {
+					got = append(got, e.Field+":"+string(e.Code))
+				}
+				if !slices.Contains(got, tt.want) {
+					t.Errorf("errors = %v, want %s", got, tt.want)
+				}
+				return
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseXPod() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertFromPodWithConfig(t *testing.T) {
+	podWithConfig := &PodWithECSConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:1.0"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		ECSConfig:  ECSConfig{Family: "web", CPU: "256", Memory: "512"},
+	}
+
+	taskDef, err := ConvertFromPodWithConfig(NewConverter(ConversionOptions{}), podWithConfig)
+	if err != nil {
+		t.Fatalf("ConvertFromPodWithConfig() error = %v", err)
+	}
+	if taskDef.Family != "web" || len(taskDef.ContainerDefinitions) != 1 {
+		t.Errorf("family/containers = %q/%d", taskDef.Family, len(taskDef.ContainerDefinitions))
+	}
+}
@@ -0,0 +1,282 @@
+package ecsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/config"
+	ecssdk "github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/smithy-go/middleware"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+// Config holds configuration for creating ECS API clients
+type Config struct {
+	// Region is the AWS region; the shared AWS configuration is used when empty
+	Region string
+	// Endpoint overrides the endpoint of the service, for example with a local stand-in. When
+	// empty the SDK resolves it from the service specific variable, such as AWS_ENDPOINT_URL_ECS,
+	// AWS_ENDPOINT_URL and the shared configuration, and otherwise from the region, covering
+	// the China and GovCloud partitions and the FIPS endpoints of AWS_USE_FIPS_ENDPOINT.
+	Endpoint string
+	// Credentials replaces the credentials of the shared AWS configuration. Requests are sent
+	// unsigned with aws.AnonymousCredentials, which is enough for a local stand-in.
+	Credentials aws.CredentialsProvider
+	// HTTPClient sends the requests (default: the HTTP client of the SDK)
+	HTTPClient *http.Client
+	// RetryMaxAttempts limits the attempts of a request. Throttled and failed requests are
+	// retried with exponential backoff (default: 3 attempts of the SDK's standard retryer).
+	RetryMaxAttempts int
+	// OnThrottle is called with the operation name for every attempt the rate limits of the API
+	// reject, including the ones the retryer retries successfully
+	OnThrottle func(operation string)
+}
+
+// loadConfig loads the shared AWS configuration, environment variables, ~/.aws/config and
+// credentials, and instance roles, with the settings of cfg
+func loadConfig(ctx context.Context, cfg Config) (aws.Config, error) {
+	var options []func(*config.LoadOptions) error
+	if cfg.Region != "" {
+		options = append(options, config.WithRegion(cfg.Region))
+	}
+	if cfg.Credentials != nil {
+		options = append(options, config.WithCredentialsProvider(cfg.Credentials))
+	}
+	if cfg.HTTPClient != nil {
+		options = append(options, config.WithHTTPClient(cfg.HTTPClient))
+	}
+	if cfg.RetryMaxAttempts > 0 {
+		options = append(options, config.WithRetryMaxAttempts(cfg.RetryMaxAttempts))
+	}
+
+	awsConfig, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	if awsConfig.Region == "" {
+		return aws.Config{}, errors.New("no AWS region is configured")
+	}
+	if cfg.OnThrottle != nil {
+		awsConfig.APIOptions = append(awsConfig.APIOptions, observeThrottles(cfg.OnThrottle))
+	}
+	return awsConfig, nil
+}
+
+// observeThrottles reports the throttled attempts of a request to onThrottle. The middleware
+// runs after the retry middleware of the finalize step, so it sees every attempt rather than
+// only the error the retryer finally returns.
+func observeThrottles(onThrottle func(operation string)) func(*middleware.Stack) error {
+	observe := middleware.FinalizeMiddlewareFunc("ObserveThrottles", func(
+		ctx context.Context,
+		in middleware.FinalizeInput,
+		next middleware.FinalizeHandler,
+	) (middleware.FinalizeOutput, middleware.Metadata, error) {
+		out, metadata, err := next.HandleFinalize(ctx, in)
+		if IsThrottling(err) {
+			onThrottle(awsmiddleware.GetOperationName(ctx))
+		}
+		return out, metadata, err
+	})
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Insert(observe, "Retry", middleware.After)
+	}
+}
+
+// AWSClient calls the ECS API through the AWS SDK. Task definitions and tasks are mapped
+// between the SDK types and the ECS JSON types of the project, so every field of
+// ecs.ECSTaskDefinition round-trips unchanged.
+type AWSClient struct {
+	client *ecssdk.Client
+}
+
+var _ Client = &AWSClient{}
+
+// NewClient creates an ECS API client. Settings missing from cfg are read from the shared
+// AWS configuration: environment variables, ~/.aws/config and credentials, and instance roles.
+func NewClient(ctx context.Context, cfg Config) (*AWSClient, error) {
+	awsConfig, err := loadConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := ecssdk.NewFromConfig(awsConfig, func(o *ecssdk.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
+	return &AWSClient{client: client}, nil
+}
+
+// convert maps a value between the SDK types and the ECS JSON types. Both name their fields
+// after the members of the ECS API, which encoding/json matches regardless of case, and
+// ecs.ECSTimestamp reads the RFC 3339 timestamps the SDK types encode.
+func convert[T any](value any) (*T, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var converted T
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return nil, err
+	}
+	return &converted, nil
+}
+
+// taskDefinitionResult converts the task definition of an output, adding the tags returned
+// next to it
+func taskDefinitionResult(
+	operation string,
+	taskDefinition *ecstypes.TaskDefinition,
+	tags []ecstypes.Tag,
+) (*ecs.ECSTaskDefinition, error) {
+	if taskDefinition == nil {
+		return nil, fmt.Errorf("%s returned no task definition", operation)
+	}
+	taskDef, err := convert[ecs.ECSTaskDefinition](taskDefinition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s output: %w", operation, err)
+	}
+	if len(taskDef.Tags) == 0 && len(tags) > 0 {
+		converted, err := convert[[]ecs.ECSTag](tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s output: %w", operation, err)
+		}
+		taskDef.Tags = *converted
+	}
+	return taskDef, nil
+}
+
+// taskDefinitionError marks the ClientException ECS returns for a task definition that does
+// not exist with ErrTaskDefinitionNotFound. Unlike clusters, which have ClusterNotFoundException,
+// ECS has no dedicated exception for missing task definitions, so the message tells them apart
+// from the other client errors, such as invalid parameters or malformed ARNs.
+func taskDefinitionError(err error) error {
+	var clientErr *ecstypes.ClientException
+	if errors.As(err, &clientErr) && slices.Contains(taskDefinitionNotFoundMessages, clientErr.ErrorMessage()) {
+		return fmt.Errorf("%w: %w", ErrTaskDefinitionNotFound, err)
+	}
+	return err
+}
+
+// RegisterTaskDefinition registers a new revision of the task definition's family
+func (c *AWSClient) RegisterTaskDefinition(
+	ctx context.Context,
+	taskDef *ecs.ECSTaskDefinition,
+) (*ecs.ECSTaskDefinition, error) {
+	input, err := convert[ecssdk.RegisterTaskDefinitionInput](taskDef.RegisterInput())
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert task definition %s: %w", taskDef.Family, err)
+	}
+	output, err := c.client.RegisterTaskDefinition(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return taskDefinitionResult("RegisterTaskDefinition", output.TaskDefinition, output.Tags)
+}
+
+// DeregisterTaskDefinition marks a task definition revision INACTIVE
+func (c *AWSClient) DeregisterTaskDefinition(
+	ctx context.Context,
+	taskDefinition string,
+) (*ecs.ECSTaskDefinition, error) {
+	output, err := c.client.DeregisterTaskDefinition(ctx, &ecssdk.DeregisterTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefinition),
+	})
+	if err != nil {
+		return nil, taskDefinitionError(err)
+	}
+	return taskDefinitionResult("DeregisterTaskDefinition", output.TaskDefinition, nil)
+}
+
+// DescribeTaskDefinition returns a task definition with its tags
+func (c *AWSClient) DescribeTaskDefinition(
+	ctx context.Context,
+	taskDefinition string,
+) (*ecs.ECSTaskDefinition, error) {
+	output, err := c.client.DescribeTaskDefinition(ctx, &ecssdk.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefinition),
+		Include:        []ecstypes.TaskDefinitionField{ecstypes.TaskDefinitionFieldTags},
+	})
+	if err != nil {
+		return nil, taskDefinitionError(err)
+	}
+	return taskDefinitionResult("DescribeTaskDefinition", output.TaskDefinition, output.Tags)
+}
+
+// RunTask starts tasks from a task definition
+func (c *AWSClient) RunTask(ctx context.Context, input *RunTaskInput) (*RunTaskOutput, error) {
+	sdkInput, err := convert[ecssdk.RunTaskInput](input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert RunTask input: %w", err)
+	}
+	output, err := c.client.RunTask(ctx, sdkInput)
+	if err != nil {
+		return nil, err
+	}
+	result, err := convert[RunTaskOutput](output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert RunTask output: %w", err)
+	}
+	return result, nil
+}
+
+// StopTask stops a running task
+func (c *AWSClient) StopTask(ctx context.Context, cluster, task, reason string) (*Task, error) {
+	output, err := c.client.StopTask(ctx, &ecssdk.StopTaskInput{
+		Cluster: aws.String(cluster),
+		Task:    aws.String(task),
+		Reason:  aws.String(reason),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Task == nil {
+		return nil, errors.New("StopTask returned no task")
+	}
+	stopped, err := convert[Task](output.Task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert StopTask output: %w", err)
+	}
+	return stopped, nil
+}
+
+// DescribeTasks returns tasks with their tags
+func (c *AWSClient) DescribeTasks(ctx context.Context, cluster string, tasks []string) (*DescribeTasksOutput, error) {
+	output, err := c.client.DescribeTasks(ctx, &ecssdk.DescribeTasksInput{
+		Cluster: aws.String(cluster),
+		Tasks:   tasks,
+		Include: []ecstypes.TaskField{ecstypes.TaskFieldTags},
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, err := convert[DescribeTasksOutput](output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert DescribeTasks output: %w", err)
+	}
+	return result, nil
+}
+
+// ListTasks returns the ARNs of the running tasks started by startedBy, following pagination
+func (c *AWSClient) ListTasks(ctx context.Context, cluster, startedBy string) ([]string, error) {
+	paginator := ecssdk.NewListTasksPaginator(c.client, &ecssdk.ListTasksInput{
+		Cluster:   aws.String(cluster),
+		StartedBy: aws.String(startedBy),
+	})
+	taskArns := []string{}
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		taskArns = append(taskArns, output.TaskArns...)
+	}
+	return taskArns, nil
+}
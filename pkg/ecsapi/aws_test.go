@@ -0,0 +1,335 @@
+package ecsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *AWSClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(context.Background(), Config{
+		Region:   "ap-northeast-1",
+		Endpoint: server.URL,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+		}),
+		RetryMaxAttempts: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+func TestAWSClient_RegisterTaskDefinition(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); target != "AmazonEC2ContainerServiceV20141113.RegisterTaskDefinition" {
+			t.Errorf("X-Amz-Target = %q", target)
+		}
+		if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "/ap-northeast-1/ecs/aws4_request") {
+			t.Errorf("Authorization = %q, want a SigV4 signature for ecs in ap-northeast-1", auth)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		var input map[string]any
+		if err := json.Unmarshal(body, &input); err != nil {
+			t.Fatalf("request body is not JSON: %v", err)
+		}
+		if _, ok := input["revision"]; ok {
+			t.Errorf("read-only fields were sent: %s", body)
+		}
+
+		input["taskDefinitionArn"] = "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/web:7"
+		input["revision"] = 7
+		input["status"] = "ACTIVE"
+		input["registeredAt"] = 1714534200.123
+		tags := input["tags"]
+		delete(input, "tags")
+		_ = json.NewEncoder(w).Encode(map[string]any{"taskDefinition": input, "tags": tags})
+	})
+
+	registered, err := client.RegisterTaskDefinition(context.Background(), &ecs.ECSTaskDefinition{
+		Family:               "web",
+		Revision:             3,
+		ContainerDefinitions: []ecs.ECSContainerDefinition{{Name: "web", Image: "nginx", Essential: true}},
+		Tags:                 []ecs.ECSTag{{Key: "team", Value: "web"}},
+	})
+	if err != nil {
+		t.Fatalf("RegisterTaskDefinition() error = %v", err)
+	}
+	if registered.Revision != 7 || registered.Status != "ACTIVE" || registered.RegisteredAt == nil {
+		t.Errorf("registered = %+v", registered)
+	}
+	if len(registered.Tags) != 1 || registered.Tags[0].Key != "team" {
+		t.Errorf("Tags = %v, want the tags of the response", registered.Tags)
+	}
+}
+
+// roundTripTaskDefinition uses nested fields of most kinds to check the mapping to the SDK types
+const roundTripTaskDefinition = `{
+  "family": "web",
+  "taskRoleArn": "arn:aws:iam::123456789012:role/web",
+  "networkMode": "awsvpc",
+  "requiresCompatibilities": ["FARGATE"],
+  "cpu": "512",
+  "memory": "1024",
+  "pidMode": "task",
+  "runtimePlatform": {"cpuArchitecture": "ARM64", "operatingSystemFamily": "LINUX"},
+  "ephemeralStorage": {"sizeInGiB": 30},
+  "containerDefinitions": [{
+    "name": "web",
+    "image": "nginx:1.27",
+    "essential": true,
+    "cpu": 256,
+    "memoryReservation": 128,
+    "command": ["nginx", "-g", "daemon off;"],
+    "portMappings": [{"containerPort": 80, "hostPort": 80, "protocol": "tcp", "name": "http", "appProtocol": "http"}],
+    "environment": [{"name": "MODE", "value": "fast"}],
+    "secrets": [{"name": "TOKEN", "valueFrom": "/pods/shop/secrets/token"}],
+    "dependsOn": [{"containerName": "init", "condition": "SUCCESS"}],
+    "healthCheck": {"command": ["CMD-SHELL", "true"], "interval": 10, "retries": 3},
+    "linuxParameters": {"initProcessEnabled": true, "capabilities": {"add": ["NET_ADMIN"]},
+      "tmpfs": [{"containerPath": "/tmp", "size": 64}]},
+    "logConfiguration": {"logDriver": "awslogs", "options": {"awslogs-group": "/ecs/web"}},
+    "mountPoints": [{"sourceVolume": "data", "containerPath": "/data", "readOnly": true}],
+    "ulimits": [{"name": "nofile", "softLimit": 1024, "hardLimit": 4096}],
+    "dockerLabels": {"team": "web"},
+    "readonlyRootFilesystem": true
+  }, {
+    "name": "init",
+    "image": "busybox",
+    "essential": false
+  }],
+  "volumes": [{
+    "name": "data",
+    "efsVolumeConfiguration": {"fileSystemId": "fs-0123", "rootDirectory": "/", "transitEncryption": "ENABLED",
+      "authorizationConfig": {"accessPointId": "fsap-0123", "iam": "ENABLED"}}
+  }],
+  "tags": [{"key": "team", "value": "web"}]
+}`
+
+func TestAWSClient_TaskDefinitionRoundTrip(t *testing.T) {
+	taskDef, err := ecs.ParseTaskDefinition([]byte(roundTripTaskDefinition))
+	if err != nil {
+		t.Fatalf("ParseTaskDefinition() error = %v", err)
+	}
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var input map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Fatalf("request body is not JSON: %v", err)
+		}
+		tags := input["tags"]
+		delete(input, "tags")
+		_ = json.NewEncoder(w).Encode(map[string]any{"taskDefinition": input, "tags": tags})
+	})
+	registered, err := client.RegisterTaskDefinition(context.Background(), taskDef)
+	if err != nil {
+		t.Fatalf("RegisterTaskDefinition() error = %v", err)
+	}
+	if !reflect.DeepEqual(registered, taskDef) {
+		want, _ := json.Marshal(taskDef)
+		got, _ := json.Marshal(registered)
+		t.Errorf("task definition changed on the way through the SDK types:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestAWSClient_ListTasksPaginates(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var input map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Fatalf("request body is not JSON: %v", err)
+		}
+		if input["startedBy"] != "pod-1" || input["cluster"] != "apps" {
+			t.Errorf("input = %v", input)
+		}
+		if input["nextToken"] == "" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"taskArns": []string{"task-1"}, "nextToken": "page-2"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"taskArns": []string{"task-2"}})
+	})
+
+	taskArns, err := client.ListTasks(context.Background(), "apps", "pod-1")
+	if err != nil {
+		t.Fatalf("ListTasks() error = %v", err)
+	}
+	if strings.Join(taskArns, ",") != "task-1,task-2" {
+		t.Errorf("ListTasks() = %v, want both pages", taskArns)
+	}
+}
+
+// writeAPIError writes a JSON protocol error response
+func writeAPIError(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{"__type": code, "message": message})
+}
+
+func TestAWSClient_Errors(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, "com.amazonaws.ecs#ClientException", "Unable to describe task definition.")
+	})
+
+	_, err := client.DescribeTaskDefinition(context.Background(), "missing")
+	if !IsNotFound(err) {
+		t.Fatalf("DescribeTaskDefinition() error = %v, want a not found error", err)
+	}
+	var clientErr *ecstypes.ClientException
+	if !errors.As(err, &clientErr) || IsThrottling(err) {
+		t.Errorf("error = %v, want the ClientException", err)
+	}
+
+	// Other client errors of the task definition operations are returned unchanged
+	invalid := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, "com.amazonaws.ecs#ClientException", "Invalid revision number. Number: web")
+	})
+	if _, err := invalid.DescribeTaskDefinition(context.Background(), "web:web"); err == nil || IsNotFound(err) {
+		t.Errorf("DescribeTaskDefinition() error = %v, want a client error that is not a missing task definition", err)
+	}
+	if _, err := invalid.DeregisterTaskDefinition(context.Background(), "web:web"); err == nil || IsNotFound(err) {
+		t.Errorf("DeregisterTaskDefinition() error = %v, want a client error that is not a missing task definition", err)
+	}
+
+	deregistered := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, "com.amazonaws.ecs#ClientException", "The specified task definition does not exist.")
+	})
+	if _, err := deregistered.DeregisterTaskDefinition(context.Background(), "web:9"); !IsNotFound(err) {
+		t.Errorf("DeregisterTaskDefinition() error = %v, want a not found error", err)
+	}
+
+	// A client error of another operation does not mean that a task definition is missing
+	_, err = client.RunTask(context.Background(), &RunTaskInput{TaskDefinition: "web"})
+	if err == nil || IsNotFound(err) {
+		t.Errorf("RunTask() error = %v, want a client error", err)
+	}
+
+	missingCluster := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, "ClusterNotFoundException", "Cluster not found.")
+	})
+	if _, err := missingCluster.ListTasks(context.Background(), "apps", "pod-1"); err == nil || IsNotFound(err) {
+		t.Errorf("ListTasks() error = %v, want a cluster error that is not a missing task definition", err)
+	}
+
+	throttled := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, "ThrottlingException", "Rate exceeded")
+	})
+	if _, err := throttled.ListTasks(context.Background(), "apps", "pod-1"); !IsThrottling(err) || IsNotFound(err) {
+		t.Errorf("ListTasks() error = %v, want a throttling error", err)
+	}
+}
+
+func TestAWSClient_RetriesThrottledRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			writeAPIError(w, "ThrottlingException", "Rate exceeded")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"taskArns": []string{"task-1"}})
+	}))
+	t.Cleanup(server.Close)
+
+	var throttled []string
+	client, err := NewClient(context.Background(), Config{
+		Region:           "us-west-2",
+		Endpoint:         server.URL,
+		Credentials:      aws.AnonymousCredentials{},
+		RetryMaxAttempts: 2,
+		OnThrottle:       func(operation string) { throttled = append(throttled, operation) },
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	taskArns, err := client.ListTasks(context.Background(), "apps", "pod-1")
+	if err != nil || len(taskArns) != 1 || calls.Load() != 2 {
+		t.Errorf("ListTasks() = %v, %v after %d calls, want the task after a retry", taskArns, err, calls.Load())
+	}
+	// The throttled attempt is reported although the retry succeeded
+	if !reflect.DeepEqual(throttled, []string{"ListTasks"}) {
+		t.Errorf("throttled attempts = %v, want the first ListTasks attempt", throttled)
+	}
+}
+
+func TestNewClient_EndpointFromEnvironment(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"taskArns": []string{}})
+	}))
+	t.Cleanup(server.Close)
+
+	// The endpoint of the service takes precedence over the one of all services
+	t.Setenv("AWS_ENDPOINT_URL", "http://127.0.0.1:1")
+	t.Setenv("AWS_ENDPOINT_URL_ECS", server.URL+"/")
+	client, err := NewClient(context.Background(), Config{Region: "us-west-2", Credentials: aws.AnonymousCredentials{}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err := client.ListTasks(context.Background(), "apps", "pod-1"); err != nil || calls.Load() != 1 {
+		t.Errorf("ListTasks() error = %v after %d calls, want a call to AWS_ENDPOINT_URL_ECS", err, calls.Load())
+	}
+}
+
+func TestAWSLogsClient_GetLogEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); target != "Logs_20140328.GetLogEvents" {
+			t.Errorf("X-Amz-Target = %q", target)
+		}
+		if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "/us-west-2/logs/aws4_request") {
+			t.Errorf("Authorization = %q, want a SigV4 signature for logs in us-west-2", auth)
+		}
+		var input GetLogEventsInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Fatalf("request body is not JSON: %v", err)
+		}
+		if input.LogGroupName != "/ecs/web" || input.LogStreamName != "web/app/0123" || input.Limit != 10 {
+			t.Errorf("input = %+v", input)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"events":           []map[string]any{{"timestamp": 1714534200123, "message": "started"}},
+			"nextForwardToken": "f/1",
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewLogsClient(context.Background(), Config{
+		Region:   "us-west-2",
+		Endpoint: server.URL,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewLogsClient() error = %v", err)
+	}
+	output, err := client.GetLogEvents(context.Background(), &GetLogEventsInput{
+		LogGroupName:  "/ecs/web",
+		LogStreamName: AWSLogsStream("web", "app", "0123"),
+		Limit:         10,
+	})
+	if err != nil {
+		t.Fatalf("GetLogEvents() error = %v", err)
+	}
+	if len(output.Events) != 1 || output.Events[0].Message != "started" ||
+		output.Events[0].Time().Format(time.RFC3339Nano) != "2024-05-01T03:30:00.123Z" || output.NextForwardToken != "f/1" {
+		t.Errorf("GetLogEvents() = %+v", output)
+	}
+}
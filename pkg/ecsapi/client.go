@@ -0,0 +1,298 @@
+// Package ecsapi registers task definitions and runs tasks through the ECS API. Client is
+// implemented by AWSClient, which talks to ECS or a local stand-in, and by the in-memory Fake.
+package ecsapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/aws/smithy-go"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+// Client covers the task definition and task operations of the ECS API used by the project
+type Client interface {
+	// RegisterTaskDefinition registers a new revision of the task definition's family. Read-only
+	// fields of the input are ignored; the registered definition is returned.
+	RegisterTaskDefinition(ctx context.Context, taskDef *ecs.ECSTaskDefinition) (*ecs.ECSTaskDefinition, error)
+	// DeregisterTaskDefinition marks a task definition revision, given as family:revision or
+	// ARN, INACTIVE
+	DeregisterTaskDefinition(ctx context.Context, taskDefinition string) (*ecs.ECSTaskDefinition, error)
+	// DescribeTaskDefinition returns a task definition given as family, family:revision or ARN.
+	// A family alone selects its latest ACTIVE revision.
+	DescribeTaskDefinition(ctx context.Context, taskDefinition string) (*ecs.ECSTaskDefinition, error)
+
+	// RunTask starts tasks from a task definition
+	RunTask(ctx context.Context, input *RunTaskInput) (*RunTaskOutput, error)
+	// StopTask stops a running task
+	StopTask(ctx context.Context, cluster, task, reason string) (*Task, error)
+	// DescribeTasks returns tasks given as IDs or ARNs
+	DescribeTasks(ctx context.Context, cluster string, tasks []string) (*DescribeTasksOutput, error)
+	// ListTasks returns the ARNs of the tasks of the cluster that should be running and were
+	// started with the startedBy value
+	ListTasks(ctx context.Context, cluster, startedBy string) ([]string, error)
+}
+
+// Task lifecycle states reported as lastStatus and desiredStatus
+const (
+	TaskStatusProvisioning   = "PROVISIONING"
+	TaskStatusPending        = "PENDING"
+	TaskStatusActivating     = "ACTIVATING"
+	TaskStatusRunning        = "RUNNING"
+	TaskStatusDeactivating   = "DEACTIVATING"
+	TaskStatusStopping       = "STOPPING"
+	TaskStatusDeprovisioning = "DEPROVISIONING"
+	TaskStatusStopped        = "STOPPED"
+)
+
+// Task definition states
+const (
+	TaskDefinitionStatusActive   = "ACTIVE"
+	TaskDefinitionStatusInactive = "INACTIVE"
+)
+
+// Launch types
+const (
+	LaunchTypeFargate  = "FARGATE"
+	LaunchTypeEC2      = "EC2"
+	LaunchTypeExternal = "EXTERNAL"
+)
+
+// RunTaskInput is the input of RunTask
+type RunTaskInput struct {
+	Cluster              string                `json:"cluster,omitempty"`
+	TaskDefinition       string                `json:"taskDefinition"`
+	Count                int                   `json:"count,omitempty"`
+	LaunchType           string                `json:"launchType,omitempty"`
+	NetworkConfiguration *NetworkConfiguration `json:"networkConfiguration,omitempty"`
+	StartedBy            string                `json:"startedBy,omitempty"`
+	Group                string                `json:"group,omitempty"`
+	Tags                 []ecs.ECSTag          `json:"tags,omitempty"`
+	PropagateTags        string                `json:"propagateTags,omitempty"`
+	EnableExecuteCommand bool                  `json:"enableExecuteCommand,omitempty"`
+	// ClientToken makes retries idempotent: RunTask calls repeating a token return the tasks
+	// of the first call
+	ClientToken string `json:"clientToken,omitempty"`
+}
+
+// NewRunTaskInput builds the input starting one task of a registered task definition with the
+// run settings. Without a launch type FARGATE is used when the task definition requires it, and
+// EC2 otherwise.
+func NewRunTaskInput(taskDef *ecs.ECSTaskDefinition, run ecs.ProfileRun) *RunTaskInput {
+	input := &RunTaskInput{
+		Cluster:        run.Cluster,
+		TaskDefinition: taskDef.TaskDefinitionArn,
+		Count:          1,
+		LaunchType:     run.LaunchType,
+		PropagateTags:  "TASK_DEFINITION",
+	}
+	if input.LaunchType == "" {
+		input.LaunchType = LaunchTypeEC2
+		if slices.Contains(taskDef.RequiresCompatibilities, LaunchTypeFargate) {
+			input.LaunchType = LaunchTypeFargate
+		}
+	}
+
+	if len(run.Subnets) > 0 {
+		assignPublicIP := "DISABLED"
+		if run.AssignPublicIP {
+			assignPublicIP = "ENABLED"
+		}
+		input.NetworkConfiguration = &NetworkConfiguration{
+			AwsvpcConfiguration: &AwsVpcConfiguration{
+				Subnets:        run.Subnets,
+				SecurityGroups: run.SecurityGroups,
+				AssignPublicIp: assignPublicIP,
+			},
+		}
+	}
+	return input
+}
+
+// NetworkConfiguration is the network configuration of awsvpc tasks
+type NetworkConfiguration struct {
+	AwsvpcConfiguration *AwsVpcConfiguration `json:"awsvpcConfiguration,omitempty"`
+}
+
+// AwsVpcConfiguration selects the subnets and security groups of a task's network interface
+type AwsVpcConfiguration struct {
+	Subnets        []string `json:"subnets"`
+	SecurityGroups []string `json:"securityGroups,omitempty"`
+	// AssignPublicIp is ENABLED or DISABLED
+	AssignPublicIp string `json:"assignPublicIp,omitempty"`
+}
+
+// RunTaskOutput is the output of RunTask. Tasks that could not be placed are listed as failures.
+type RunTaskOutput struct {
+	Tasks    []Task    `json:"tasks"`
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// DescribeTasksOutput is the output of DescribeTasks. Unknown tasks are listed as failures.
+type DescribeTasksOutput struct {
+	Tasks    []Task    `json:"tasks"`
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// Failure describes a task that could not be started or found
+type Failure struct {
+	Arn    string `json:"arn,omitempty"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Task is a task started from a task definition
+type Task struct {
+	TaskArn           string            `json:"taskArn"`
+	ClusterArn        string            `json:"clusterArn"`
+	TaskDefinitionArn string            `json:"taskDefinitionArn"`
+	LastStatus        string            `json:"lastStatus"`
+	DesiredStatus     string            `json:"desiredStatus"`
+	HealthStatus      string            `json:"healthStatus,omitempty"`
+	LaunchType        string            `json:"launchType,omitempty"`
+	CPU               string            `json:"cpu,omitempty"`
+	Memory            string            `json:"memory,omitempty"`
+	Group             string            `json:"group,omitempty"`
+	StartedBy         string            `json:"startedBy,omitempty"`
+	StopCode          string            `json:"stopCode,omitempty"`
+	StoppedReason     string            `json:"stoppedReason,omitempty"`
+	Containers        []Container       `json:"containers,omitempty"`
+	Attachments       []Attachment      `json:"attachments,omitempty"`
+	Tags              []ecs.ECSTag      `json:"tags,omitempty"`
+	CreatedAt         *ecs.ECSTimestamp `json:"createdAt,omitempty"`
+	StartedAt         *ecs.ECSTimestamp `json:"startedAt,omitempty"`
+	StoppingAt        *ecs.ECSTimestamp `json:"stoppingAt,omitempty"`
+	StoppedAt         *ecs.ECSTimestamp `json:"stoppedAt,omitempty"`
+}
+
+// Container is the state of a container of a task
+type Container struct {
+	Name         string `json:"name"`
+	Image        string `json:"image,omitempty"`
+	LastStatus   string `json:"lastStatus,omitempty"`
+	HealthStatus string `json:"healthStatus,omitempty"`
+	ExitCode     *int   `json:"exitCode,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// Attachment is a resource attached to a task, such as the elastic network interface of awsvpc
+// tasks
+type Attachment struct {
+	ID      string         `json:"id"`
+	Type    string         `json:"type"`
+	Status  string         `json:"status"`
+	Details []KeyValuePair `json:"details,omitempty"`
+}
+
+// KeyValuePair is a detail of an attachment
+type KeyValuePair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Attachment types and details read by the project
+const (
+	AttachmentTypeENI         = "ElasticNetworkInterface"
+	AttachmentDetailPrivateIP = "privateIPv4Address"
+)
+
+// PrivateIPv4Address returns the private IP address of the task's elastic network interface,
+// or an empty string for tasks without one
+func (t *Task) PrivateIPv4Address() string {
+	for _, attachment := range t.Attachments {
+		if attachment.Type != AttachmentTypeENI {
+			continue
+		}
+		for _, detail := range attachment.Details {
+			if detail.Name == AttachmentDetailPrivateIP {
+				return detail.Value
+			}
+		}
+	}
+	return ""
+}
+
+// ID returns the task ID, the last element of the task ARN
+func (t *Task) ID() string {
+	return t.TaskArn[strings.LastIndex(t.TaskArn, "/")+1:]
+}
+
+// APIError is an error of the ECS API as returned by Fake and served by the stand-in. Like the
+// errors of the AWS SDK it implements smithy.APIError, so callers inspect both the same way.
+type APIError struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Code is the exception name, such as ClientException or ClusterNotFoundException
+	Code    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// ErrorCode implements smithy.APIError
+func (e *APIError) ErrorCode() string {
+	return e.Code
+}
+
+// ErrorMessage implements smithy.APIError
+func (e *APIError) ErrorMessage() string {
+	return e.Message
+}
+
+// ErrorFault implements smithy.APIError
+func (e *APIError) ErrorFault() smithy.ErrorFault {
+	if e.StatusCode >= http.StatusInternalServerError {
+		return smithy.FaultServer
+	}
+	return smithy.FaultClient
+}
+
+// HTTPStatusCode returns the HTTP status like the response errors of the AWS SDK
+func (e *APIError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+// Error codes of the ECS API
+const (
+	ErrorCodeClient           = "ClientException"
+	ErrorCodeServer           = "ServerException"
+	ErrorCodeInvalidParameter = "InvalidParameterException"
+	ErrorCodeClusterNotFound  = "ClusterNotFoundException"
+	ErrorCodeAccessDenied     = "AccessDeniedException"
+	ErrorCodeUnknownOperation = "UnknownOperationException"
+	ErrorCodeThrottling       = "ThrottlingException"
+)
+
+// ErrTaskDefinitionNotFound is wrapped by the errors of the task definition operations for task
+// definitions that do not exist
+var ErrTaskDefinitionNotFound = errors.New("task definition not found")
+
+// taskDefinitionNotFoundMessages are the messages of the ClientException ECS returns for task
+// definitions that do not exist, from DescribeTaskDefinition and DeregisterTaskDefinition
+var taskDefinitionNotFoundMessages = []string{
+	"Unable to describe task definition.",
+	"The specified task definition does not exist.",
+}
+
+// IsNotFound reports whether err is the error of a task definition that does not exist
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrTaskDefinitionNotFound)
+}
+
+// IsThrottling reports whether err is an ECS error for a request rejected by the rate limits of
+// the API
+func IsThrottling(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == ErrorCodeThrottling {
+		return true
+	}
+	var statusErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &statusErr) && statusErr.HTTPStatusCode() == http.StatusTooManyRequests
+}
@@ -0,0 +1,463 @@
+package ecsapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+// Fake is an in-memory Client for tests. It keeps task definition revisions per family and
+// tasks per cluster, and returns the same errors as ECS for unknown task definitions and
+// tasks. Tasks start PROVISIONING; Advance moves them through their lifecycle.
+type Fake struct {
+	// Region and AccountID are used in the ARNs of task definitions and tasks
+	Region    string
+	AccountID string
+
+	mu       sync.Mutex
+	families map[string][]*ecs.ECSTaskDefinition
+	tasks    map[string]*Task
+	order    []string
+	tokens   map[string][]string
+	nextTask int
+}
+
+var _ Client = &Fake{}
+
+// NewFake creates an empty fake in us-east-1
+func NewFake() *Fake {
+	return &Fake{
+		Region:    "us-east-1",
+		AccountID: "123456789012",
+		families:  map[string][]*ecs.ECSTaskDefinition{},
+		tasks:     map[string]*Task{},
+		tokens:    map[string][]string{},
+	}
+}
+
+func clientError(code, format string, args ...any) *APIError {
+	return &APIError{StatusCode: http.StatusBadRequest, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// unknownTaskDefinition returns the error of ECS for a task definition that does not exist
+func unknownTaskDefinition() error {
+	return fmt.Errorf("%w: %w", ErrTaskDefinitionNotFound,
+		clientError(ErrorCodeClient, "%s", taskDefinitionNotFoundMessages[0]))
+}
+
+// copyOf deep copies a JSON encodable value
+func copyOf[T any](value *T) *T {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	var copied T
+	if err := json.Unmarshal(data, &copied); err != nil {
+		panic(err)
+	}
+	return &copied
+}
+
+func now() *ecs.ECSTimestamp {
+	return &ecs.ECSTimestamp{Time: time.Now().UTC().Truncate(time.Millisecond)}
+}
+
+// RegisterTaskDefinition stores a new ACTIVE revision of the family
+func (f *Fake) RegisterTaskDefinition(
+	_ context.Context,
+	taskDef *ecs.ECSTaskDefinition,
+) (*ecs.ECSTaskDefinition, error) {
+	if taskDef.Family == "" {
+		return nil, clientError(ErrorCodeClient, "Family must not be empty.")
+	}
+	if len(taskDef.ContainerDefinitions) == 0 {
+		return nil, clientError(ErrorCodeClient, "Container list cannot be empty.")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	registered := copyOf(taskDef.RegisterInput())
+	registered.Revision = len(f.families[taskDef.Family]) + 1
+	registered.TaskDefinitionArn = fmt.Sprintf("arn:aws:ecs:%s:%s:task-definition/%s:%d",
+		f.Region, f.AccountID, registered.Family, registered.Revision)
+	registered.Status = TaskDefinitionStatusActive
+	registered.Compatibilities = registered.RequiresCompatibilities
+	registered.RegisteredAt = now()
+	registered.RegisteredBy = fmt.Sprintf("arn:aws:iam::%s:root", f.AccountID)
+	f.families[registered.Family] = append(f.families[registered.Family], registered)
+
+	return copyOf(registered), nil
+}
+
+// lookupTaskDefinition resolves family, family:revision or an ARN; f.mu must be held
+func (f *Fake) lookupTaskDefinition(taskDefinition string) (*ecs.ECSTaskDefinition, error) {
+	name := taskDefinition[strings.LastIndex(taskDefinition, "/")+1:]
+	family, revision, hasRevision := strings.Cut(name, ":")
+	revisions := f.families[family]
+
+	if !hasRevision {
+		for i := len(revisions) - 1; i >= 0; i-- {
+			if revisions[i].Status == TaskDefinitionStatusActive {
+				return revisions[i], nil
+			}
+		}
+		return nil, unknownTaskDefinition()
+	}
+
+	number, err := strconv.Atoi(revision)
+	if err != nil || number < 1 {
+		return nil, clientError(ErrorCodeClient, "Invalid revision number. Number: %s", revision)
+	}
+	if number > len(revisions) {
+		return nil, unknownTaskDefinition()
+	}
+	return revisions[number-1], nil
+}
+
+// DeregisterTaskDefinition marks a revision INACTIVE
+func (f *Fake) DeregisterTaskDefinition(_ context.Context, taskDefinition string) (*ecs.ECSTaskDefinition, error) {
+	if !strings.Contains(taskDefinition[strings.LastIndex(taskDefinition, "/")+1:], ":") {
+		return nil, clientError(ErrorCodeClient, "A revision must be specified to deregister %s.", taskDefinition)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	registered, err := f.lookupTaskDefinition(taskDefinition)
+	if err != nil {
+		return nil, err
+	}
+	registered.Status = TaskDefinitionStatusInactive
+	registered.DeregisteredAt = now()
+	return copyOf(registered), nil
+}
+
+// DescribeTaskDefinition returns a stored revision
+func (f *Fake) DescribeTaskDefinition(_ context.Context, taskDefinition string) (*ecs.ECSTaskDefinition, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	registered, err := f.lookupTaskDefinition(taskDefinition)
+	if err != nil {
+		return nil, err
+	}
+	return copyOf(registered), nil
+}
+
+func clusterName(cluster string) string {
+	if cluster == "" {
+		return "default"
+	}
+	return cluster[strings.LastIndex(cluster, "/")+1:]
+}
+
+func (f *Fake) clusterArn(cluster string) string {
+	return fmt.Sprintf("arn:aws:ecs:%s:%s:cluster/%s", f.Region, f.AccountID, clusterName(cluster))
+}
+
+// RunTask creates PROVISIONING tasks from an ACTIVE task definition
+func (f *Fake) RunTask(_ context.Context, input *RunTaskInput) (*RunTaskOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if arns, ok := f.tokens[input.ClientToken]; ok && input.ClientToken != "" {
+		output := &RunTaskOutput{}
+		for _, arn := range arns {
+			output.Tasks = append(output.Tasks, *copyOf(f.tasks[arn]))
+		}
+		return output, nil
+	}
+
+	taskDef, err := f.lookupTaskDefinition(input.TaskDefinition)
+	if err != nil {
+		return nil, err
+	}
+	if taskDef.Status != TaskDefinitionStatusActive {
+		return nil, clientError(ErrorCodeClient, "TaskDefinition is inactive")
+	}
+	if (taskDef.NetworkMode == "awsvpc" || input.LaunchType == LaunchTypeFargate) &&
+		(input.NetworkConfiguration == nil || input.NetworkConfiguration.AwsvpcConfiguration == nil) {
+		return nil, clientError(ErrorCodeInvalidParameter,
+			"Network Configuration must be provided when networkMode 'awsvpc' is specified.")
+	}
+
+	count := max(input.Count, 1)
+	group := input.Group
+	if group == "" {
+		group = "family:" + taskDef.Family
+	}
+
+	output := &RunTaskOutput{}
+	for range count {
+		f.nextTask++
+		task := &Task{
+			TaskArn: fmt.Sprintf("arn:aws:ecs:%s:%s:task/%s/%032x",
+				f.Region, f.AccountID, clusterName(input.Cluster), f.nextTask),
+			ClusterArn:        f.clusterArn(input.Cluster),
+			TaskDefinitionArn: taskDef.TaskDefinitionArn,
+			LastStatus:        TaskStatusProvisioning,
+			DesiredStatus:     TaskStatusRunning,
+			LaunchType:        input.LaunchType,
+			CPU:               taskDef.CPU,
+			Memory:            taskDef.Memory,
+			Group:             group,
+			StartedBy:         input.StartedBy,
+			Tags:              slices.Clone(input.Tags),
+			CreatedAt:         now(),
+		}
+		if taskDef.NetworkMode == "awsvpc" {
+			task.Attachments = []Attachment{{
+				ID:     fmt.Sprintf("%08x-0000-4000-8000-%012x", f.nextTask, f.nextTask),
+				Type:   AttachmentTypeENI,
+				Status: "ATTACHED",
+				Details: []KeyValuePair{
+					{Name: "networkInterfaceId", Value: fmt.Sprintf("eni-%017x", f.nextTask)},
+					{Name: AttachmentDetailPrivateIP, Value: fmt.Sprintf("10.0.%d.%d", f.nextTask/250, f.nextTask%250+4)},
+				},
+			}}
+		}
+		for _, container := range taskDef.ContainerDefinitions {
+			task.Containers = append(task.Containers, Container{
+				Name:       container.Name,
+				Image:      container.Image,
+				LastStatus: TaskStatusPending,
+			})
+		}
+		f.tasks[task.TaskArn] = task
+		f.order = append(f.order, task.TaskArn)
+		if input.ClientToken != "" {
+			f.tokens[input.ClientToken] = append(f.tokens[input.ClientToken], task.TaskArn)
+		}
+		output.Tasks = append(output.Tasks, *copyOf(task))
+	}
+	return output, nil
+}
+
+// lookupTask resolves a task ID or ARN in the cluster; f.mu must be held
+func (f *Fake) lookupTask(cluster, task string) *Task {
+	for _, arn := range f.order {
+		candidate := f.tasks[arn]
+		if candidate.ClusterArn == f.clusterArn(cluster) && (arn == task || candidate.ID() == task) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// StopTask sets the desired status of the task to STOPPED
+func (f *Fake) StopTask(_ context.Context, cluster, task, reason string) (*Task, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stopped := f.lookupTask(cluster, task)
+	if stopped == nil {
+		return nil, clientError(ErrorCodeInvalidParameter, "The referenced task was not found.")
+	}
+	if stopped.DesiredStatus != TaskStatusStopped {
+		stopped.DesiredStatus = TaskStatusStopped
+		stopped.StopCode = "UserInitiated"
+		stopped.StoppedReason = reason
+		stopped.StoppingAt = now()
+	}
+	return copyOf(stopped), nil
+}
+
+// DescribeTasks returns the known tasks and a MISSING failure for the others
+func (f *Fake) DescribeTasks(_ context.Context, cluster string, tasks []string) (*DescribeTasksOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	output := &DescribeTasksOutput{Tasks: []Task{}}
+	for _, task := range tasks {
+		if found := f.lookupTask(cluster, task); found != nil {
+			output.Tasks = append(output.Tasks, *copyOf(found))
+		} else {
+			output.Failures = append(output.Failures, Failure{Arn: task, Reason: "MISSING"})
+		}
+	}
+	return output, nil
+}
+
+// ListTasks returns the tasks of the cluster whose desired status is RUNNING, optionally only
+// those started by startedBy
+func (f *Fake) ListTasks(_ context.Context, cluster, startedBy string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	taskArns := []string{}
+	for _, arn := range f.order {
+		task := f.tasks[arn]
+		if task.ClusterArn == f.clusterArn(cluster) && task.DesiredStatus == TaskStatusRunning &&
+			(startedBy == "" || task.StartedBy == startedBy) {
+			taskArns = append(taskArns, arn)
+		}
+	}
+	return taskArns, nil
+}
+
+// Advance moves every task one step through its lifecycle: PROVISIONING, PENDING and RUNNING
+// while it should run, then STOPPING and STOPPED once it is stopped. It reports whether any
+// task changed.
+func (f *Fake) Advance() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	changed := false
+	for _, arn := range f.order {
+		task := f.tasks[arn]
+		next := nextStatus(task)
+		if next == task.LastStatus {
+			continue
+		}
+		changed = true
+		task.LastStatus = next
+
+		switch next {
+		case TaskStatusRunning:
+			task.StartedAt = now()
+			for i := range task.Containers {
+				task.Containers[i].LastStatus = TaskStatusRunning
+			}
+		case TaskStatusStopped:
+			task.StoppedAt = now()
+			for i := range task.Containers {
+				container := &task.Containers[i]
+				if container.LastStatus == TaskStatusRunning && container.ExitCode == nil {
+					// Stopped containers receive SIGTERM, then SIGKILL
+					exitCode := 137
+					container.ExitCode = &exitCode
+				}
+				container.LastStatus = TaskStatusStopped
+			}
+		}
+	}
+	return changed
+}
+
+func nextStatus(task *Task) string {
+	if task.DesiredStatus == TaskStatusStopped {
+		switch task.LastStatus {
+		case TaskStatusStopping, TaskStatusStopped:
+			return TaskStatusStopped
+		default:
+			return TaskStatusStopping
+		}
+	}
+	switch task.LastStatus {
+	case TaskStatusProvisioning:
+		return TaskStatusPending
+	case TaskStatusPending:
+		return TaskStatusRunning
+	default:
+		return task.LastStatus
+	}
+}
+
+// ExitContainer records that a container of a task exited. The task stops with reason
+// "Essential container in task exited" once an essential container, or every container, has
+// exited.
+func (f *Fake) ExitContainer(taskArn, container string, exitCode int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	task, ok := f.tasks[taskArn]
+	if !ok {
+		return fmt.Errorf("task %s not found", taskArn)
+	}
+	index := slices.IndexFunc(task.Containers, func(c Container) bool { return c.Name == container })
+	if index < 0 {
+		return fmt.Errorf("task %s has no container %s", taskArn, container)
+	}
+	task.Containers[index].ExitCode = &exitCode
+	task.Containers[index].LastStatus = TaskStatusStopped
+
+	essential := true
+	if taskDef, err := f.lookupTaskDefinition(task.TaskDefinitionArn); err == nil {
+		for _, definition := range taskDef.ContainerDefinitions {
+			if definition.Name == container {
+				essential = definition.Essential
+			}
+		}
+	}
+	allExited := !slices.ContainsFunc(task.Containers, func(c Container) bool { return c.ExitCode == nil })
+	if (essential || allExited) && task.DesiredStatus != TaskStatusStopped {
+		task.DesiredStatus = TaskStatusStopped
+		task.StopCode = "EssentialContainerExited"
+		task.StoppedReason = "Essential container in task exited"
+		task.StoppingAt = now()
+	}
+	return nil
+}
+
+// FakeLogs is an in-memory LogsClient for tests
+type FakeLogs struct {
+	mu      sync.Mutex
+	streams map[string][]OutputLogEvent
+}
+
+var _ LogsClient = &FakeLogs{}
+
+// NewFakeLogs creates a fake without log streams
+func NewFakeLogs() *FakeLogs {
+	return &FakeLogs{streams: map[string][]OutputLogEvent{}}
+}
+
+// PutLogEvents appends events with the current time to a log stream, creating it
+func (f *FakeLogs) PutLogEvents(group, stream string, messages ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := group + ":" + stream
+	timestamp := time.Now().UnixMilli()
+	for _, message := range messages {
+		f.streams[key] = append(f.streams[key], OutputLogEvent{Timestamp: timestamp, Message: message,
+			IngestionTime: timestamp})
+	}
+}
+
+// GetLogEvents returns the events of a log stream. Tokens are f/ followed by the index of the
+// next event.
+func (f *FakeLogs) GetLogEvents(_ context.Context, input *GetLogEventsInput) (*GetLogEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	events, ok := f.streams[input.LogGroupName+":"+input.LogStreamName]
+	if !ok {
+		return nil, clientError(ErrorCodeResourceNotFound, "The specified log stream does not exist.")
+	}
+	events = slices.DeleteFunc(slices.Clone(events), func(event OutputLogEvent) bool {
+		return input.StartTime != nil && event.Timestamp < *input.StartTime ||
+			input.EndTime != nil && event.Timestamp >= *input.EndTime
+	})
+
+	limit := int(input.Limit)
+	if limit <= 0 || limit > 10000 {
+		limit = 10000
+	}
+	start := 0
+	switch {
+	case input.NextToken != "":
+		index, err := strconv.Atoi(strings.TrimPrefix(input.NextToken, "f/"))
+		if err != nil || !strings.HasPrefix(input.NextToken, "f/") {
+			return nil, clientError("InvalidParameterException", "The specified nextToken is invalid.")
+		}
+		start = min(index, len(events))
+	case !input.StartFromHead:
+		start = max(len(events)-limit, 0)
+	}
+	end := min(start+limit, len(events))
+	return &GetLogEventsOutput{
+		Events:            events[start:end],
+		NextForwardToken:  fmt.Sprintf("f/%d", end),
+		NextBackwardToken: fmt.Sprintf("b/%d", start),
+	}, nil
+}
@@ -0,0 +1,198 @@
+package ecsapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+func TestFake_TaskDefinitions(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake()
+	taskDef := &ecs.ECSTaskDefinition{
+		Family:               "web",
+		ContainerDefinitions: []ecs.ECSContainerDefinition{{Name: "web", Image: "nginx:1.25", Essential: true}},
+	}
+
+	first, err := fake.RegisterTaskDefinition(ctx, taskDef)
+	if err != nil {
+		t.Fatalf("RegisterTaskDefinition() error = %v", err)
+	}
+	taskDef.ContainerDefinitions[0].Image = "nginx:1.27"
+	second, err := fake.RegisterTaskDefinition(ctx, taskDef)
+	if err != nil {
+		t.Fatalf("RegisterTaskDefinition() error = %v", err)
+	}
+	if first.Revision != 1 || second.Revision != 2 ||
+		second.TaskDefinitionArn != "arn:aws:ecs:us-east-1:123456789012:task-definition/web:2" {
+		t.Errorf("revisions = %d (%s), %d (%s)", first.Revision, first.TaskDefinitionArn,
+			second.Revision, second.TaskDefinitionArn)
+	}
+
+	if _, err := fake.DeregisterTaskDefinition(ctx, "web:2"); err != nil {
+		t.Fatalf("DeregisterTaskDefinition() error = %v", err)
+	}
+	latest, err := fake.DescribeTaskDefinition(ctx, "web")
+	if err != nil {
+		t.Fatalf("DescribeTaskDefinition() error = %v", err)
+	}
+	if latest.Revision != 1 || latest.ContainerDefinitions[0].Image != "nginx:1.25" {
+		t.Errorf("latest ACTIVE revision = %d with %s, want 1 with nginx:1.25",
+			latest.Revision, latest.ContainerDefinitions[0].Image)
+	}
+
+	if _, err := fake.DescribeTaskDefinition(ctx, "api"); !IsNotFound(err) {
+		t.Errorf("DescribeTaskDefinition(api) error = %v, want not found", err)
+	}
+	if _, err := fake.DeregisterTaskDefinition(ctx, "web"); err == nil {
+		t.Error("DeregisterTaskDefinition() without a revision succeeded")
+	}
+}
+
+func TestFake_TaskLifecycle(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake()
+	if _, err := fake.RegisterTaskDefinition(ctx, &ecs.ECSTaskDefinition{
+		Family:      "job",
+		NetworkMode: "awsvpc",
+		ContainerDefinitions: []ecs.ECSContainerDefinition{
+			{Name: "main", Image: "job:1.0", Essential: true},
+			{Name: "sidecar", Image: "envoy:1.0"},
+		},
+	}); err != nil {
+		t.Fatalf("RegisterTaskDefinition() error = %v", err)
+	}
+
+	if _, err := fake.RunTask(ctx, &RunTaskInput{Cluster: "batch", TaskDefinition: "job"}); err == nil {
+		t.Error("RunTask() without a network configuration succeeded for awsvpc")
+	}
+	output, err := fake.RunTask(ctx, &RunTaskInput{
+		Cluster:        "batch",
+		TaskDefinition: "job",
+		Count:          2,
+		NetworkConfiguration: &NetworkConfiguration{
+			AwsvpcConfiguration: &AwsVpcConfiguration{Subnets: []string{"subnet-1"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunTask() error = %v", err)
+	}
+	if len(output.Tasks) != 2 || output.Tasks[0].LastStatus != TaskStatusProvisioning {
+		t.Fatalf("RunTask() = %+v", output)
+	}
+	completed, stopped := output.Tasks[0], output.Tasks[1]
+	if ip := completed.PrivateIPv4Address(); ip == "" || ip == stopped.PrivateIPv4Address() {
+		t.Errorf("awsvpc tasks got IPs %q and %q, want distinct addresses", ip, stopped.PrivateIPv4Address())
+	}
+
+	fake.Advance()
+	fake.Advance()
+	if fake.Advance() {
+		t.Error("Advance() changed tasks that are already RUNNING")
+	}
+
+	if err := fake.ExitContainer(completed.TaskArn, "main", 0); err != nil {
+		t.Fatalf("ExitContainer() error = %v", err)
+	}
+	if _, err := fake.StopTask(ctx, "batch", stopped.ID(), "scaled down"); err != nil {
+		t.Fatalf("StopTask() error = %v", err)
+	}
+	fake.Advance()
+	fake.Advance()
+
+	described, err := fake.DescribeTasks(ctx, "batch", []string{completed.TaskArn, stopped.ID(), "unknown"})
+	if err != nil {
+		t.Fatalf("DescribeTasks() error = %v", err)
+	}
+	if len(described.Tasks) != 2 || len(described.Failures) != 1 || described.Failures[0].Reason != "MISSING" {
+		t.Fatalf("DescribeTasks() = %+v", described)
+	}
+	for _, task := range described.Tasks {
+		if task.LastStatus != TaskStatusStopped || task.StoppedAt == nil {
+			t.Errorf("task %s lastStatus = %s, want STOPPED", task.ID(), task.LastStatus)
+		}
+	}
+	if task := described.Tasks[0]; task.StopCode != "EssentialContainerExited" || *task.Containers[0].ExitCode != 0 {
+		t.Errorf("completed task = %+v", task)
+	}
+	if task := described.Tasks[1]; task.StoppedReason != "scaled down" || task.StopCode != "UserInitiated" {
+		t.Errorf("stopped task = %+v", task)
+	}
+}
+
+func TestFake_ClientTokenAndListTasks(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake()
+	if _, err := fake.RegisterTaskDefinition(ctx, &ecs.ECSTaskDefinition{
+		Family:               "web",
+		ContainerDefinitions: []ecs.ECSContainerDefinition{{Name: "web", Image: "nginx", Essential: true}},
+	}); err != nil {
+		t.Fatalf("RegisterTaskDefinition() error = %v", err)
+	}
+
+	input := &RunTaskInput{Cluster: "apps", TaskDefinition: "web", StartedBy: "pod-1", ClientToken: "pod-1"}
+	first, err := fake.RunTask(ctx, input)
+	if err != nil {
+		t.Fatalf("RunTask() error = %v", err)
+	}
+	retried, err := fake.RunTask(ctx, input)
+	if err != nil {
+		t.Fatalf("RunTask() retry error = %v", err)
+	}
+	if retried.Tasks[0].TaskArn != first.Tasks[0].TaskArn {
+		t.Errorf("retry started %s, want the task of the first call %s", retried.Tasks[0].TaskArn, first.Tasks[0].TaskArn)
+	}
+	if _, err := fake.RunTask(ctx, &RunTaskInput{Cluster: "apps", TaskDefinition: "web", StartedBy: "pod-2"}); err != nil {
+		t.Fatalf("RunTask(pod-2) error = %v", err)
+	}
+
+	listed, err := fake.ListTasks(ctx, "apps", "pod-1")
+	if err != nil {
+		t.Fatalf("ListTasks() error = %v", err)
+	}
+	if len(listed) != 1 || listed[0] != first.Tasks[0].TaskArn {
+		t.Errorf("ListTasks(pod-1) = %v, want [%s]", listed, first.Tasks[0].TaskArn)
+	}
+	if all, _ := fake.ListTasks(ctx, "apps", ""); len(all) != 2 {
+		t.Errorf("ListTasks() = %v, want both tasks", all)
+	}
+
+	if _, err := fake.StopTask(ctx, "apps", first.Tasks[0].TaskArn, "done"); err != nil {
+		t.Fatalf("StopTask() error = %v", err)
+	}
+	if listed, _ := fake.ListTasks(ctx, "apps", "pod-1"); len(listed) != 0 {
+		t.Errorf("ListTasks(pod-1) after StopTask = %v, want none", listed)
+	}
+}
+
+func TestFakeLogs_GetLogEvents(t *testing.T) {
+	ctx := context.Background()
+	logs := NewFakeLogs()
+	logs.PutLogEvents("/ecs/web", "web/app/1", "one", "two", "three")
+
+	missing := &GetLogEventsInput{LogGroupName: "/ecs/web", LogStreamName: "missing"}
+	if _, err := logs.GetLogEvents(ctx, missing); err == nil {
+		t.Error("GetLogEvents() of a missing stream succeeded")
+	}
+
+	latest, err := logs.GetLogEvents(ctx, &GetLogEventsInput{LogGroupName: "/ecs/web", LogStreamName: "web/app/1",
+		Limit: 2})
+	if err != nil {
+		t.Fatalf("GetLogEvents() error = %v", err)
+	}
+	if len(latest.Events) != 2 || latest.Events[0].Message != "two" {
+		t.Errorf("latest events = %+v, want the last two", latest.Events)
+	}
+
+	// The forward token returns only newer events
+	logs.PutLogEvents("/ecs/web", "web/app/1", "four")
+	newer, err := logs.GetLogEvents(ctx, &GetLogEventsInput{LogGroupName: "/ecs/web", LogStreamName: "web/app/1",
+		NextToken: latest.NextForwardToken})
+	if err != nil {
+		t.Fatalf("GetLogEvents() error = %v", err)
+	}
+	if len(newer.Events) != 1 || newer.Events[0].Message != "four" {
+		t.Errorf("newer events = %+v, want four", newer.Events)
+	}
+}
@@ -0,0 +1,122 @@
+package ecsapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+// LogsClient reads the container logs ECS tasks send to CloudWatch Logs with the awslogs driver
+type LogsClient interface {
+	// GetLogEvents returns a page of the events of a log stream
+	GetLogEvents(ctx context.Context, input *GetLogEventsInput) (*GetLogEventsOutput, error)
+}
+
+// Options of the awslogs log driver naming the log stream of a container
+const (
+	LogDriverAWSLogs          = "awslogs"
+	AWSLogsGroupOption        = "awslogs-group"
+	AWSLogsStreamPrefixOption = "awslogs-stream-prefix"
+)
+
+// AWSLogsStream returns the log stream the awslogs driver writes a container of a task to,
+// prefix/container/task-id. Without a stream prefix the stream is named after the Docker
+// container ID, which the ECS API does not expose.
+func AWSLogsStream(prefix, container, taskID string) string {
+	return prefix + "/" + container + "/" + taskID
+}
+
+// GetLogEventsInput selects the events of a log stream. Without a token and StartFromHead the
+// latest events are returned.
+type GetLogEventsInput struct {
+	LogGroupName  string `json:"logGroupName"`
+	LogStreamName string `json:"logStreamName"`
+	// StartTime and EndTime are in milliseconds since the epoch
+	StartTime     *int64 `json:"startTime,omitempty"`
+	EndTime       *int64 `json:"endTime,omitempty"`
+	Limit         int32  `json:"limit,omitempty"`
+	NextToken     string `json:"nextToken,omitempty"`
+	StartFromHead bool   `json:"startFromHead"`
+}
+
+// GetLogEventsOutput is a page of log events. NextForwardToken is returned unchanged when
+// there are no newer events.
+type GetLogEventsOutput struct {
+	Events            []OutputLogEvent `json:"events"`
+	NextForwardToken  string           `json:"nextForwardToken,omitempty"`
+	NextBackwardToken string           `json:"nextBackwardToken,omitempty"`
+}
+
+// OutputLogEvent is a log event with timestamps in milliseconds since the epoch
+type OutputLogEvent struct {
+	Timestamp     int64  `json:"timestamp"`
+	Message       string `json:"message"`
+	IngestionTime int64  `json:"ingestionTime,omitempty"`
+}
+
+// Time returns the time of the event
+func (e OutputLogEvent) Time() time.Time {
+	return time.UnixMilli(e.Timestamp).UTC()
+}
+
+// AWSLogsClient calls the CloudWatch Logs API through the AWS SDK
+type AWSLogsClient struct {
+	client *cloudwatchlogs.Client
+}
+
+var _ LogsClient = &AWSLogsClient{}
+
+// NewLogsClient creates a CloudWatch Logs API client. Settings missing from cfg are read from
+// the shared AWS configuration as for NewClient.
+func NewLogsClient(ctx context.Context, cfg Config) (*AWSLogsClient, error) {
+	awsConfig, err := loadConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := cloudwatchlogs.NewFromConfig(awsConfig, func(o *cloudwatchlogs.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
+	return &AWSLogsClient{client: client}, nil
+}
+
+// GetLogEvents returns a page of the events of a log stream
+func (c *AWSLogsClient) GetLogEvents(ctx context.Context, input *GetLogEventsInput) (*GetLogEventsOutput, error) {
+	sdkInput := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(input.LogGroupName),
+		LogStreamName: aws.String(input.LogStreamName),
+		StartTime:     input.StartTime,
+		EndTime:       input.EndTime,
+		StartFromHead: aws.Bool(input.StartFromHead),
+	}
+	if input.Limit > 0 {
+		sdkInput.Limit = aws.Int32(input.Limit)
+	}
+	if input.NextToken != "" {
+		sdkInput.NextToken = aws.String(input.NextToken)
+	}
+
+	output, err := c.client.GetLogEvents(ctx, sdkInput)
+	if err != nil {
+		return nil, err
+	}
+	result := &GetLogEventsOutput{
+		Events:            make([]OutputLogEvent, 0, len(output.Events)),
+		NextForwardToken:  aws.ToString(output.NextForwardToken),
+		NextBackwardToken: aws.ToString(output.NextBackwardToken),
+	}
+	for _, event := range output.Events {
+		result.Events = append(result.Events, OutputLogEvent{
+			Timestamp:     aws.ToInt64(event.Timestamp),
+			Message:       aws.ToString(event.Message),
+			IngestionTime: aws.ToInt64(event.IngestionTime),
+		})
+	}
+	return result, nil
+}
+
+// Error code of CloudWatch Logs for log groups and streams that do not exist
+const ErrorCodeResourceNotFound = "ResourceNotFoundException"
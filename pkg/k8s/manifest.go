@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// StdinPath is the input path that reads manifests from standard input
+const StdinPath = "-"
+
+// Manifest is a single Kubernetes object read from a manifest file or stream
+type Manifest struct {
+	// Source is the file the object was read from, or "<stdin>"
+	Source string
+	// Index is the position of the document within Source, starting at 0
+	Index int
+
+	metav1.TypeMeta
+	Metadata metav1.ObjectMeta
+
+	// Raw is the object encoded as JSON
+	Raw []byte
+}
+
+// String identifies the object for messages, as kind namespace/name
+func (m Manifest) String() string {
+	name := m.Metadata.Name
+	if m.Metadata.Namespace != "" {
+		name = m.Metadata.Namespace + "/" + name
+	}
+	switch {
+	case m.Kind == "":
+		return fmt.Sprintf("document %d of %s", m.Index, m.Source)
+	case name == "":
+		return m.Kind
+	default:
+		return fmt.Sprintf("%s %s", m.Kind, name)
+	}
+}
+
+// ReadManifests reads every object from the given paths. A path may be a file, a directory
+// searched recursively for .yaml, .yml and .json files, or StdinPath. Files may hold several
+// documents separated by ---, such as helm template output; empty documents are skipped
+// and List objects are expanded into their items.
+func ReadManifests(stdin io.Reader, paths ...string) ([]Manifest, error) {
+	var manifests []Manifest
+
+	for _, path := range paths {
+		if path == StdinPath {
+			read, err := ReadManifestStream(stdin, "<stdin>")
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, read...)
+			continue
+		}
+
+		err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return nil
+			}
+			if file != path && !isManifestFile(file) {
+				return nil
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+
+			read, err := ReadManifestStream(f, file)
+			if err != nil {
+				return err
+			}
+			manifests = append(manifests, read...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return manifests, nil
+}
+
+func isManifestFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+// ReadManifestStream reads every object of a YAML or JSON stream
+func ReadManifestStream(r io.Reader, source string) ([]Manifest, error) {
+	var manifests []Manifest
+
+	reader := yaml.NewYAMLReader(bufio.NewReader(r))
+	for index := 0; ; index++ {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return manifests, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+
+		data, err := yaml.ToJSON(document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse document %d of %s: %w", index, source, err)
+		}
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+			// Comment-only documents, as emitted by helm template for empty templates
+			continue
+		}
+
+		read, err := decodeManifest(data, source, index)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, read...)
+	}
+}
+
+func decodeManifest(data []byte, source string, index int) ([]Manifest, error) {
+	var object struct {
+		metav1.TypeMeta
+		Metadata metav1.ObjectMeta `json:"metadata"`
+		Items    []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("failed to parse document %d of %s: %w", index, source, err)
+	}
+
+	if strings.HasSuffix(object.Kind, "List") && object.Items != nil {
+		var manifests []Manifest
+		for _, item := range object.Items {
+			read, err := decodeManifest(item, source, index)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, read...)
+		}
+		return manifests, nil
+	}
+
+	return []Manifest{{
+		Source:   source,
+		Index:    index,
+		TypeMeta: object.TypeMeta,
+		Metadata: object.Metadata,
+		Raw:      data,
+	}}, nil
+}
@@ -0,0 +1,94 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadManifestStream(t *testing.T) {
+	stream := `---
+# Source: chart/templates/empty.yaml
+---
+# Source: chart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: a
+    namespace: shop
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: b
+`
+
+	manifests, err := ReadManifestStream(strings.NewReader(stream), "<stdin>")
+	if err != nil {
+		t.Fatalf("ReadManifestStream() failed: %v", err)
+	}
+
+	var got []string
+	for _, manifest := range manifests {
+		got = append(got, manifest.String())
+	}
+	want := []string{"Service web", "Pod shop/a", "Pod b"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ReadManifestStream() = %v, want %v", got, want)
+	}
+	if manifests[0].Index != 1 || manifests[1].Index != 2 {
+		t.Errorf("document indexes = %d, %d, want 1, 2", manifests[0].Index, manifests[1].Index)
+	}
+}
+
+func TestReadManifests(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"pod.yaml": "apiVersion: v1\nkind: Pod\nmetadata: {name: a}\n",
+		"nested/pods.yml": "apiVersion: v1\nkind: Pod\nmetadata: {name: b}\n---\n" +
+			"apiVersion: v1\nkind: Pod\nmetadata: {name: c}\n",
+		"nested/pod.json":     `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "d"}}`,
+		"nested/notes.txt":    "not a manifest",
+		"nested/deeper/x.yml": "apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: e}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdin := strings.NewReader("apiVersion: v1\nkind: Pod\nmetadata: {name: from-stdin}\n")
+	manifests, err := ReadManifests(stdin, dir, StdinPath)
+	if err != nil {
+		t.Fatalf("ReadManifests() failed: %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, manifest := range manifests {
+		names[manifest.Metadata.Name] = true
+	}
+	for _, name := range []string{"a", "b", "c", "d", "e", "from-stdin"} {
+		if !names[name] {
+			t.Errorf("ReadManifests() did not return %s, got %v", name, names)
+		}
+	}
+	if len(manifests) != 6 {
+		t.Errorf("ReadManifests() returned %d manifests, want 6", len(manifests))
+	}
+
+	if _, err := ReadManifests(nil, filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("ReadManifests() should fail for a missing path")
+	}
+}
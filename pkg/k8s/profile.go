@@ -0,0 +1,79 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecs"
+)
+
+const (
+	// ProfileConfigMapKey is the ConfigMap key holding the converter configuration
+	ProfileConfigMapKey = "config.yaml"
+
+	// ConfigMapSourcePrefix marks a configuration source as a ConfigMap reference of the
+	// form configmap:namespace/name
+	ConfigMapSourcePrefix = "configmap:"
+)
+
+// LoadProfileConfigFromConfigMap reads the converter configuration stored under
+// ProfileConfigMapKey in a ConfigMap
+func LoadProfileConfigFromConfigMap(
+	ctx context.Context,
+	client *Client,
+	namespace, name string,
+) (*ecs.ProfileConfig, error) {
+	configMap, err := client.Clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap %s/%s: %w", namespace, name, err)
+	}
+
+	data, ok := configMap.Data[ProfileConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s/%s has no %s key", namespace, name, ProfileConfigMapKey)
+	}
+
+	config, err := ecs.ParseProfileConfig([]byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("ConfigMap %s/%s: %w", namespace, name, err)
+	}
+	return config, nil
+}
+
+// LoadProfileConfigSource reads the converter configuration from a file path, or from a
+// ConfigMap when source is configmap:namespace/name. The Kubernetes client is only
+// created for ConfigMap sources.
+func LoadProfileConfigSource(
+	ctx context.Context,
+	source string,
+	clientConfig ClientConfig,
+) (*ecs.ProfileConfig, error) {
+	reference, isConfigMap := strings.CutPrefix(source, ConfigMapSourcePrefix)
+	if !isConfigMap {
+		return ecs.LoadProfileConfig(source)
+	}
+
+	namespace, name, found := strings.Cut(reference, "/")
+	if !found || namespace == "" || name == "" {
+		return nil, fmt.Errorf("expected %snamespace/name, got %q", ConfigMapSourcePrefix, source)
+	}
+
+	client, err := NewClient(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return LoadProfileConfigFromConfigMap(ctx, client, namespace, name)
+}
+
+// LoadProfile reads the converter configuration from source and selects the named profile,
+// or the default profile when name is empty
+func LoadProfile(ctx context.Context, source, name string, clientConfig ClientConfig) (*ecs.Profile, error) {
+	config, err := LoadProfileConfigSource(ctx, source, clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	return config.Profile(name)
+}
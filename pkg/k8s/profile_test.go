@@ -0,0 +1,60 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadProfileConfigFromConfigMap(t *testing.T) {
+	fakeClientset := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-to-ecs", Namespace: "tools"},
+			Data: map[string]string{
+				ProfileConfigMapKey: `apiVersion: ecs.takutakahashi.dev/v1alpha1
+kind: ConverterConfig
+profiles:
+  staging:
+    region: us-west-2
+    secrets:
+      prefix: /staging/pods
+`,
+			},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "tools"},
+		},
+	)
+	client := &Client{Clientset: fakeClientset}
+	ctx := context.Background()
+
+	config, err := LoadProfileConfigFromConfigMap(ctx, client, "tools", "pod-to-ecs")
+	if err != nil {
+		t.Fatalf("LoadProfileConfigFromConfigMap() failed: %v", err)
+	}
+	profile, err := config.Profile("")
+	if err != nil {
+		t.Fatalf("Profile() failed: %v", err)
+	}
+	if options := profile.ConversionOptions(); options.ParameterStorePrefix != "/staging/pods" {
+		t.Errorf("ParameterStorePrefix = %q, want /staging/pods", options.ParameterStorePrefix)
+	}
+
+	if _, err := LoadProfileConfigFromConfigMap(ctx, client, "tools", "empty"); err == nil ||
+		!strings.Contains(err.Error(), ProfileConfigMapKey) {
+		t.Errorf("LoadProfileConfigFromConfigMap() error = %v, want missing key", err)
+	}
+	if _, err := LoadProfileConfigFromConfigMap(ctx, client, "tools", "missing"); err == nil {
+		t.Error("LoadProfileConfigFromConfigMap() should fail for a missing ConfigMap")
+	}
+}
+
+func TestLoadProfileConfigSource_InvalidReference(t *testing.T) {
+	if _, err := LoadProfileConfigSource(context.Background(), "configmap:tools", ClientConfig{}); err == nil {
+		t.Error("LoadProfileConfigSource() should reject a ConfigMap reference without a name")
+	}
+}
@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceAccountService provides operations for working with ServiceAccounts
+type ServiceAccountService struct {
+	client *Client
+}
+
+// NewServiceAccountService creates a new ServiceAccountService
+func NewServiceAccountService(client *Client) *ServiceAccountService {
+	return &ServiceAccountService{
+		client: client,
+	}
+}
+
+// GetServiceAccount gets a specific ServiceAccount by name and namespace
+func (s *ServiceAccountService) GetServiceAccount(
+	ctx context.Context,
+	namespace, name string,
+) (*corev1.ServiceAccount, error) {
+	return s.client.Clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// ManifestServiceAccounts serves ServiceAccounts read from local manifest files
+type ManifestServiceAccounts struct {
+	serviceAccounts map[string]*corev1.ServiceAccount
+}
+
+// LoadServiceAccountManifests reads the ServiceAccounts from YAML or JSON manifests.
+// Paths may be files or directories, which are searched recursively for .yaml, .yml and
+// .json files. Other kinds of objects in the manifests are ignored.
+func LoadServiceAccountManifests(paths ...string) (*ManifestServiceAccounts, error) {
+	manifests, err := ReadManifests(os.Stdin, paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccounts := &ManifestServiceAccounts{serviceAccounts: map[string]*corev1.ServiceAccount{}}
+	for _, manifest := range manifests {
+		if manifest.Kind != "ServiceAccount" {
+			continue
+		}
+
+		var serviceAccount corev1.ServiceAccount
+		if err := json.Unmarshal(manifest.Raw, &serviceAccount); err != nil {
+			return nil, fmt.Errorf("failed to parse %s in %s: %w", manifest, manifest.Source, err)
+		}
+
+		namespace := serviceAccount.Namespace
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+			serviceAccount.Namespace = namespace
+		}
+		serviceAccounts.serviceAccounts[namespace+"/"+serviceAccount.Name] = &serviceAccount
+	}
+
+	return serviceAccounts, nil
+}
+
+// GetServiceAccount returns the ServiceAccount, or a NotFound error if no manifest defines it
+func (m *ManifestServiceAccounts) GetServiceAccount(
+	_ context.Context,
+	namespace, name string,
+) (*corev1.ServiceAccount, error) {
+	serviceAccount, ok := m.serviceAccounts[namespace+"/"+name]
+	if !ok {
+		return nil, apierrors.NewNotFound(corev1.Resource("serviceaccounts"), name)
+	}
+	return serviceAccount, nil
+}
@@ -0,0 +1,97 @@
+package k8s
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServiceAccountService_GetServiceAccount(t *testing.T) {
+	fakeClientset := fake.NewSimpleClientset(
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "web",
+				Namespace: "shop",
+				Annotations: map[string]string{
+					"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/web",
+				},
+			},
+		},
+	)
+
+	service := NewServiceAccountService(&Client{Clientset: fakeClientset})
+	ctx := context.Background()
+
+	serviceAccount, err := service.GetServiceAccount(ctx, "shop", "web")
+	if err != nil {
+		t.Fatalf("GetServiceAccount() failed: %v", err)
+	}
+	if serviceAccount.Annotations["eks.amazonaws.com/role-arn"] != "arn:aws:iam::123456789012:role/web" {
+		t.Errorf("GetServiceAccount() got annotations %v", serviceAccount.Annotations)
+	}
+
+	if _, err := service.GetServiceAccount(ctx, "shop", "missing"); !apierrors.IsNotFound(err) {
+		t.Errorf("GetServiceAccount() error = %v, want NotFound", err)
+	}
+}
+
+func TestLoadServiceAccountManifests(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web
+  namespace: shop
+  annotations:
+    eks.amazonaws.com/role-arn: arn:aws:iam::123456789012:role/web
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+  namespace: shop
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: worker
+`
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nested", "serviceaccounts.yaml"), []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# not a manifest"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	manifests, err := LoadServiceAccountManifests(dir)
+	if err != nil {
+		t.Fatalf("LoadServiceAccountManifests() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	web, err := manifests.GetServiceAccount(ctx, "shop", "web")
+	if err != nil {
+		t.Fatalf("GetServiceAccount() failed: %v", err)
+	}
+	if web.Annotations["eks.amazonaws.com/role-arn"] != "arn:aws:iam::123456789012:role/web" {
+		t.Errorf("GetServiceAccount() got annotations %v", web.Annotations)
+	}
+
+	if _, err := manifests.GetServiceAccount(ctx, "default", "worker"); err != nil {
+		t.Errorf("ServiceAccount without namespace should default to the default namespace: %v", err)
+	}
+
+	if _, err := manifests.GetServiceAccount(ctx, "shop", "missing"); !apierrors.IsNotFound(err) {
+		t.Errorf("GetServiceAccount() error = %v, want NotFound", err)
+	}
+}
@@ -1,12 +1,18 @@
 package integration_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/takutakahashi/k8s-ecstask/internal/standin"
+	"github.com/takutakahashi/k8s-ecstask/pkg/ecsapi"
 )
 
 func TestPodToECSConversion(t *testing.T) {
@@ -52,6 +58,21 @@ func TestPodToECSConversion(t *testing.T) {
 			inputFile:      "../fixtures/pod-with-mixed-compatibility.yaml",
 			expectedChecks: testPodWithMixedCompatibility,
 		},
+		{
+			name:           "XPod document",
+			inputFile:      "../../examples/sample-xpod.yaml",
+			expectedChecks: testXPod,
+		},
+		{
+			name:           "Pod with converter profile",
+			inputFile:      "../fixtures/simple-pod-unquoted.yaml",
+			expectedChecks: testPodWithProfile,
+			additionalArgs: []string{
+				"-config", "../../examples/converter-config.yaml",
+				"-profile", "staging",
+				"-log-group", "/ecs/override",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -59,6 +80,118 @@ func TestPodToECSConversion(t *testing.T) {
 			runTest(t, tt.inputFile, tt.expectedChecks, tt.additionalArgs)
 		})
 	}
+
+	t.Run("Multi-document input to output directory", testMultiDocument)
+	t.Run("Diff against previous task definitions", testDiff)
+	t.Run("Register and run against the local stand-in", testStandIn)
+}
+
+func testMultiDocument(t *testing.T) {
+	outputDir := t.TempDir()
+
+	cmd := exec.Command("./pod-to-ecs",
+		"-input", "-",
+		"-output-dir", outputDir,
+		"-cpu", "256",
+		"-memory", "512",
+	)
+	input, err := os.Open("../../examples/test-pod.yaml")
+	if err != nil {
+		t.Fatalf("Failed to open input: %v", err)
+	}
+	defer func() { _ = input.Close() }()
+	cmd.Stdin = input
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to run pod-to-ecs: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output), "2 converted, 0 skipped, 0 failed") {
+		t.Errorf("Expected a summary of 2 converted pods, got:\n%s", output)
+	}
+
+	for _, family := range []string{"default-allowed-pod-f41562fc", "default-blocked-pod-7ead533d"} {
+		data, err := os.ReadFile(filepath.Join(outputDir, family+".json"))
+		if err != nil {
+			t.Errorf("Expected output for family %s: %v", family, err)
+			continue
+		}
+		var taskDef map[string]interface{}
+		if err := json.Unmarshal(data, &taskDef); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+		if taskDef["family"] != family {
+			t.Errorf("Expected family %s, got %v", family, taskDef["family"])
+		}
+	}
+}
+
+func testDiff(t *testing.T) {
+	previousDir := t.TempDir()
+	args := []string{"-input", "../../examples/test-pod.yaml", "-cpu", "256", "-memory", "512"}
+
+	convert := exec.Command("./pod-to-ecs", append(args, "-output-dir", previousDir)...)
+	if output, err := convert.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to run pod-to-ecs: %v\nOutput: %s", err, output)
+	}
+
+	unchanged := exec.Command("./pod-to-ecs", append([]string{"diff", "-against", previousDir}, args...)...)
+	if output, err := unchanged.CombinedOutput(); err != nil {
+		t.Fatalf("Expected no changes, got %v\nOutput: %s", err, output)
+	}
+
+	changed := exec.Command("./pod-to-ecs", "diff", "-against", previousDir,
+		"-input", "../../examples/test-pod.yaml", "-cpu", "256", "-memory", "1024")
+	output, err := changed.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+		t.Fatalf("Expected exit status 2 for changes, got %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output), `~ memory: "512" -> "1024"`) {
+		t.Errorf("Expected the memory change in the diff, got:\n%s", output)
+	}
+}
+
+func testStandIn(t *testing.T) {
+	server := standin.NewServer("us-east-1", "123456789012")
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	args := []string{
+		"-input", "../../examples/kubernetes-pod.yaml", "-cpu", "512", "-memory", "1024",
+		"-region", "us-east-1", "-endpoint-url", httpServer.URL,
+	}
+	env := append(os.Environ(), "AWS_ACCESS_KEY_ID=test", "AWS_SECRET_ACCESS_KEY=test")
+
+	run := exec.Command("./pod-to-ecs", append(args, "-output", os.DevNull, "-run",
+		"-cluster", "apps", "-subnets", "subnet-0abc")...)
+	run.Env = env
+	output, err := run.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to run pod-to-ecs: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output),
+		"Registered arn:aws:ecs:us-east-1:123456789012:task-definition/production-web-app-pod-98c02156:1") {
+		t.Errorf("Expected the registered task definition ARN, got:\n%s", output)
+	}
+
+	// The task starts PROVISIONING and advances to RUNNING
+	server.ECS.Advance()
+	server.ECS.Advance()
+	tasks, err := server.ECS.DescribeTasks(context.Background(), "apps",
+		[]string{"00000000000000000000000000000001"})
+	if err != nil || len(tasks.Tasks) != 1 {
+		t.Fatalf("DescribeTasks() = %+v, %v", tasks, err)
+	}
+	if task := tasks.Tasks[0]; task.LastStatus != ecsapi.TaskStatusRunning || len(task.Containers) != 2 {
+		t.Errorf("Expected a RUNNING task with 2 containers, got %+v", task)
+	}
+
+	diff := exec.Command("./pod-to-ecs", append([]string{"diff", "-against", "ecs:"}, args...)...)
+	diff.Env = env
+	if output, err := diff.CombinedOutput(); err != nil {
+		t.Errorf("Expected no changes against the registered task definition, got %v\nOutput: %s", err, output)
+	}
 }
 
 func runTest(
@@ -327,3 +460,43 @@ func testPodWithMixedCompatibility(t *testing.T, taskDef map[string]interface{})
 	}
 
 }
+
+func testPodWithProfile(t *testing.T, taskDef map[string]interface{}) {
+	container := taskDef["containerDefinitions"].([]interface{})[0].(map[string]interface{})
+	logOptions := container["logConfiguration"].(map[string]interface{})["options"].(map[string]interface{})
+
+	// The log group flag overrides the profile, the region comes from the staging profile
+	if logOptions["awslogs-group"] != "/ecs/override" {
+		t.Errorf("Expected awslogs-group '/ecs/override', got %v", logOptions["awslogs-group"])
+	}
+	if logOptions["awslogs-region"] != "us-west-2" {
+		t.Errorf("Expected awslogs-region 'us-west-2', got %v", logOptions["awslogs-region"])
+	}
+}
+
+func testXPod(t *testing.T, taskDef map[string]interface{}) {
+	// The -family flag overrides the family of the document
+	if taskDef["family"] != "test-app" {
+		t.Errorf("Expected family 'test-app', got %v", taskDef["family"])
+	}
+
+	containerDefs := taskDef["containerDefinitions"].([]interface{})
+	if len(containerDefs) != 2 {
+		t.Fatalf("Expected 2 containers, got %d", len(containerDefs))
+	}
+
+	// Per-container overrides from ecsConfig.containers
+	nginx := containerDefs[0].(map[string]interface{})
+	if nginx["readonlyRootFilesystem"] != true {
+		t.Errorf("Expected nginx readonlyRootFilesystem true, got %v", nginx["readonlyRootFilesystem"])
+	}
+	app := containerDefs[1].(map[string]interface{})
+	if app["stopTimeout"] != float64(30) {
+		t.Errorf("Expected app stopTimeout 30, got %v", app["stopTimeout"])
+	}
+
+	tags := taskDef["tags"].([]interface{})
+	if len(tags) != 4 {
+		t.Errorf("Expected 4 tags from ecsConfig, got %v", tags)
+	}
+}